@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Alert rule evaluated against newly ingested findings. Empty conditions match everything.
+type alertRule struct {
+	Name       string   `yaml:"name"`
+	Detector   string   `yaml:"detector"`   // detector name, case-insensitive
+	Verified   *bool    `yaml:"verified"`   // only verified (true) or unverified (false) findings
+	Visibility string   `yaml:"visibility"` // repository visibility: "public" or "private"
+	Term       string   `yaml:"term"`       // optional search term
+	Mode       string   `yaml:"mode"`       // search mode for term (default "contains")
+	Field      string   `yaml:"field"`      // field to search term in (default: everywhere)
+	Sinks      []string `yaml:"sinks"`      // names of the sinks receiving the alert
+
+	term *searcher.Searcher // Term compiled by compileAlerts
+}
+
+// A rule that fired for a finding
+type firedAlert struct {
+	Rule        string   `json:"rule"`
+	Fingerprint string   `json:"fingerprint"`
+	Detector    string   `json:"detector"`
+	Repository  string   `json:"repository,omitempty"`
+	Verified    bool     `json:"verified"`
+	Finding     JSONData `json:"-"`
+}
+
+// Repository visibility values used by trufflehog's source metadata
+var visibilityNames = map[float64]string{0: "public", 1: "private"}
+
+// Check every alert rule of the configuration and compile its term, so a
+// rule that could never fire fails at startup instead of staying silent
+func compileAlerts(cfg *config) error {
+	for i := range cfg.Alerts {
+		rule := &cfg.Alerts[i]
+		if rule.Name == "" {
+			return fmt.Errorf("alert rule %d has no name", i+1)
+		}
+		for _, name := range rule.Sinks {
+			sinkCfg, ok := cfg.Sinks[name]
+			if !ok {
+				return fmt.Errorf("alert %s: unknown sink %q", rule.Name, name)
+			}
+			// A sink that cannot be built would only fail once an alert fires
+			if _, err := newAlertSink(sinkCfg); err != nil {
+				return fmt.Errorf("alert %s: sink %s: %w", rule.Name, name, err)
+			}
+		}
+		if rule.Term == "" {
+			if rule.Mode != "" || rule.Field != "" {
+				return fmt.Errorf("alert %s: mode and field need a term", rule.Name)
+			}
+			continue
+		}
+		s, err := searcher.New(searcher.Options{Terms: []string{rule.Term}, Mode: rule.Mode, Field: rule.Field})
+		if err != nil {
+			return fmt.Errorf("alert %s: %w", rule.Name, err)
+		}
+		rule.term = s
+	}
+	return nil
+}
+
+// Check whether a finding satisfies every condition of the rule
+func (r alertRule) matches(data JSONData) bool {
+	if r.Detector != "" {
+		detector, _ := data["DetectorName"].(string)
+		if !strings.EqualFold(detector, r.Detector) {
+			return false
+		}
+	}
+	if r.Verified != nil {
+		verified, _ := data["Verified"].(bool)
+		if verified != *r.Verified {
+			return false
+		}
+	}
+	if r.Visibility != "" {
+		value, _ := searcher.Lookup(data, "visibility")
+		visibility, ok := value.(float64)
+		if !ok || visibilityNames[visibility] != r.Visibility {
+			return false
+		}
+	}
+	if r.term != nil {
+		if _, ok := r.term.Match(data); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate every rule against a finding
+func evaluateAlerts(rules []alertRule, data JSONData, fp string) []firedAlert {
+	var fired []firedAlert
+	for _, rule := range rules {
+		if !rule.matches(data) {
+			continue
+		}
+		flat := flattenFinding(data, "", 0)
+		fired = append(fired, firedAlert{
+			Rule:        rule.Name,
+			Fingerprint: fp,
+			Detector:    flat.DetectorName,
+			Repository:  flat.Repository,
+			Verified:    flat.Verified,
+			Finding:     data,
+		})
+	}
+	return fired
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// An outlier value of the detector or repository field among the matches
+type anomaly struct {
+	field  string // "detector" or "repository"
+	value  string
+	count  int
+	median float64
+}
+
+func (a anomaly) String() string {
+	if a.field == "detector" {
+		return fmt.Sprintf("rare detector %s: %d match(es), the median detector has %g", a.value, a.count, a.median)
+	}
+	return fmt.Sprintf("repository %s: %d match(es), %.0fx the median repository (%g)", a.value, a.count, float64(a.count)/a.median, a.median)
+}
+
+// Find the detectors with factor times fewer matches than the median detector
+// and the repositories with factor times more than the median repository.
+// The matches of those are marked and moved first.
+func markAnomalies(matches []*searchMatch, factor float64) ([]*searchMatch, []anomaly) {
+	detectors, repositories := map[string]int{}, map[string]int{}
+	for _, m := range matches {
+		if m.ignoredBy != "" {
+			continue
+		}
+		flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+		detectors[flat.DetectorName]++
+		repositories[flat.Repository]++
+	}
+
+	var anomalies []anomaly
+	rare := outliers(detectors, func(count int, median float64) bool { return float64(count)*factor <= median })
+	for _, entry := range rare {
+		anomalies = append(anomalies, anomaly{"detector", entry.value, entry.count, median(detectors)})
+	}
+	busy := outliers(repositories, func(count int, median float64) bool { return float64(count) >= factor*median })
+	for _, entry := range busy {
+		anomalies = append(anomalies, anomaly{"repository", entry.value, entry.count, median(repositories)})
+	}
+	if len(anomalies) == 0 {
+		return matches, nil
+	}
+
+	reasons := map[[2]string]string{}
+	rank := map[string]int{}
+	for i, a := range anomalies {
+		reasons[[2]string{a.field, a.value}] = a.String()
+		rank[a.String()] = i
+	}
+	for _, m := range matches {
+		if m.ignoredBy != "" {
+			continue
+		}
+		flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+		if reason, ok := reasons[[2]string{"detector", flat.DetectorName}]; ok {
+			m.anomaly = reason
+		} else if reason, ok := reasons[[2]string{"repository", flat.Repository}]; ok {
+			m.anomaly = reason
+		}
+	}
+	ordered := make([]*searchMatch, len(matches))
+	copy(ordered, matches)
+	// Anomalous findings come first, in the order of the anomalies
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i].anomaly, ordered[j].anomaly
+		return a != "" && (b == "" || rank[a] < rank[b])
+	})
+	return ordered, anomalies
+}
+
+// The values of a field whose count is an outlier, largest counts first.
+// Findings without the field are left out.
+func outliers(counts map[string]int, outlier func(count int, median float64) bool) []valueCount {
+	middle := median(counts)
+	var found []valueCount
+	for _, entry := range sortedCounts(counts) {
+		if entry.value != "" && outlier(entry.count, middle) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Median count of the values of a field that are set
+func median(counts map[string]int) float64 {
+	var values []int
+	for value, count := range counts {
+		if value != "" {
+			values = append(values, count)
+		}
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Ints(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return float64(values[middle-1]+values[middle]) / 2
+	}
+	return float64(values[middle])
+}
+
+// Print the anomalies found, then every match with the anomalous ones first
+func (p *printer) writeAnomalies(anomalies []anomaly) {
+	if p.flags.format == "text" && p.fields == nil {
+		if len(anomalies) == 0 {
+			fmt.Fprintln(p.w, "=== No anomalies ===")
+		} else {
+			fmt.Fprintf(p.w, "=== Anomalies: %d ===\n", len(anomalies))
+		}
+		for _, a := range anomalies {
+			fmt.Fprintf(p.w, "- %s\n", a)
+		}
+	}
+	for _, m := range p.collected {
+		p.emit(m)
+	}
+}
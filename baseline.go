@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Findings of an earlier scan. Matches already in the baseline are left out,
+// so only new findings are reported.
+type baselineSet struct {
+	mu    sync.Mutex
+	known map[string]JSONData // by occurrence fingerprint
+	order []string
+	seen  map[string]bool // baseline findings met again in the input
+}
+
+func newBaselineSet() *baselineSet {
+	return &baselineSet{known: map[string]JSONData{}, seen: map[string]bool{}}
+}
+
+// Load the findings of a baseline file or directory that match the search.
+// Both trufflehog output and -o json results are accepted.
+func loadBaseline(ctx context.Context, path string, s *searcher.Searcher, filter inputFilter, keep bool) (*baselineSet, error) {
+	b := newBaselineSet()
+	err := readBaselineFindings(ctx, path, filter, func(fp string, data JSONData) error {
+		if _, ok := s.Match(data); ok {
+			b.add(fp, data, keep)
+		}
+		return nil
+	}, func(name string, lineNum int, err error) {
+		// Its findings will be reported as new
+		fmt.Fprintf(os.Stderr, "Error parsing JSON at line %d in baseline file %s: %v\n", lineNum, name, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Load the findings of a baseline recorded by baseline create that match the search
+func loadNamedBaseline(dbPath, name string, s *searcher.Searcher, keep bool) (*baselineSet, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("no baseline named %q in %s", name, dbPath)
+	}
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", dbPath, err)
+	}
+	defer db.Close()
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM baselines WHERE name = ?`, name).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("no baseline named %q in %s (baseline list shows them)", name, dbPath)
+	}
+
+	rows, err := db.Query(`SELECT fingerprint, data FROM baseline_findings WHERE baseline = ? ORDER BY rowid`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// The secrets were not stored, so every fingerprint counts as known and
+	// -show-resolved lists the findings whose redacted form matches the search
+	b := newBaselineSet()
+	for rows.Next() {
+		var fp, encoded string
+		if err := rows.Scan(&fp, &encoded); err != nil {
+			return nil, err
+		}
+		if !keep {
+			b.addUnlisted(fp)
+			continue
+		}
+		var data JSONData
+		if err := json.Unmarshal([]byte(encoded), &data); err != nil {
+			return nil, fmt.Errorf("baseline %s: finding %s: %w", name, fp, err)
+		}
+		if _, ok := s.Match(data); ok {
+			b.add(fp, data, true)
+		} else {
+			b.addUnlisted(fp)
+		}
+	}
+	return b, rows.Err()
+}
+
+// Call fn with the fingerprint of every finding of a baseline file or
+// directory, trufflehog output or -o json results, in input order, and
+// onParseError with every entry that could not be parsed
+func readBaselineFindings(ctx context.Context, path string, filter inputFilter, fn func(fp string, data JSONData) error, onParseError func(name string, lineNum int, err error)) error {
+	sources, err := listInputs(ctx, path, filter)
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		err := readSource(ctx, src, filter, func(name string, r io.Reader) error {
+			var fnErr error
+			err := scanFindings(r, func(lineNum int, data JSONData) {
+				if fnErr != nil {
+					return
+				}
+				fp := ""
+				if result, ok := unwrapResult(data); ok {
+					data, fp = result.Finding, result.Fingerprint
+				}
+				if fp == "" {
+					fp = fingerprint(data, occurrenceFields)
+				}
+				fnErr = fn(fp, data)
+			}, func(lineNum int, err error) {
+				onParseError(name, lineNum, err)
+			})
+			if err != nil {
+				return err
+			}
+			return fnErr
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add a finding of the baseline, once per fingerprint
+func (b *baselineSet) add(fp string, data JSONData, keep bool) {
+	if _, ok := b.known[fp]; ok {
+		return
+	}
+	// Only -show-resolved needs the findings themselves
+	if keep {
+		b.known[fp] = data
+	} else {
+		b.known[fp] = nil
+	}
+	b.order = append(b.order, fp)
+}
+
+// Add a finding of the baseline that -show-resolved does not list
+func (b *baselineSet) addUnlisted(fp string) {
+	if _, ok := b.known[fp]; !ok {
+		b.known[fp] = nil
+	}
+}
+
+// Whether a finding is missing from the baseline. Baseline findings are
+// remembered as still present.
+func (b *baselineSet) isNew(fp string) bool {
+	if _, ok := b.known[fp]; !ok {
+		return true
+	}
+	b.mu.Lock()
+	b.seen[fp] = true
+	b.mu.Unlock()
+	return false
+}
+
+// A finding of the baseline with its occurrence fingerprint
+type baselineFinding struct {
+	fingerprint string
+	data        JSONData
+}
+
+// Baseline findings the input no longer has, in baseline order
+func (b *baselineSet) resolved() []baselineFinding {
+	var resolved []baselineFinding
+	for _, fp := range b.order {
+		if !b.seen[fp] {
+			resolved = append(resolved, baselineFinding{fp, b.known[fp]})
+		}
+	}
+	return resolved
+}
+
+// Manage the named baselines of the findings database
+func runBaseline(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Error: expected a baseline command: create, list or delete")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "create":
+		runBaselineCreate(args[1:])
+	case "list":
+		runBaselineList(args[1:])
+	case "delete":
+		runBaselineDelete(args[1:])
+	default:
+		fmt.Printf("Error: unknown baseline command %q (expected create, list or delete)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// Record the fingerprints of every finding of a corpus under a name, for
+// later searches with -against NAME
+func runBaselineCreate(args []string) {
+	fs := flag.NewFlagSet("baseline create", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory, file, '-' for stdin, or s3://, gs://, azblob://, sftp:// or http(s):// location of JSON trufflehog output or -o json results (required)")
+	recursive := fs.Bool("r", false, "Read subdirectories of -i")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	force := fs.Bool("force", false, "Replace a baseline of the same name")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher baseline create NAME -i INPUT [-r]")
+		fs.PrintDefaults()
+	}
+	names := parseInterleaved(fs, args)
+	if len(names) != 1 || *inDir == "" {
+		fmt.Println("Error: one baseline name and -i are required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	lock, err := lockDB(ctx, *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	name := names[0]
+	count, err := createBaseline(ctx, db, name, *inDir, inputFilter{recursive: *recursive}, *force)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Recorded %d finding(s) of %s as baseline %q\n", count, *inDir, name)
+}
+
+// Store the fingerprints of the findings of an input under a baseline name in one transaction
+func createBaseline(ctx context.Context, db *sql.DB, name, input string, filter inputFilter, force bool) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM baselines WHERE name = ?`, name).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		if !force {
+			return 0, fmt.Errorf("baseline %q already exists (-force replaces it)", name)
+		}
+		if _, err := deleteBaseline(tx, name); err != nil {
+			return 0, err
+		}
+	}
+
+	// Only the redacted finding is kept besides the fingerprint, for -show-resolved
+	count, parseErrors := 0, 0
+	err = readBaselineFindings(ctx, input, filter, func(fp string, data JSONData) error {
+		encoded, err := json.Marshal(searcher.Redact(data, searcher.DefaultRedactFields))
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`INSERT OR IGNORE INTO baseline_findings (baseline, fingerprint, data) VALUES (?, ?, ?)`, name, fp, string(encoded))
+		if err != nil {
+			return err
+		}
+		added, _ := result.RowsAffected()
+		count += int(added)
+		return nil
+	}, func(name string, lineNum int, err error) {
+		parseErrors++
+		fmt.Fprintf(os.Stderr, "Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	// A baseline missing findings would report them as new on every run
+	if parseErrors > 0 {
+		return 0, fmt.Errorf("%d finding(s) of %s could not be parsed; baseline %q was not recorded", parseErrors, input, name)
+	}
+	if _, err := tx.Exec(`INSERT INTO baselines (name, source, findings, created_at) VALUES (?, ?, ?, ?)`,
+		name, input, count, time.Now().Unix()); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// Remove a baseline and its findings; false when there was none of that name
+func deleteBaseline(tx *sql.Tx, name string) (bool, error) {
+	if _, err := tx.Exec(`DELETE FROM baseline_findings WHERE baseline = ?`, name); err != nil {
+		return false, err
+	}
+	result, err := tx.Exec(`DELETE FROM baselines WHERE name = ?`, name)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// List the named baselines, newest first
+func runBaselineList(args []string) {
+	fs := flag.NewFlagSet("baseline list", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*dbPath); errors.Is(err, os.ErrNotExist) {
+		fmt.Println("No baselines")
+		return
+	}
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT name, findings, created_at, source FROM baselines ORDER BY created_at DESC, name`)
+	if err != nil {
+		fmt.Printf("Error reading database: %v\n", err)
+		os.Exit(1)
+	}
+	defer rows.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tFindings\tCreated\tSource")
+	for rows.Next() {
+		var name, source string
+		var findings int
+		var created int64
+		if err := rows.Scan(&name, &findings, &created, &source); err != nil {
+			fmt.Printf("Error reading database: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", name, findings, time.Unix(created, 0).Format(time.DateTime), source)
+	}
+	w.Flush()
+}
+
+// Remove named baselines
+func runBaselineDelete(args []string) {
+	fs := flag.NewFlagSet("baseline delete", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher baseline delete NAME...")
+		fs.PrintDefaults()
+	}
+	names := parseInterleaved(fs, args)
+	if len(names) == 0 {
+		fmt.Println("Error: at least one baseline name is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	lock, err := lockDB(context.Background(), *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer tx.Rollback()
+	for _, name := range names {
+		deleted, err := deleteBaseline(tx, name)
+		if err == nil && !deleted {
+			err = fmt.Errorf("no baseline named %q", name)
+		}
+		if err != nil {
+			fmt.Printf("Error deleting baseline %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Deleted %d baseline(s)\n", len(names))
+}
+
+// Parse flags given before, between or after the names, as in
+// baseline create NAME -i INPUT, and return the names
+func parseInterleaved(fs *flag.FlagSet, args []string) []string {
+	var names []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return names
+		}
+		names = append(names, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of the synthetic corpus, each read by another decoder
+var benchCompressions = []string{"none", "gzip", "zstd"}
+
+// Detectors of the synthetic findings with a generator of their secrets
+var benchDetectors = []struct {
+	name   string
+	secret func(r *rand.Rand) string
+}{
+	{"AWS", func(r *rand.Rand) string { return "AKIA" + randomString(r, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", 16) }},
+	{"Github", func(r *rand.Rand) string { return "ghp_" + randomString(r, alphanumeric, 36) }},
+	{"Slack", func(r *rand.Rand) string {
+		return "xoxb-" + randomString(r, "0123456789", 12) + "-" + randomString(r, alphanumeric, 24)
+	}},
+	{"Stripe", func(r *rand.Rand) string { return "sk_live_" + randomString(r, alphanumeric, 24) }},
+	{"Postgres", func(r *rand.Rand) string {
+		return "postgres://app:" + randomString(r, alphanumeric, 16) + "@db.internal:5432/app"
+	}},
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Generate a synthetic corpus and measure how fast it is parsed, matched and
+// searched end to end with the given search flags, so -t, -line-workers,
+// compressions and output formats can be compared on the same hardware
+func runBench(args []string) {
+	var findings, files int
+	var compression, dir string
+	var seed int64
+	extra := func(fs *flag.FlagSet) {
+		fs.IntVar(&findings, "findings", 100000, "Number of synthetic findings generated")
+		fs.IntVar(&files, "files", 4, "Number of files the findings are spread over")
+		fs.StringVar(&compression, "compress", "none", "Compression of the generated files: 'none', 'gzip' or 'zstd'")
+		fs.Int64Var(&seed, "seed", 1, "Seed of the generator; the same seed gives the same corpus")
+		fs.StringVar(&dir, "dir", "", "Directory receiving the corpus, kept after the run (default: a temporary directory)")
+		fs.Usage = func() {
+			fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher bench [-findings N] [-files N] [-compress none|gzip|zstd] [search flags]")
+			fmt.Fprintln(fs.Output(), "e.g. trufflehog-searcher bench -findings 500000 -compress zstd -t 8 -o json -s acme")
+			fs.PrintDefaults()
+		}
+	}
+
+	// Read the flags once for the corpus and the parse and match phases; the
+	// search phase parses them again like any search
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	f := registerSearchFlags(fs)
+	extra(fs)
+	fs.Parse(args)
+	f.tunePools(fs)
+	if findings < 1 || files < 1 {
+		fmt.Println("Error: -findings and -files must be at least 1.")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if !containsString(benchCompressions, compression) {
+		fmt.Printf("Error: -compress must be one of %s.\n", strings.Join(benchCompressions, ", "))
+		os.Exit(1)
+	}
+	searchArgs := append([]string{"-output-file", os.DevNull}, args...)
+	if !f.hasCriteria() {
+		searchArgs = append(searchArgs, "-s", "acme")
+		f.terms = append(f.terms, "acme")
+	}
+	opts, err := f.options()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	s, err := searcher.New(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "ths-bench-"); err != nil {
+			fmt.Printf("Error creating the corpus directory: %v\n", err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(dir)
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Printf("Error creating the corpus directory: %v\n", err)
+		os.Exit(1)
+	}
+	start := time.Now()
+	size, err := generateBenchCorpus(dir, findings, files, compression, seed)
+	if err != nil {
+		fmt.Printf("Error generating the corpus: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Corpus: %d findings in %d file(s), %.1f MB uncompressed (%s), generated in %s\n",
+		findings, files, float64(size)/(1<<20), compression, time.Since(start).Round(time.Millisecond))
+
+	// Parse: read, decompress and decode every finding
+	start = time.Now()
+	var decoded []JSONData
+	paths, err := listInputFiles(dir)
+	if err != nil {
+		fmt.Printf("Error reading the corpus: %v\n", err)
+		os.Exit(1)
+	}
+	for _, path := range paths {
+		fileHandle, err := openInput(path)
+		if err != nil {
+			fmt.Printf("Error opening file %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		err = scanFindings(fileHandle, func(lineNum int, data JSONData) {
+			decoded = append(decoded, data)
+		}, nil)
+		fileHandle.Close()
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+	parse := time.Since(start)
+
+	// Match: the search flags over the decoded findings, on one goroutine
+	start = time.Now()
+	matched := 0
+	for _, data := range decoded {
+		if _, ok := s.Match(data); ok {
+			matched++
+		}
+	}
+	match := time.Since(start)
+
+	// Search: the whole pipeline as a search of the corpus, output discarded
+	start = time.Now()
+	code := runSearch("bench", append(searchArgs, "-i", dir), extra, nil)
+	search := time.Since(start)
+	if code == 2 {
+		fmt.Println("Error: the search phase failed; its timing is not comparable")
+	}
+
+	fmt.Printf("%d of %d findings match\n\n", matched, findings)
+	fmt.Printf("%-8s %10s %14s %10s\n", "Phase", "Time", "Findings/s", "MB/s")
+	for _, phase := range []struct {
+		name    string
+		elapsed time.Duration
+		bytes   bool
+	}{{"parse", parse, true}, {"match", match, false}, {"search", search, true}} {
+		seconds := phase.elapsed.Seconds()
+		throughput := "-"
+		if phase.bytes {
+			throughput = fmt.Sprintf("%.1f", float64(size)/(1<<20)/seconds)
+		}
+		fmt.Printf("%-8s %10s %14.0f %10s\n", phase.name, phase.elapsed.Round(time.Millisecond), float64(findings)/seconds, throughput)
+	}
+	fmt.Printf("\nsearch: -t %d, -line-workers %d, -o %s\n", f.threads, f.lineWorkers, f.format)
+}
+
+// Write the synthetic findings, spread over files, and return their
+// uncompressed size
+func generateBenchCorpus(dir string, findings, files int, compression string, seed int64) (int64, error) {
+	r := rand.New(rand.NewSource(seed))
+	ext := map[string]string{"none": ".json", "gzip": ".json.gz", "zstd": ".json.zst"}[compression]
+	var size int64
+	for i := 0; i < files; i++ {
+		count := findings / files
+		if i < findings%files {
+			count++
+		}
+		written, err := writeBenchFile(filepath.Join(dir, fmt.Sprintf("bench-%03d%s", i, ext)), count, compression, r)
+		size += written
+		if err != nil {
+			return size, err
+		}
+	}
+	return size, nil
+}
+
+func writeBenchFile(path string, count int, compression string, r *rand.Rand) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var w io.Writer = file
+	var compressor io.WriteCloser
+	switch compression {
+	case "gzip":
+		compressor = gzip.NewWriter(file)
+	case "zstd":
+		if compressor, err = zstd.NewWriter(file); err != nil {
+			return 0, err
+		}
+	}
+	if compressor != nil {
+		w = compressor
+	}
+	buffered := bufio.NewWriter(w)
+	var size int64
+	for i := 0; i < count; i++ {
+		line, err := json.Marshal(benchFinding(r))
+		if err != nil {
+			return size, err
+		}
+		n, err := buffered.Write(append(line, '\n'))
+		size += int64(n)
+		if err != nil {
+			return size, err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return size, err
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return size, err
+		}
+	}
+	return size, file.Close()
+}
+
+// A finding shaped like trufflehog git output
+func benchFinding(r *rand.Rand) JSONData {
+	detector := benchDetectors[r.Intn(len(benchDetectors))]
+	secret := detector.secret(r)
+	orgs := []string{"acme", "acme-labs", "globex", "initech"}
+	repository := fmt.Sprintf("https://github.com/%s/service-%d.git", orgs[r.Intn(len(orgs))], r.Intn(50))
+	extensions := []string{".go", ".py", ".js", ".yaml", ".env", ".tf"}
+	file := fmt.Sprintf("%s/%s%s", []string{"src", "config", "deploy", "scripts"}[r.Intn(4)], randomString(r, "abcdefghijklmnopqrstuvwxyz", 8), extensions[r.Intn(len(extensions))])
+	author := fmt.Sprintf("dev%d", r.Intn(200))
+	return JSONData{
+		"SourceMetadata": map[string]interface{}{"Data": map[string]interface{}{"Github": map[string]interface{}{
+			"link":       "",
+			"repository": repository,
+			"commit":     randomString(r, "0123456789abcdef", 40),
+			"email":      fmt.Sprintf("%s <%s@%s.com>", author, author, orgs[r.Intn(len(orgs))]),
+			"file":       file,
+			"timestamp":  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(r.Intn(365*24)) * time.Hour).Format("2006-01-02 15:04:05 -0700"),
+			"line":       r.Intn(500) + 1,
+			"visibility": 0,
+		}}},
+		"SourceID":       1,
+		"SourceType":     16,
+		"SourceName":     "trufflehog - github",
+		"DetectorType":   r.Intn(1000),
+		"DetectorName":   detector.name,
+		"DecoderName":    "PLAIN",
+		"Verified":       r.Intn(10) == 0,
+		"Raw":            secret,
+		"RawV2":          "",
+		"Redacted":       secret[:min(len(secret), 8)],
+		"ExtraData":      nil,
+		"StructuredData": nil,
+	}
+}
+
+func randomString(r *rand.Rand, alphabet string, length int) string {
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(b)
+}
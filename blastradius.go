@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Where one secret was found
+type secretLocation struct {
+	Repository string `json:"repository,omitempty"`
+	File       string `json:"file,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	Line       int64  `json:"line,omitempty"`
+}
+
+// Every location of one unique secret
+type secretExposure struct {
+	Secret       string           `json:"secret"` // sha256 of the secret, never the value
+	Detector     string           `json:"detector"`
+	Redacted     string           `json:"redacted,omitempty"`
+	Verified     bool             `json:"verified"`
+	Repositories []string         `json:"repositories"`
+	Locations    []secretLocation `json:"locations"`
+	CrossRepo    bool             `json:"cross_repo"`
+	Compliance   []string         `json:"compliance"`
+}
+
+// List every repository, file and commit containing each unique secret,
+// putting secrets shared by several repositories first
+func runBlastRadius(args []string) {
+	fs := flag.NewFlagSet("blast-radius", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory or file containing JSON trufflehog output (required)")
+	outFile := fs.String("o", "", "Output file (default: stdout)")
+	asJSON := fs.Bool("json", false, "Write the report as JSON instead of text")
+	configPath := fs.String("config", defaultConfigPath(), "Path of the configuration file holding compliance mappings")
+	minRepos := fs.Int("min-repos", 0, "Only report secrets found in at least this many repositories (0 reports every secret)")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+	setComplianceConfig(cfg.Compliance)
+
+	files, err := listInputFiles(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	exposures := map[string]*secretExposure{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		fileHandle, err := openInput(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file %s: %v\n", file, err)
+			continue
+		}
+		name := filepath.Base(file)
+		err = scanFindings(fileHandle, func(lineNum int, data JSONData) {
+			f := flattenFinding(data, name, lineNum)
+			secret := f.RawV2
+			if secret == "" {
+				secret = f.Raw
+			}
+			if secret == "" {
+				return
+			}
+			key := hashValue(secret)
+			exposure, ok := exposures[key]
+			if !ok {
+				exposure = &secretExposure{Secret: key, Detector: f.DetectorName, Redacted: f.Redacted, Compliance: complianceTags(f.DetectorName)}
+				exposures[key] = exposure
+			}
+			exposure.Verified = exposure.Verified || f.Verified
+
+			location := secretLocation{Repository: f.Repository, File: f.File, Commit: f.Commit, Line: f.Line}
+			if location.File == "" {
+				location.File = name
+			}
+			locationKey := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d", key, location.Repository, location.File, location.Commit, location.Line)
+			if seen[locationKey] {
+				return
+			}
+			seen[locationKey] = true
+			exposure.Locations = append(exposure.Locations, location)
+			if location.Repository != "" && !containsString(exposure.Repositories, location.Repository) {
+				exposure.Repositories = append(exposure.Repositories, location.Repository)
+			}
+		}, func(lineNum int, err error) {
+			fmt.Fprintf(os.Stderr, "Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+		})
+		fileHandle.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", name, err)
+		}
+	}
+
+	var report []*secretExposure
+	for _, exposure := range exposures {
+		if len(exposure.Repositories) < *minRepos {
+			continue
+		}
+		exposure.CrossRepo = len(exposure.Repositories) > 1
+		sort.Strings(exposure.Repositories)
+		sort.Slice(exposure.Locations, func(i, j int) bool {
+			a, b := exposure.Locations[i], exposure.Locations[j]
+			if a.Repository != b.Repository {
+				return a.Repository < b.Repository
+			}
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Commit != b.Commit {
+				return a.Commit < b.Commit
+			}
+			return a.Line < b.Line
+		})
+		report = append(report, exposure)
+	}
+	// Widest blast radius first: most repositories, then most locations
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if len(a.Repositories) != len(b.Repositories) {
+			return len(a.Repositories) > len(b.Repositories)
+		}
+		if len(a.Locations) != len(b.Locations) {
+			return len(a.Locations) > len(b.Locations)
+		}
+		return a.Secret < b.Secret
+	})
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		fileHandle, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Printf("Error creating file %s: %v\n", *outFile, err)
+			os.Exit(1)
+		}
+		defer fileHandle.Close()
+		out = fileHandle
+	}
+
+	if *asJSON {
+		if report == nil {
+			report = []*secretExposure{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printBlastRadius(out, report)
+}
+
+// Write the text form of the blast-radius report
+func printBlastRadius(w io.Writer, report []*secretExposure) {
+	crossRepo := 0
+	for _, exposure := range report {
+		if exposure.CrossRepo {
+			crossRepo++
+		}
+		marker := ""
+		if exposure.CrossRepo {
+			marker = " [ROTATE FIRST]"
+		}
+		details := "unverified"
+		if exposure.Verified {
+			details = "verified"
+		}
+		if exposure.Redacted != "" {
+			details += ", " + exposure.Redacted
+		}
+		fmt.Fprintf(w, "%s %s (%s): %d repositories, %d locations%s\n", exposure.Secret[:19], exposure.Detector, details,
+			len(exposure.Repositories), len(exposure.Locations), marker)
+		fmt.Fprintf(w, "    controls: %s\n", strings.Join(exposure.Compliance, ", "))
+		for _, location := range exposure.Locations {
+			parts := []string{}
+			if location.Repository != "" {
+				parts = append(parts, location.Repository)
+			}
+			file := location.File
+			if location.Line > 0 {
+				file = fmt.Sprintf("%s:%d", file, location.Line)
+			}
+			parts = append(parts, file)
+			if location.Commit != "" {
+				commit := location.Commit
+				if len(commit) > 12 {
+					commit = commit[:12]
+				}
+				parts = append(parts, commit)
+			}
+			fmt.Fprintf(w, "    %s\n", strings.Join(parts, "  "))
+		}
+	}
+	fmt.Fprintf(w, "\n%d unique secrets, %d found in more than one repository\n", len(report), crossRepo)
+}
+
+// Report whether a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
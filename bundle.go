@@ -0,0 +1,204 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Fields holding secret material, always hashed in bundles
+var secretFields = map[string]bool{"Raw": true, "RawV2": true, "Redacted": true}
+
+// Fields identifying people or code bases, hashed together with secrets by --anonymize
+var identifyingFields = map[string]bool{
+	"email": true, "repository": true, "link": true, "commit": true, "file": true,
+	"username": true, "bucket": true, "image": true, "project": true,
+}
+
+// Package matched findings, the query and run metadata into a shareable tar.gz archive
+func runBundle(args []string) {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory containing JSON trufflehog output files (required)")
+	match := registerMatchFlags(fs)
+	outFile := fs.String("o", "bundle.tar.gz", "Output archive")
+	anonymize := fs.Bool("anonymize", false, "Also hash the query and identifying fields (emails, repositories, commits, files)")
+	fs.Parse(args)
+
+	if *inDir == "" || !match.hasCriteria() {
+		fmt.Println("Error: -i and -s (or another search flag such as -query or -detector) are required parameters.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	opts, err := match.options()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	s, err := searcher.New(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	files, err := listInputFiles(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	var findings bytes.Buffer
+	secrets := map[string]bool{}
+	scanned, matched := 0, 0
+	for _, file := range files {
+		fileHandle, err := openInput(file)
+		if err != nil {
+			fmt.Printf("Error opening file %s: %v\n", file, err)
+			continue
+		}
+		err = scanFindings(fileHandle, func(lineNum int, data JSONData) {
+			scanned++
+			if _, ok := s.Match(data); !ok {
+				return
+			}
+			matched++
+			if raw, ok := data["Raw"].(string); ok && raw != "" {
+				secrets[hashValue(raw)] = true
+			}
+			record := map[string]interface{}{"finding": anonymizeValue(data, *anonymize)}
+			if !*anonymize {
+				record["source_file"] = filepath.Base(file)
+				record["source_line"] = lineNum
+			}
+			line, _ := json.Marshal(record)
+			findings.Write(line)
+			findings.WriteByte('\n')
+		}, nil)
+		fileHandle.Close()
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", filepath.Base(file), err)
+		}
+	}
+
+	queryInfo := map[string]interface{}{"terms": opts.Terms, "mode": opts.Mode, "field": opts.Field, "normalize": opts.Normalize}
+	if opts.Mode == searcher.ModeNear {
+		queryInfo["near"], queryInfo["within"] = opts.Near, opts.Within
+	}
+	if opts.Query != "" {
+		queryInfo["query"] = opts.Query
+	}
+	if *anonymize {
+		hashed := make([]string, len(opts.Terms))
+		for i, term := range opts.Terms {
+			hashed[i] = hashValue(strings.ToLower(term))
+		}
+		queryInfo["terms"] = hashed
+		if opts.Near != "" {
+			queryInfo["near"] = hashValue(strings.ToLower(opts.Near))
+		}
+		if opts.Query != "" {
+			queryInfo["query"] = hashValue(opts.Query)
+		}
+	}
+	metadata := map[string]interface{}{
+		"tool":             "trufflehog-searcher",
+		"created_at":       time.Now().UTC().Format(time.RFC3339),
+		"anonymized":       *anonymize,
+		"files_scanned":    len(files),
+		"findings_scanned": scanned,
+		"findings_matched": matched,
+	}
+	hashes := make([]string, 0, len(secrets))
+	for hash := range secrets {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"findings.jsonl", findings.Bytes()},
+		{"query.json", marshalIndented(queryInfo)},
+		{"metadata.json", marshalIndented(metadata)},
+		{"secrets.sha256", []byte(strings.Join(hashes, "\n") + "\n")},
+	}
+
+	archive, err := os.Create(*outFile)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", *outFile, err)
+		os.Exit(1)
+	}
+	defer archive.Close()
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(header); err != nil {
+			fmt.Printf("Error writing archive: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			fmt.Printf("Error writing archive: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		fmt.Printf("Error writing archive: %v\n", err)
+		os.Exit(1)
+	}
+	if err := gz.Close(); err != nil {
+		fmt.Printf("Error writing archive: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Bundled %d matching findings (%d unique secrets) into %s\n", matched, len(hashes), *outFile)
+}
+
+// Hash a value so it can be correlated without being revealed
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Copy a JSON value, hashing secret fields and, when anonymizing, identifying fields too
+func anonymizeValue(value interface{}, anonymize bool) interface{} {
+	switch v := value.(type) {
+	case JSONData:
+		return anonymizeValue(map[string]interface{}(v), anonymize)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if text, ok := item.(string); ok && text != "" && (secretFields[key] || (anonymize && identifyingFields[key])) {
+				result[key] = hashValue(text)
+				continue
+			}
+			result[key] = anonymizeValue(item, anonymize)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = anonymizeValue(item, anonymize)
+		}
+		return result
+	}
+	return value
+}
+
+// Marshal a value as indented JSON for the bundle
+func marshalIndented(value interface{}) []byte {
+	data, _ := json.MarshalIndent(value, "", "  ")
+	return append(data, '\n')
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Results of earlier searches per file content, so -cache only parses the
+// lines that matched instead of every line of an unchanged file. Only line
+// numbers are stored; the lines are read again from the file, so no secret
+// is kept in the findings database.
+type resultCache struct {
+	db     *sql.DB
+	search string
+	mu     sync.Mutex // serializes the workers' writes
+	hits   int
+}
+
+// One stream of a cached file: the file itself, or an archive entry
+type cachedStream struct {
+	Name    string `json:"name"`
+	Lines   int64  `json:"lines"`
+	Records []int  `json:"records"` // lines that matched or could not be parsed
+}
+
+// Key of the cached results of a search. It covers the resolved search
+// options, so edited -terms-file contents give another key, but not the
+// -since/-until window: the findings of every commit time are cached and the
+// window of the run applies when they are replayed, so a relative age such
+// as 30d still hits the cache.
+func resultCacheKey(inputFormat string, jobFlags []*searchFlags) (string, error) {
+	hasher := sha256.New()
+	fmt.Fprintln(hasher, inputFormat)
+	for _, f := range jobFlags {
+		opts, err := f.options()
+		if err != nil {
+			return "", err
+		}
+		opts.Since, opts.Until = time.Time{}, time.Time{}
+		encoded, err := json.Marshal(opts)
+		if err != nil {
+			return "", err
+		}
+		hasher.Write(append(encoded, '\n'))
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func openResultCache(dbPath string, jobFlags []*searchFlags) (*resultCache, error) {
+	key, err := resultCacheKey(jobFlags[0].inputFormat, jobFlags)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", dbPath, err)
+	}
+	return &resultCache{db: db, search: key}, nil
+}
+
+// Hash a local file and look up its cached results
+func (c *resultCache) lookup(src inputSource) (string, []cachedStream, bool) {
+	hash, err := c.fileHash(src)
+	if err != nil {
+		return "", nil, false
+	}
+	var encoded string
+	err = c.db.QueryRow("SELECT streams FROM result_lines WHERE search = ? AND file_hash = ?", c.search, hash).Scan(&encoded)
+	if err != nil {
+		return hash, nil, false
+	}
+	var streams []cachedStream
+	if err := json.Unmarshal([]byte(encoded), &streams); err != nil {
+		return hash, nil, false
+	}
+	c.mu.Lock()
+	c.hits++
+	c.mu.Unlock()
+	return hash, streams, true
+}
+
+// Store the results of a file searched completely
+func (c *resultCache) store(hash string, streams []cachedStream) error {
+	encoded, err := json.Marshal(streams)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.db.Exec(`INSERT INTO result_lines(search, file_hash, streams, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(search, file_hash) DO UPDATE SET streams = excluded.streams, created_at = excluded.created_at`,
+		c.search, hash, string(encoded), time.Now().Unix())
+	return err
+}
+
+// Content hash of a local file. The hash is remembered with the size and
+// modification time of the file, so unchanged files are not read again.
+func (c *resultCache) fileHash(src inputSource) (string, error) {
+	path := absPath(src.path)
+	var size, mtime int64
+	var hash string
+	err := c.db.QueryRow("SELECT size, mtime, hash FROM file_hashes WHERE path = ?", path).Scan(&size, &mtime, &hash)
+	if err == nil && size == src.size && mtime == src.mtime.UnixNano() {
+		return hash, nil
+	}
+	if hash, err = hashFile(src.path); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.db.Exec(`INSERT INTO file_hashes(path, size, mtime, hash) VALUES (?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET size = excluded.size, mtime = excluded.mtime, hash = excluded.hash`,
+		path, src.size, src.mtime.UnixNano(), hash)
+	return hash, err
+}
+
+func (c *resultCache) Close() error {
+	return c.db.Close()
+}
+
+// Collects the results of one file while it is searched
+type cacheRecorder struct {
+	streams []cachedStream
+}
+
+func (r *cacheRecorder) startStream(name string) {
+	if r != nil {
+		r.streams = append(r.streams, cachedStream{Name: name})
+	}
+}
+
+// Keep the line of a processed record when a job's searcher matched it or it could not be parsed
+func (r *cacheRecorder) add(record *searcher.Record, hit bool) {
+	if r == nil {
+		return
+	}
+	stream := &r.streams[len(r.streams)-1]
+	stream.Lines++
+	if record.Err != nil || hit {
+		stream.Records = append(stream.Records, record.Line)
+	}
+}
+
+// Search a stream of a cached file again, parsing only the lines that
+// matched or failed before. They go through the jobs again, so ignore rules,
+// baselines, triage states and policies apply as they are now.
+func (p *pipeline) replayStream(ctx context.Context, name string, r io.Reader, cached cachedStream, outs []*sourceOutput, stats *fileMetrics) error {
+	r, err := adaptStream(p.inputFormat, r)
+	if err != nil {
+		return err
+	}
+	lines := make(map[int]bool, len(cached.Records))
+	for _, line := range cached.Records {
+		lines[line] = true
+	}
+	return searcher.Scan(ctx, r, p.scan, func(record *searcher.Record) processed {
+		if !lines[record.Line] {
+			return processed{skipped: true}
+		}
+		return p.process(ctx, name, record)
+	}, func(record *searcher.Record, result processed) error {
+		if result.skipped {
+			// The lines that matched nothing are only counted
+			p.metrics.addLines(stats, 1)
+			return nil
+		}
+		p.emit(name, record, result.matches, outs, stats)
+		return nil
+	})
+}
+
+// Largest output of a search stored whole; a larger one is only cached per file
+const queryCacheMaxBytes = 64 << 20
+
+// Whole outputs of earlier searches under the query and a fingerprint of the
+// corpus, so -cache answers a search repeated over unchanged inputs at once
+// without reading any of them
+type queryCache struct {
+	db     *sql.DB
+	search string
+	corpus string
+	stdout cappedBuffer
+	stderr cappedBuffer
+}
+
+// Whether the whole output of a search can be stored: one job writing only to
+// the terminal, over local files, whose output depends on nothing but the
+// query, the corpus and the findings database
+func queryCacheEligible(f *searchFlags, jobFlags []*searchFlags) bool {
+	if len(jobFlags) > 1 || f.inDir == "-" || isRemote(f.inDir) || f.outputFile != "" || f.outDir != "" || f.exportKind != "" ||
+		len(f.sinks) > 0 || f.notifyWebhook != "" || f.report != "" || f.graph != "" || f.metricsJSON != "" || f.manifest != "" || f.requireManifest != "" {
+		return false
+	}
+	if f.tui || f.watch || f.follow > 0 || f.changedSince != "" || f.enrichGit != "" || f.baseline != "" || f.expiry != "" || f.policyPath != "" {
+		return false
+	}
+	// The database never holds a secret in full
+	if f.showSecrets {
+		return false
+	}
+	// Colors depend on the terminal, and an age such as 30d on the day
+	if f.bySeverity && useColor(f.color, os.Stdout) {
+		return false
+	}
+	for _, bound := range []string{f.since, f.until} {
+		if _, err := parseAge(bound); bound != "" && err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Open the stored outputs of a search over the sources walk lists. The
+// search key covers every resolved flag, the configuration, the contents of
+// the -terms-file, -scope-file and ignore files, the named baseline and the
+// triage states; the corpus key covers the path, size and modification time
+// of every input file.
+func openQueryCache(dbPath string, f *searchFlags, cfg *config, walk func(fn func(inputSource) error) error) (*queryCache, error) {
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", dbPath, err)
+	}
+	q := &queryCache{db: db, stdout: cappedBuffer{limit: queryCacheMaxBytes}, stderr: cappedBuffer{limit: queryCacheMaxBytes}}
+	if q.search, err = q.searchKey(f, cfg); err == nil {
+		q.corpus, err = corpusKey(walk)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *queryCache) searchKey(f *searchFlags, cfg *config) (string, error) {
+	hasher := sha256.New()
+	fmt.Fprintln(hasher, toolVersion())
+	opts, err := f.options()
+	if err != nil {
+		return "", err
+	}
+	for _, value := range []interface{}{f.resolved, opts, cfg} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		hasher.Write(append(encoded, '\n'))
+	}
+	ignoreHash, _ := hashFile(f.ignoreFile)
+	fmt.Fprintln(hasher, ignoreHash)
+	if f.against != "" {
+		var created int64
+		q.db.QueryRow("SELECT created_at FROM baselines WHERE name = ?", f.against).Scan(&created)
+		fmt.Fprintln(hasher, created)
+	}
+
+	rows, err := q.db.Query("SELECT fingerprint, status, note FROM triage ORDER BY fingerprint")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fp, status, note string
+		if err := rows.Scan(&fp, &status, &note); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hasher, "%s\x00%s\x00%s\n", fp, status, note)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Fingerprint of the listed input files
+func corpusKey(walk func(fn func(inputSource) error) error) (string, error) {
+	hasher := sha256.New()
+	err := walk(func(src inputSource) error {
+		if src.path == "" {
+			return fmt.Errorf("%s is not a local file", src.name)
+		}
+		fmt.Fprintf(hasher, "%s\x00%d\x00%d\n", absPath(src.path), src.size, src.mtime.UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Write the stored output of the search to the terminal, returning its exit code
+func (q *queryCache) replay(now time.Time) (int, bool) {
+	var stdout, stderr []byte
+	var code int
+	var validUntil sql.NullInt64
+	err := q.db.QueryRow("SELECT stdout, stderr, exit_code, valid_until FROM query_cache WHERE search = ? AND corpus = ?", q.search, q.corpus).
+		Scan(&stdout, &stderr, &code, &validUntil)
+	if err != nil || (validUntil.Valid && now.Unix() >= validUntil.Int64) {
+		return 0, false
+	}
+	os.Stdout.Write(stdout)
+	os.Stderr.Write(stderr)
+	return code, true
+}
+
+// Streams recording what is written to them besides the terminal
+func (q *queryCache) record(streams outputStreams) outputStreams {
+	return outputStreams{
+		stdout: io.MultiWriter(streams.stdout, &q.stdout),
+		stderr: io.MultiWriter(streams.stderr, &q.stderr),
+	}
+}
+
+// Store the recorded output with the exit code of the search. An output
+// naming a secret that is still valid is kept until the secret expires, as
+// its expiry status changes then.
+func (q *queryCache) store(code int, validUntil time.Time) error {
+	if q.stdout.overflow || q.stderr.overflow {
+		return nil
+	}
+	var until sql.NullInt64
+	if !validUntil.IsZero() {
+		until = sql.NullInt64{Int64: validUntil.Unix(), Valid: true}
+	}
+	_, err := q.db.Exec(`INSERT INTO query_cache(search, corpus, stdout, stderr, exit_code, valid_until, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(search, corpus) DO UPDATE SET stdout = excluded.stdout, stderr = excluded.stderr, exit_code = excluded.exit_code,
+		valid_until = excluded.valid_until, created_at = excluded.created_at`,
+		q.search, q.corpus, q.stdout.Bytes(), q.stderr.Bytes(), code, until, time.Now().Unix())
+	return err
+}
+
+func (q *queryCache) Close() error {
+	return q.db.Close()
+}
+
+// Keeps what is written to it up to a limit, and nothing once it is exceeded
+type cappedBuffer struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.buf.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Controls touched by any hardcoded credential: protection of authentication
+// information and logical access security
+var baseComplianceControls = []string{"ISO 27001 A.5.17", "SOC 2 CC6.1"}
+
+// Additional controls per detector, keyed by lower-case detector name
+var builtinCompliance = map[string][]string{
+	// Payment processors reach the cardholder data environment, where
+	// application account credentials must not be hard coded
+	"stripe":       {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"square":       {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"paypaloauth":  {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"braintree":    {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"adyen":        {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"authorizenet": {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"checkout":     {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"razorpay":     {"PCI DSS 8.3.2", "PCI DSS 8.6.2"},
+	"aws":          {"SOC 2 CC6.6", "ISO 27001 A.8.2"},
+	"gcp":          {"SOC 2 CC6.6", "ISO 27001 A.8.2"},
+	"azure":        {"SOC 2 CC6.6", "ISO 27001 A.8.2"},
+	"postgres":     {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"mysql":        {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"mongodb":      {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"redis":        {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"jdbc":         {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"uri":          {"PCI DSS 8.6.2", "ISO 27001 A.8.3"},
+	"github":       {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"gitlab":       {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"bitbucket":    {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"privatekey":   {"ISO 27001 A.8.24", "PCI DSS 3.6.1"},
+	"npmtoken":     {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"pypi":         {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"dockerhub":    {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"artifactory":  {"ISO 27001 A.8.4", "SOC 2 CC8.1"},
+	"okta":         {"ISO 27001 A.5.16", "SOC 2 CC6.2"},
+	"auth0":        {"ISO 27001 A.5.16", "SOC 2 CC6.2"},
+	"vault":        {"ISO 27001 A.8.24", "SOC 2 CC6.1"},
+}
+
+// Controls from the compliance section of the configuration file, keyed by lower-case detector name
+var customCompliance = map[string][]string{}
+
+// Add the detector mappings of the configuration file to the built-in ones
+func setComplianceConfig(mappings map[string][]string) {
+	for detector, controls := range mappings {
+		key := strings.ToLower(detector)
+		customCompliance[key] = append(customCompliance[key], controls...)
+	}
+}
+
+// Sorted compliance controls a finding of the detector touches
+func complianceTags(detector string) []string {
+	key := strings.ToLower(detector)
+	seen := map[string]bool{}
+	var tags []string
+	for _, group := range [][]string{baseComplianceControls, builtinCompliance[key], customCompliance[key]} {
+		for _, control := range group {
+			if !seen[control] {
+				seen[control] = true
+				tags = append(tags, control)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings read from the configuration file
+type config struct {
+	Retention retentionConfig       `yaml:"retention"`
+	Alerts    []alertRule           `yaml:"alerts"`
+	Sinks     map[string]sinkConfig `yaml:"sinks"`
+	// Extra compliance controls per detector name, added to the built-in mapping
+	Compliance map[string][]string `yaml:"compliance"`
+	ExitCodes  exitCodeConfig      `yaml:"exit_codes"`
+	// Flag values used when a flag is not given, e.g. t: 8 or o: json
+	Defaults map[string]interface{} `yaml:"defaults"`
+	// Named searches run with -preset, each a map of flag names to values
+	Presets map[string]map[string]interface{} `yaml:"presets"`
+	// Ignore rules applied on top of the ignore file
+	Ignore []ignoreRule `yaml:"ignore"`
+	// Example credentials suppressed besides the built-in ones
+	ExampleSecrets []exampleSecret `yaml:"example_secrets"`
+	// Path globs of third-party code besides the built-in ones, e.g. extern/**
+	ThirdPartyPaths []string `yaml:"third_party_paths"`
+	// Names of DetectorType codes besides the built-in ones, e.g. 1001: MyCustomDetector
+	DetectorTypes map[int64]string `yaml:"detector_types"`
+}
+
+// How long imported findings are kept, e.g. "180d"
+type retentionConfig struct {
+	Default string            `yaml:"default"`
+	Corpora map[string]string `yaml:"corpora"`
+}
+
+// Default location of the configuration file
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "trufflehog-searcher", "config.yaml")
+}
+
+// Read the configuration file. A missing file yields an empty configuration.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	// Every command reading the configuration names the same codes
+	for code, name := range cfg.DetectorTypes {
+		customDetectorTypes[code] = name
+	}
+	return cfg, nil
+}
+
+// Names of DetectorType codes from the configuration, taking precedence over searcher.DetectorTypes
+var customDetectorTypes = map[int64]string{}
+
+// Code and name of the DetectorType of a finding; known is false when
+// neither the configuration nor the built-in table names the code, and the
+// name is then the DetectorName
+func detectorType(data JSONData) (code int64, name string, known bool) {
+	code, name, ok := searcher.DetectorType(data)
+	if !ok {
+		return 0, "", false
+	}
+	if custom, found := customDetectorTypes[code]; found {
+		return code, custom, true
+	}
+	_, known = searcher.DetectorTypes[code]
+	return code, name, known
+}
+
+// Parse an age such as "180d", "2w" or any time.ParseDuration value like "36h"
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Convert a whole directory of trufflehog output into another format
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory containing JSON trufflehog output files (required)")
+	from := fs.String("from", "trufflehog", "Input format: 'trufflehog', 'self' (result files written by -out-dir), 'trufflehog-legacy', 'trufflehog3', 'ggshield' or 'semgrep'")
+	to := fs.String("to", "", "Output format: "+strings.Join(outputFormats, ", ")+" (required)")
+	outFile := fs.String("o", "", "Output file (default: stdout)")
+	configPath := fs.String("config", defaultConfigPath(), "Path of the configuration file holding compliance mappings")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if !containsString(inputFormats, *from) {
+		fmt.Printf("Error: --from must be one of %s.\n", strings.Join(inputFormats, ", "))
+		os.Exit(1)
+	}
+
+	if *to == "" {
+		fmt.Println("Error: --to is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+	setComplianceConfig(cfg.Compliance)
+
+	files, err := listInputFiles(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		fileHandle, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Printf("Error creating file %s: %v\n", *outFile, err)
+			os.Exit(1)
+		}
+		defer fileHandle.Close()
+		out = fileHandle
+	}
+
+	writer, err := newFindingWriter(*to, out)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	converted := 0
+	for _, file := range files {
+		if err := convertFile(file, *from, writer, &converted); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", filepath.Base(file), err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Converted %d findings from %d files\n", converted, len(files))
+}
+
+// Write every finding of one file to the writer
+func convertFile(filePath, from string, writer findingWriter, converted *int) error {
+	fileHandle, err := openInput(filePath)
+	if err != nil {
+		return err
+	}
+	defer fileHandle.Close()
+
+	name := filepath.Base(filePath)
+	reader, err := adaptStream(from, fileHandle)
+	if err != nil {
+		return err
+	}
+	var writeErr error
+	err = scanFindings(reader, func(lineNum int, data JSONData) {
+		if writeErr != nil {
+			return
+		}
+		sourceFile, sourceLine := name, lineNum
+		if from == "self" {
+			record, ok := unwrapResult(data)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error parsing result at line %d in file %s: missing finding\n", lineNum, name)
+				return
+			}
+			data, sourceFile, sourceLine = record.Finding, record.SourceFile, record.SourceLine
+		}
+		if adapt := findingAdapters[from]; adapt != nil {
+			finding, err := adapt(data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing finding at line %d in file %s: %v\n", lineNum, name, err)
+				return
+			}
+			data = finding
+		}
+		if writeErr = writer.WriteFinding(data, sourceFile, sourceLine); writeErr == nil {
+			*converted++
+		}
+	}, func(lineNum int, err error) {
+		fmt.Fprintf(os.Stderr, "Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
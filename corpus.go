@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// A parsed line kept in memory together with where it came from
+type corpusEntry struct {
+	line     int
+	data     JSONData
+	parseErr error
+}
+
+// All findings of one input file
+type corpusFile struct {
+	name    string
+	entries []corpusEntry
+	readErr error
+}
+
+// Size and modification time of an input file when it was loaded
+type corpusStamp struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+// Input directory parsed once and held in memory for repeated searches
+type corpus struct {
+	dir      string
+	files    []corpusFile
+	stamps   []corpusStamp
+	findings int
+}
+
+// Parse every input file of a directory into memory
+func loadCorpus(dir string) (*corpus, error) {
+	files, err := listInputFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &corpus{dir: dir}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			loaded.stamps = append(loaded.stamps, corpusStamp{file, info.Size(), info.ModTime()})
+		}
+		fileHandle, err := openInput(file)
+		if err != nil {
+			fmt.Printf("Error opening file %s: %v\n", file, err)
+			continue
+		}
+		cf := corpusFile{name: filepath.Base(file)}
+		cf.readErr = scanFindings(fileHandle, func(lineNum int, data JSONData) {
+			cf.entries = append(cf.entries, corpusEntry{line: lineNum, data: data})
+			loaded.findings++
+		}, func(lineNum int, err error) {
+			cf.entries = append(cf.entries, corpusEntry{line: lineNum, parseErr: err})
+		})
+		fileHandle.Close()
+		loaded.files = append(loaded.files, cf)
+	}
+	return loaded, nil
+}
+
+// Whether input files were added, removed or modified since the corpus was loaded
+func (c *corpus) changed() bool {
+	files, err := listInputFiles(c.dir)
+	if err != nil {
+		return true
+	}
+	stamps := make([]corpusStamp, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return true
+		}
+		stamps = append(stamps, corpusStamp{file, info.Size(), info.ModTime()})
+	}
+	return !slices.EqualFunc(stamps, c.stamps, func(a, b corpusStamp) bool {
+		return a.path == b.path && a.size == b.size && a.mtime.Equal(b.mtime)
+	})
+}
+
+// Search the in-memory corpus, writing the same output as a search over the files.
+// Secrets are masked in the given fields and known example secrets are left out.
+// Returns the number of matching findings.
+func (c *corpus) search(w io.Writer, s *searcher.Searcher, field string, redact []string, multiTerm bool) int {
+	matches := 0
+	for _, cf := range c.files {
+		fmt.Fprintf(w, "\n--- Searching in file: %s ---\n", cf.name)
+		for _, entry := range cf.entries {
+			if entry.parseErr != nil {
+				fmt.Fprintf(w, "Error parsing JSON at line %d in file %s: %v\n", entry.line, cf.name, entry.parseErr)
+				continue
+			}
+			terms, ok := s.Match(entry.data)
+			if !ok || builtinExamples.match(entry.data) != "" {
+				continue
+			}
+			m := &searchMatch{location: fmt.Sprintf("line %d", entry.line), data: entry.data, terms: terms, link: permalink(entry.data),
+				falsePositive: scoreFalsePositive(entry.data), thirdParty: builtinThirdParty.match(entry.data),
+				fieldPath: resolvedField(entry.data, field)}
+			data := entry.data
+			if redact != nil {
+				data = searcher.Redact(data, redact)
+			}
+			writeTextMatch(w, m, data, multiTerm)
+			matches++
+		}
+		if cf.readErr != nil {
+			fmt.Fprintf(w, "Error reading file %s: %v\n", cf.name, cf.readErr)
+		}
+	}
+	return matches
+}
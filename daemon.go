@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Query sent by the CLI to a keep-alive daemon
+type daemonRequest struct {
+	Options searcher.Options `json:"options"`
+	Redact  []string         `json:"redact"` // fields to mask, nil with -show-secrets
+}
+
+// Separates the search output of a daemon from the number of matches that follows it
+const daemonCountMarker = 0
+
+// Directory of the daemon sockets, private to the user so nobody else can
+// listen in place of their daemon: $XDG_RUNTIME_DIR, or one under the user
+// cache directory
+func daemonSocketDir() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "trufflehog-searcher", "daemon")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() || !privateDir(info) {
+		return "", fmt.Errorf("%s is not a directory only you can access", dir)
+	}
+	return dir, nil
+}
+
+// Unix socket of the daemon serving an input directory
+func daemonSocketPath(inDir string) (string, error) {
+	dir, err := daemonSocketDir()
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(inDir)
+	if err != nil {
+		absDir = inDir
+	}
+	sum := sha256.Sum256([]byte(absDir))
+	return filepath.Join(dir, fmt.Sprintf("trufflehog-searcher-%s.sock", hex.EncodeToString(sum[:8]))), nil
+}
+
+// Load the corpus once and answer queries over a unix socket until interrupted
+func runKeepAlive(inDir string) {
+	start := time.Now()
+	loaded, err := loadCorpus(inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	socketPath, err := daemonSocketPath(inDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// A socket nobody answers on is left over from a daemon that did not shut down cleanly
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		fmt.Printf("Error: a daemon is already serving %s on %s\n", inDir, socketPath)
+		os.Exit(1)
+	}
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		fmt.Printf("Error listening on %s: %v\n", socketPath, err)
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Printf("Serving %d findings from %d files (loaded in %s) on %s\n", loaded.findings, len(loaded.files), time.Since(start).Round(time.Millisecond), socketPath)
+	served := &daemonCorpus{loaded: loaded}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		go serveDaemonQuery(conn, served)
+	}
+	os.Remove(socketPath)
+}
+
+// Corpus of a daemon, loaded again when its files change
+type daemonCorpus struct {
+	mu     sync.Mutex
+	loaded *corpus
+}
+
+// The corpus as the files are now
+func (d *daemonCorpus) current() (*corpus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.loaded.changed() {
+		start := time.Now()
+		loaded, err := loadCorpus(d.loaded.dir)
+		if err != nil {
+			return nil, err
+		}
+		d.loaded = loaded
+		fmt.Printf("Reloaded %d findings from %d files in %s\n", loaded.findings, len(loaded.files), time.Since(start).Round(time.Millisecond))
+	}
+	return d.loaded, nil
+}
+
+// Answer one query with the same output a search over the files would print,
+// followed by the number of matches
+func serveDaemonQuery(conn net.Conn, served *daemonCorpus) {
+	defer conn.Close()
+	var request daemonRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		fmt.Fprintf(conn, "Error decoding query: %v\n", err)
+		return
+	}
+	out := bufio.NewWriter(conn)
+	loaded, err := served.current()
+	if err != nil {
+		fmt.Fprintf(out, "Error reading directory: %v\n", err)
+		out.Flush()
+		return
+	}
+	s, err := searcher.New(request.Options)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		out.Flush()
+		return
+	}
+	matches := loaded.search(out, s, request.Options.Field, request.Redact, len(request.Options.Terms) > 1)
+	out.WriteByte(daemonCountMarker)
+	fmt.Fprintf(out, "%d\n", matches)
+	out.Flush()
+}
+
+// Send the query to a running daemon for the input directory, copying its answer to stdout.
+// Returns the number of matches, and false when no daemon is available so the caller
+// can search the files itself. Only a socket of the user is trusted.
+func queryDaemon(inDir string, request daemonRequest) (int, bool) {
+	socketPath, err := daemonSocketPath(inDir)
+	if err != nil {
+		return 0, false
+	}
+	if info, err := os.Lstat(socketPath); err != nil || info.Mode()&os.ModeSocket == 0 || !ownedByUser(info) {
+		return 0, false
+	}
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return 0, false
+	}
+	answer := bufio.NewReader(conn)
+	output, err := answer.ReadBytes(daemonCountMarker)
+	os.Stdout.Write(bytes.TrimSuffix(output, []byte{daemonCountMarker}))
+	if err != nil {
+		if err != io.EOF {
+			fmt.Printf("Error reading from daemon: %v\n", err)
+		}
+		outcome.errors.Add(1)
+		return 0, true
+	}
+	var matches int
+	fmt.Fscan(answer, &matches)
+	return matches, true
+}
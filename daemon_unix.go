@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Whether a file belongs to the user running the program
+func ownedByUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
+
+// Whether only the user running the program can access a directory
+func privateDir(info os.FileInfo) bool {
+	return ownedByUser(info) && info.Mode().Perm()&0o077 == 0
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Whether a file belongs to the user running the program. The socket
+// directory is under the profile of the user, which others cannot write to.
+func ownedByUser(info os.FileInfo) bool {
+	return true
+}
+
+func privateDir(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Schema of the persistent findings database
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS imported_files (
+	hash        TEXT PRIMARY KEY,
+	path        TEXT NOT NULL,
+	corpus      TEXT NOT NULL,
+	imported_at INTEGER NOT NULL,
+	findings    INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS findings (
+	id          INTEGER PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	corpus      TEXT NOT NULL,
+	file_hash   TEXT NOT NULL,
+	source_file TEXT NOT NULL,
+	source_line INTEGER NOT NULL,
+	detector    TEXT,
+	repository  TEXT,
+	commit_hash TEXT,
+	file        TEXT,
+	line        INTEGER,
+	verified    INTEGER,
+	timestamp   TEXT,
+	raw_hash    TEXT,
+	data        TEXT NOT NULL,
+	imported_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS findings_fingerprint ON findings(fingerprint);
+CREATE INDEX IF NOT EXISTS findings_corpus ON findings(corpus, imported_at);
+CREATE TABLE IF NOT EXISTS searched_files (
+	search      TEXT NOT NULL,
+	path        TEXT NOT NULL,
+	size        INTEGER NOT NULL,
+	mtime       INTEGER NOT NULL,
+	searched_at INTEGER NOT NULL,
+	PRIMARY KEY (search, path)
+);
+-- result_cache held the matching lines themselves, secrets included
+DROP TABLE IF EXISTS result_cache;
+CREATE TABLE IF NOT EXISTS result_lines (
+	search      TEXT NOT NULL,
+	file_hash   TEXT NOT NULL,
+	streams     TEXT NOT NULL,
+	created_at  INTEGER NOT NULL,
+	PRIMARY KEY (search, file_hash)
+);
+CREATE TABLE IF NOT EXISTS query_cache (
+	search      TEXT NOT NULL,
+	corpus      TEXT NOT NULL,
+	stdout      BLOB NOT NULL,
+	stderr      BLOB NOT NULL,
+	exit_code   INTEGER NOT NULL,
+	valid_until INTEGER,
+	created_at  INTEGER NOT NULL,
+	PRIMARY KEY (search, corpus)
+);
+CREATE TABLE IF NOT EXISTS file_hashes (
+	path        TEXT PRIMARY KEY,
+	size        INTEGER NOT NULL,
+	mtime       INTEGER NOT NULL,
+	hash        TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS baselines (
+	name        TEXT PRIMARY KEY,
+	source      TEXT NOT NULL,
+	findings    INTEGER NOT NULL,
+	created_at  INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS baseline_findings (
+	baseline    TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	data        TEXT NOT NULL,
+	PRIMARY KEY (baseline, fingerprint)
+);
+CREATE TABLE IF NOT EXISTS triage (
+	fingerprint TEXT PRIMARY KEY,
+	status      TEXT NOT NULL,
+	note        TEXT NOT NULL DEFAULT '',
+	updated_at  INTEGER NOT NULL
+);
+`
+
+// Default location of the findings database
+func defaultDBPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "trufflehog-searcher", "findings.db")
+}
+
+// Open (and create if needed) the findings database
+func openDB(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	// WAL lets searches read while findings are written, and writers wait
+	// for each other up to 5s instead of failing with "database is locked"
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(dbSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Manage the persistent findings database
+func runDB(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Error: expected a db command: import or purge")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "import":
+		runDBImport(args[1:])
+	case "purge":
+		runDBPurge(args[1:])
+	default:
+		fmt.Printf("Error: unknown db command %q (expected import or purge)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// Load trufflehog output into the database, skipping files that were already imported
+func runDBImport(args []string) {
+	fs := flag.NewFlagSet("db import", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory or file containing JSON trufflehog output (required)")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	corpusName := fs.String("corpus", "", "Corpus name recorded with the findings (default: name of the -i directory)")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	lock, err := lockDB(context.Background(), *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	importInputs(db, *inDir, *corpusName)
+}
+
+// Import every input file of a directory, skipping files that were already imported
+func importInputs(db *sql.DB, inDir, corpusName string) {
+	if corpusName == "" {
+		absDir, _ := filepath.Abs(inDir)
+		corpusName = filepath.Base(absDir)
+	}
+
+	files, err := listInputFiles(inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	imported, skipped, findings := 0, 0, 0
+	for _, file := range files {
+		count, err := importFile(db, file, corpusName)
+		switch {
+		case err == errAlreadyImported:
+			skipped++
+		case err != nil:
+			fmt.Printf("Error importing file %s: %v\n", filepath.Base(file), err)
+		default:
+			imported++
+			findings += count
+		}
+	}
+	fmt.Printf("Imported %d findings from %d files into corpus %q (%d files already imported)\n", findings, imported, corpusName, skipped)
+}
+
+// Returned by importFile for files whose content is already in the database
+var errAlreadyImported = fmt.Errorf("file already imported")
+
+// Hash a file's raw content
+func hashFile(filePath string) (string, error) {
+	fileHandle, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer fileHandle.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, fileHandle); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Import one file in a single transaction and return the number of findings stored
+func importFile(db *sql.DB, filePath, corpusName string) (int, error) {
+	hash, err := hashFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM imported_files WHERE hash = ?`, hash).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		return 0, errAlreadyImported
+	}
+
+	fileHandle, err := openInput(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer fileHandle.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	name := filepath.Base(filePath)
+	count := 0
+	var insertErr error
+	err = scanFindings(fileHandle, func(lineNum int, data JSONData) {
+		if insertErr != nil {
+			return
+		}
+		insertErr = insertFinding(tx, data, corpusName, hash, name, lineNum, now)
+		count++
+	}, func(lineNum int, err error) {
+		fmt.Printf("Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if insertErr != nil {
+		return 0, insertErr
+	}
+
+	if _, err := tx.Exec(`INSERT INTO imported_files (hash, path, corpus, imported_at, findings) VALUES (?, ?, ?, ?, ?)`,
+		hash, filePath, corpusName, now, count); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// Store one finding with its indexed columns
+func insertFinding(tx *sql.Tx, data JSONData, corpusName, fileHash, sourceFile string, sourceLine int, importedAt int64) error {
+	flat := flattenFinding(data, sourceFile, sourceLine)
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	rawHash := ""
+	if flat.Raw != "" {
+		rawHash = hashValue(flat.Raw)
+	}
+	_, err = tx.Exec(`INSERT INTO findings (fingerprint, corpus, file_hash, source_file, source_line, detector, repository,
+		commit_hash, file, line, verified, timestamp, raw_hash, data, imported_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fingerprint(data, occurrenceFields), corpusName, fileHash, sourceFile, sourceLine, flat.DetectorName, flat.Repository,
+		flat.Commit, flat.File, flat.Line, flat.Verified, flat.Timestamp, rawHash, string(encoded), importedAt)
+	return err
+}
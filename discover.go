@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Longest example value printed by -l
+const exampleValueLength = 60
+
+// Stops listing the input once -sample findings were read
+var errSampleComplete = errors.New("sample complete")
+
+// Print the fields that can be searched with -f. Without an input the common
+// trufflehog fields are listed; with -i the fields are discovered from up to
+// sample findings of the input, with their counts and an example value.
+func printSearchableFields(ctx context.Context, f *searchFlags, filter inputFilter) error {
+	if f.inDir == "" {
+		printCommonFields()
+		return nil
+	}
+
+	var redact []string
+	if !f.showSecrets {
+		redact = splitList(f.redactFields)
+	}
+	profile, err := profileFields(ctx, f.inDir, filter, f.inputFormat, redact, f.sample, 1)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Searchable Fields found in %d finding(s) (case-sensitive):\n", profile.findings)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Field\tCount\tExample")
+	for _, path := range profile.paths() {
+		field := profile.fields[path]
+		fmt.Fprintf(w, "%s\t%d\t%s\n", path, field.count, field.samples[0])
+	}
+	return w.Flush()
+}
+
+// Shape of the findings of an input: the fields found and their values
+type fieldProfile struct {
+	findings   int
+	maxSamples int
+	fields     map[string]*fieldStats
+}
+
+// How one field is filled across the findings
+type fieldStats struct {
+	count   int
+	types   map[string]int // JSON type name to count
+	samples []string       // distinct values, in order of appearance
+}
+
+// Read the fields of up to sample findings of an input (all of them for 0),
+// keeping up to maxSamples distinct values per field
+func profileFields(ctx context.Context, input string, filter inputFilter, inputFormat string, redact []string, sample, maxSamples int) (*fieldProfile, error) {
+	profile := &fieldProfile{maxSamples: maxSamples, fields: map[string]*fieldStats{}}
+	complete := func() bool { return sample > 0 && profile.findings >= sample }
+
+	// The listing stops once the sample is complete
+	err := walkInputs(ctx, input, filter, func(src inputSource) error {
+		if complete() {
+			return errSampleComplete
+		}
+		err := readSource(ctx, src, filter, func(name string, r io.Reader) error {
+			r, err := adaptStream(inputFormat, r)
+			if err != nil {
+				return err
+			}
+			return scanFindings(r, func(lineNum int, data JSONData) {
+				if complete() {
+					return
+				}
+				if result, ok := unwrapResult(data); ok && inputFormat == "self" {
+					data = result.Finding
+				}
+				if adapt := findingAdapters[inputFormat]; adapt != nil {
+					finding, err := adapt(data)
+					if err != nil {
+						return
+					}
+					data = finding
+				}
+				if redact != nil {
+					data = searcher.Redact(data, redact)
+				}
+				profile.add(data)
+			}, nil)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", src.name, err)
+		}
+		return nil
+	})
+	if err != nil && err != errSampleComplete {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Record the fields of one finding
+func (p *fieldProfile) add(data JSONData) {
+	p.findings++
+	searcher.Walk(data, p.record)
+	// Composite credentials also expose their components
+	raw, _ := data["RawV2"].(string)
+	for component, value := range searcher.ParseRawV2(raw) {
+		p.record("rawv2."+component, value)
+	}
+}
+
+func (p *fieldProfile) record(path string, value interface{}) {
+	field, ok := p.fields[path]
+	if !ok {
+		field = &fieldStats{types: map[string]int{}}
+		p.fields[path] = field
+	}
+	field.count++
+	field.types[jsonTypeName(value)]++
+	if len(field.samples) < p.maxSamples {
+		if example := exampleValue(value); !containsString(field.samples, example) {
+			field.samples = append(field.samples, example)
+		}
+	}
+}
+
+// Field paths in name order
+func (p *fieldProfile) paths() []string {
+	paths := make([]string, 0, len(p.fields))
+	for path := range p.fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Name of the JSON type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// A value shortened to one line for the -l listing
+func exampleValue(value interface{}) string {
+	text := strings.Join(strings.Fields(searcher.String(value)), " ")
+	if len(text) > exampleValueLength {
+		text = text[:exampleValueLength-3] + "..."
+	}
+	return text
+}
+
+// Print the fields common to trufflehog findings
+func printCommonFields() {
+	fields := []string{
+		"DecoderName", "DetectorDescription", "DetectorName", "DetectorType", "project", "rotation_guide",
+		"Raw", "RawV2", "Redacted", "SourceID", "commit", "email", "file", "line", "link",
+		"repository", "timestamp", "SourceName", "SourceType", "StructuredData", "VerificationFromCache", "Verified",
+	}
+
+	fmt.Println("Searchable Fields (case-sensitive):")
+	fmt.Println(strings.Repeat("-", 40))
+	for _, field := range fields {
+		fmt.Printf("- %s\n", field)
+	}
+	for _, component := range searcher.RawV2Components {
+		fmt.Printf("- rawv2.%s\n", component)
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Println("Fields are found under SourceMetadata.Data for every source type; use -l with -i to list the fields of your input.")
+}
+
+// One field in the JSON output of explore
+type exploredField struct {
+	Field   string         `json:"field"`
+	Count   int            `json:"count"`
+	Fill    float64        `json:"fill"` // share of the findings holding the field
+	Types   map[string]int `json:"types"`
+	Samples []string       `json:"samples"`
+}
+
+// Show the shape of an input before querying it: per field, how often it is
+// filled, the JSON types of its values and a few sample values
+func runExplore(args []string) {
+	fs := flag.NewFlagSet("explore", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory or file containing JSON trufflehog output (required)")
+	recursive := fs.Bool("r", false, "Explore subdirectories of -i")
+	sample := fs.Int("sample", 10000, "Number of findings read (0 reads every finding)")
+	values := fs.Int("values", 3, "Number of distinct sample values shown per field")
+	inputFormat := fs.String("input-format", "trufflehog", "Input format: 'trufflehog', 'self' (result files written by -out-dir or -o json), 'trufflehog-legacy' (trufflehog 2 --json or --json-legacy), 'trufflehog3', 'ggshield' (ggshield secret scan --json) or 'semgrep' (semgrep --json)")
+	showSecrets := fs.Bool("show-secrets", false, "Show secret values in full instead of masking them")
+	asJSON := fs.Bool("json", false, "Write the fields as JSON instead of a table")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if !containsString(inputFormats, *inputFormat) {
+		fmt.Printf("Error: -input-format must be one of %s.\n", strings.Join(inputFormats, ", "))
+		os.Exit(1)
+	}
+	redact := searcher.DefaultRedactFields
+	if *showSecrets {
+		redact = nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	profile, err := profileFields(ctx, *inDir, inputFilter{recursive: *recursive}, *inputFormat, redact, *sample, *values)
+	if err != nil {
+		fmt.Printf("Error reading input: %v\n", err)
+		os.Exit(1)
+	}
+
+	fields := make([]exploredField, 0, len(profile.fields))
+	for _, path := range profile.paths() {
+		field := profile.fields[path]
+		fields = append(fields, exploredField{Field: path, Count: field.count, Fill: float64(field.count) / float64(profile.findings),
+			Types: field.types, Samples: field.samples})
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(fields); err != nil {
+			fmt.Printf("Error writing fields: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("%d field(s) found in %d finding(s):\n", len(fields), profile.findings)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Field\tFill\tTypes\tSamples")
+	for _, field := range fields {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\n", field.Field, 100*field.Fill, typeDistribution(field.Types, field.Count), strings.Join(field.Samples, " | "))
+	}
+	w.Flush()
+}
+
+// Types of a field's values, most common first, with their share when mixed
+func typeDistribution(types map[string]int, count int) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) == 1 {
+		return names[0]
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %.0f%%", name, 100*float64(types[name])/float64(count))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Lines of code shown on each side of a finding by -enrich-git
+const blameContextLines = 3
+
+// Author of the line holding a finding and the code around it, read from a local clone
+type blameInfo struct {
+	Author  string        `json:"author"`
+	Email   string        `json:"email,omitempty"`
+	Time    time.Time     `json:"time"`
+	Context []contextLine `json:"context,omitempty"`
+}
+
+type contextLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Link to the line of a git finding on its host. Findings carrying their own
+// link, and sources whose host is unknown, get none.
+func permalink(data JSONData) string {
+	if link, _ := searcher.Lookup(data, "link"); searcher.String(link) != "" {
+		return ""
+	}
+	repository, commit, file, line := gitLocation(data)
+	if repository == "" || commit == "" || file == "" || line <= 0 {
+		return ""
+	}
+	repository = webRepository(repository)
+	switch {
+	case strings.Contains(repository, "github"):
+		return fmt.Sprintf("%s/blob/%s/%s#L%d", repository, commit, file, line)
+	case strings.Contains(repository, "gitlab"):
+		return fmt.Sprintf("%s/-/blob/%s/%s#L%d", repository, commit, file, line)
+	}
+	return ""
+}
+
+// Web address of a clone URL: scp-like and ssh:// URLs become https, and
+// credentials embedded in the URL are dropped so they do not end up in links
+func webRepository(repository string) string {
+	repository = strings.TrimSuffix(strings.TrimSuffix(repository, "/"), ".git")
+	if !strings.Contains(repository, "://") {
+		// git@github.com:acme/api
+		if _, rest, ok := strings.Cut(repository, "@"); ok {
+			if host, path, ok := strings.Cut(rest, ":"); ok {
+				return "https://" + host + "/" + path
+			}
+		}
+		return repository
+	}
+	u, err := url.Parse(repository)
+	if err != nil {
+		return repository
+	}
+	if u.Scheme == "ssh" || u.Scheme == "git" {
+		u.Scheme, u.Host = "https", u.Hostname()
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Repository, commit, file and line of a finding from a git source
+func gitLocation(data JSONData) (string, string, string, int) {
+	text := func(field string) string {
+		value, _ := searcher.Lookup(data, field)
+		return searcher.String(value)
+	}
+	line, _ := strconv.Atoi(text("line"))
+	return text("repository"), text("commit"), text("file"), line
+}
+
+// Run git blame on the line of a finding in a local clone and read the lines around it
+func gitBlame(ctx context.Context, clone string, data JSONData) (*blameInfo, error) {
+	_, commit, file, line := gitLocation(data)
+	if commit == "" || file == "" || line <= 0 {
+		return nil, nil
+	}
+	porcelain, err := exec.CommandContext(ctx, "git", "-C", clone, "blame", "--porcelain",
+		"-L", fmt.Sprintf("%d,%d", line, line), commit, "--", file).Output()
+	if err != nil {
+		return nil, gitError(err)
+	}
+
+	info := &blameInfo{}
+	scanner := bufio.NewScanner(bytes.NewReader(porcelain))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "author":
+			info.Author = value
+		case "author-mail":
+			info.Email = strings.Trim(value, "<>")
+		case "author-time":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.Time = time.Unix(seconds, 0).UTC()
+			}
+		}
+	}
+
+	content, err := exec.CommandContext(ctx, "git", "-C", clone, "show", commit+":"+file).Output()
+	if err != nil {
+		return info, gitError(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	for n := max(1, line-blameContextLines); n <= min(len(lines), line+blameContextLines); n++ {
+		info.Context = append(info.Context, contextLine{Line: n, Text: lines[n-1]})
+	}
+	return info, nil
+}
+
+// Include what git printed on stderr in its errors
+func gitError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("git: %s", bytes.TrimSpace(exitErr.Stderr))
+	}
+	return err
+}
+
+// Print the blame of a finding followed by its surrounding code
+func writeBlame(w io.Writer, b *blameInfo) {
+	author := b.Author
+	if b.Email != "" {
+		author += " <" + b.Email + ">"
+	}
+	fmt.Fprintf(w, "--- Blame: %s, %s ---\n", author, b.Time.Format(time.DateOnly))
+	for _, line := range b.Context {
+		fmt.Fprintf(w, "%6d | %s\n", line.Line, line.Text)
+	}
+}
+
+// Mask the secret wherever it appears in the surrounding code
+func (b *blameInfo) redact(secret string) {
+	if secret == "" {
+		return
+	}
+	for i := range b.Context {
+		b.Context[i].Text = strings.ReplaceAll(b.Context[i].Text, secret, searcher.Mask(secret))
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Bulk-indexes findings into Elasticsearch or OpenSearch. Documents are keyed by
+// occurrence fingerprint, so exporting the same input again updates them in place.
+type bulkIndexer struct {
+	url      string
+	index    string
+	batch    int
+	retries  int
+	user     string
+	password string
+	apiKey   string
+
+	body    bytes.Buffer
+	pending int
+	indexed int
+	failed  int
+}
+
+// Queue one document, sending the batch once it is full
+func (b *bulkIndexer) add(ctx context.Context, id string, doc interface{}) error {
+	action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": b.index, "_id": id}})
+	source, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	b.body.Write(action)
+	b.body.WriteByte('\n')
+	b.body.Write(source)
+	b.body.WriteByte('\n')
+	b.pending++
+	if b.pending < b.batch {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+// Send the queued documents, retrying with backoff on connection errors,
+// 429 Too Many Requests and server errors
+func (b *bulkIndexer) flush(ctx context.Context) error {
+	if b.pending == 0 {
+		return nil
+	}
+	payload := append([]byte(nil), b.body.Bytes()...)
+	count := b.pending
+	b.body.Reset()
+	b.pending = 0
+
+	var lastErr error
+	for attempt := 0; attempt <= b.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<(attempt-1)) * time.Second):
+			}
+		}
+		response, err := b.post(ctx, payload)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+			message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+			response.Body.Close()
+			lastErr = fmt.Errorf("%s: %s", response.Status, bytes.TrimSpace(message))
+			continue
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= 300 {
+			message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+			return fmt.Errorf("%s: %s", response.Status, bytes.TrimSpace(message))
+		}
+		return b.readResult(response.Body, count)
+	}
+	b.failed += count
+	return fmt.Errorf("bulk request failed after %d attempt(s): %w", b.retries+1, lastErr)
+}
+
+func (b *bulkIndexer) post(ctx context.Context, payload []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(b.url, "/")+"/_bulk", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case b.apiKey != "":
+		request.Header.Set("Authorization", "ApiKey "+b.apiKey)
+	case b.user != "":
+		request.SetBasicAuth(b.user, b.password)
+	}
+	return http.DefaultClient.Do(request)
+}
+
+// Count the documents of a bulk response. Rejected documents are reported
+// without stopping the export.
+func (b *bulkIndexer) readResult(body io.Reader, count int) error {
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding bulk response: %w", err)
+	}
+	if !result.Errors {
+		b.indexed += count
+		return nil
+	}
+	rejected := 0
+	var firstErr json.RawMessage
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status < 300 {
+				b.indexed++
+				continue
+			}
+			rejected++
+			if firstErr == nil {
+				firstErr = outcome.Error
+			}
+		}
+	}
+	b.failed += rejected
+	fmt.Printf("Error: %d document(s) rejected, first error: %s\n", rejected, firstErr)
+	return nil
+}
+
+// Export findings, optionally only those matching the search flags, to Elasticsearch or OpenSearch
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory, file, '-' for stdin, or s3://, gs:// or http(s):// location of JSON trufflehog output (required)")
+	recursive := fs.Bool("r", false, "Export subdirectories of -i")
+	match := registerMatchFlags(fs)
+	url := fs.String("url", "", "Elasticsearch or OpenSearch URL, e.g. https://localhost:9200 (required)")
+	index := fs.String("index", "trufflehog-findings", "Index receiving the findings")
+	batch := fs.Int("batch", 500, "Findings sent per bulk request")
+	retries := fs.Int("retries", 3, "Retries of a failed bulk request")
+	user := fs.String("user", "", "User for basic authentication")
+	password := fs.String("password", os.Getenv("THS_ES_PASSWORD"), "Password for basic authentication (default $THS_ES_PASSWORD)")
+	apiKey := fs.String("api-key", os.Getenv("THS_ES_API_KEY"), "Base64 API key, sent instead of basic authentication (default $THS_ES_API_KEY)")
+	showSecrets := fs.Bool("show-secrets", false, "Index secret values in full instead of masking them")
+	fs.Parse(args)
+
+	if *inDir == "" || *url == "" {
+		fmt.Println("Error: -i and -url are required parameters.")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *batch < 1 {
+		fmt.Println("Error: -batch must be at least 1.")
+		os.Exit(1)
+	}
+
+	opts, err := match.options()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	s, err := searcher.New(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	redact := searcher.DefaultRedactFields
+	if *showSecrets {
+		redact = nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	filter := inputFilter{recursive: *recursive}
+	indexer := &bulkIndexer{url: *url, index: *index, batch: *batch, retries: *retries, user: *user, password: *password, apiKey: *apiKey}
+	var exportErr error
+	listErr := walkInputs(ctx, *inDir, filter, func(src inputSource) error {
+		err := readSource(ctx, src, filter, func(name string, r io.Reader) error {
+			return scanFindings(r, func(lineNum int, data JSONData) {
+				if exportErr != nil {
+					return
+				}
+				if _, ok := s.Match(data); !ok {
+					return
+				}
+				m := &searchMatch{sourceFile: name, sourceLine: lineNum, fingerprint: fingerprint(data, occurrenceFields), link: permalink(data)}
+				shown := data
+				if redact != nil {
+					shown = searcher.Redact(data, redact)
+				}
+				exportErr = indexer.add(ctx, m.fingerprint, newJSONResult(m, shown, false))
+			}, func(lineNum int, err error) {
+				fmt.Printf("Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+			})
+		})
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", src.name, err)
+		}
+		return exportErr
+	})
+	if listErr != nil && exportErr == nil {
+		fmt.Printf("Error reading input: %v\n", listErr)
+		os.Exit(1)
+	}
+	if exportErr == nil {
+		exportErr = indexer.flush(ctx)
+	}
+
+	fmt.Printf("Indexed %d findings into %s (%d failed)\n", indexer.indexed, *index, indexer.failed)
+	if exportErr != nil {
+		fmt.Printf("Error exporting: %v\n", exportErr)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Exit codes per search outcome, e.g. verified_match: 10. Unset outcomes use the default exit code.
+type exitCodeConfig struct {
+	PolicyDeny      *int `yaml:"policy_deny"`
+	NewFindings     *int `yaml:"new_findings"`
+	VerifiedMatch   *int `yaml:"verified_match"`
+	UnverifiedMatch *int `yaml:"unverified_match"`
+	Errors          *int `yaml:"errors"`
+	ParseErrors     *int `yaml:"parse_errors"`
+	NoMatch         *int `yaml:"no_match"`
+}
+
+// Outcomes of a search, counted by every worker
+type searchOutcome struct {
+	denied      atomic.Int64
+	newFindings atomic.Int64
+	verified    atomic.Int64
+	unverified  atomic.Int64
+	errors      atomic.Int64 // inputs that could not be opened or read
+	parseErrors atomic.Int64
+}
+
+// Outcomes of the current search
+var outcome searchOutcome
+
+// Record one match
+func (o *searchOutcome) match(data JSONData, decision policyDecision) {
+	if decision.Deny {
+		o.denied.Add(1)
+	}
+	if verified, _ := data["Verified"].(bool); verified {
+		o.verified.Add(1)
+	} else {
+		o.unverified.Add(1)
+	}
+}
+
+// Whether any exit code is configured
+func (e exitCodeConfig) configured() bool {
+	return e.PolicyDeny != nil || e.NewFindings != nil || e.VerifiedMatch != nil || e.UnverifiedMatch != nil ||
+		e.Errors != nil || e.ParseErrors != nil || e.NoMatch != nil
+}
+
+// Reject codes a process cannot exit with
+func (e exitCodeConfig) validate() error {
+	for name, code := range map[string]*int{
+		"policy_deny": e.PolicyDeny, "new_findings": e.NewFindings, "verified_match": e.VerifiedMatch,
+		"unverified_match": e.UnverifiedMatch, "errors": e.Errors, "parse_errors": e.ParseErrors, "no_match": e.NoMatch,
+	} {
+		if code != nil && (*code < 0 || *code > 255) {
+			return fmt.Errorf("exit_codes.%s must be between 0 and 255, got %d", name, *code)
+		}
+	}
+	return nil
+}
+
+// Whether anything matched
+func (o *searchOutcome) matched() bool {
+	return o.verified.Load()+o.unverified.Load() > 0
+}
+
+// Exit code of the first configured outcome that occurred, checked in the order
+// policy_deny, new_findings, verified_match, unverified_match, errors, parse_errors, no_match.
+// Without one, the fallback code is returned.
+func (e exitCodeConfig) code(o *searchOutcome, fallback int) int {
+	outcomes := []struct {
+		occurred bool
+		code     *int
+	}{
+		{o.denied.Load() > 0, e.PolicyDeny},
+		{o.newFindings.Load() > 0, e.NewFindings},
+		{o.verified.Load() > 0, e.VerifiedMatch},
+		{o.unverified.Load() > 0, e.UnverifiedMatch},
+		{o.errors.Load() > 0, e.Errors},
+		{o.parseErrors.Load() > 0, e.ParseErrors},
+		{!o.matched(), e.NoMatch},
+	}
+	for _, candidate := range outcomes {
+		if candidate.occurred && candidate.code != nil {
+			return *candidate.code
+		}
+	}
+	return fallback
+}
+
+// Default exit code, like grep: 0 with matches, 1 without and 2 after errors, except that
+// -q with a match exits 0. Against a -baseline or -against, new findings exit 3 and no new findings 0.
+func defaultExitCode(o *searchOutcome, quiet, baseline bool) int {
+	switch {
+	case o.errors.Load() > 0 && !(quiet && o.matched()):
+		return 2
+	case baseline && o.newFindings.Load() > 0:
+		return 3
+	case baseline || o.matched():
+		return 0
+	}
+	return 1
+}
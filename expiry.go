@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Values of -expiry
+var expiryStatuses = []string{"expired", "valid", "unknown"}
+
+// Whether a secret can still work, judged offline from what its format tells:
+// the expiry of a JWT or signed URL, or the lifetime of a short-lived token
+// and when it was committed
+type expiryInfo struct {
+	Status    string `json:"status"` // expired, valid or unknown
+	Reason    string `json:"reason,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"` // RFC3339, when known
+}
+
+func (e expiryInfo) String() string {
+	label := map[string]string{"expired": "possibly expired", "valid": "likely valid"}[e.Status]
+	if label == "" {
+		label = e.Status
+	}
+	if e.ExpiresAt != "" {
+		label += " at " + e.ExpiresAt
+	}
+	return label + " (" + e.Reason + ")"
+}
+
+// Longest lifetime of tokens recognized by their prefix
+var tokenLifetimes = []struct {
+	prefix   string
+	lifetime time.Duration
+	name     string
+}{
+	{"ASIA", 36 * time.Hour, "temporary AWS credentials"},
+	{"ghs_", time.Hour, "GitHub App installation token"},
+	{"ghu_", 8 * time.Hour, "GitHub App user token"},
+	{"ya29.", time.Hour, "Google OAuth access token"},
+}
+
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.(eyJ[A-Za-z0-9_-]+)\.[A-Za-z0-9_-]*`)
+
+// Estimate whether the secret of a finding has expired by now
+func estimateExpiry(data JSONData, now time.Time) expiryInfo {
+	for _, field := range []string{"Raw", "RawV2"} {
+		secret, _ := data[field].(string)
+		if secret == "" {
+			continue
+		}
+		if expires, reason, ok := embeddedExpiry(secret); ok {
+			return expiryAt(expires, reason, now)
+		}
+		for _, token := range tokenLifetimes {
+			if !strings.HasPrefix(secret, token.prefix) {
+				continue
+			}
+			value, _ := searcher.Lookup(data, "timestamp")
+			committed, ok := searcher.ParseTime(value)
+			if !ok {
+				return expiryInfo{Status: "unknown", Reason: fmt.Sprintf("%s, no commit time", token.name)}
+			}
+			return expiryAt(committed.Add(token.lifetime), fmt.Sprintf("%s, lasting at most %dh from its commit", token.name, int(token.lifetime.Hours())), now)
+		}
+	}
+	return expiryInfo{Status: "unknown", Reason: "no expiry in the secret"}
+}
+
+func expiryAt(expires time.Time, reason string, now time.Time) expiryInfo {
+	info := expiryInfo{Status: "valid", Reason: reason, ExpiresAt: expires.UTC().Format(time.RFC3339)}
+	if expires.Before(now) {
+		info.Status = "expired"
+	}
+	return info
+}
+
+// Expiry written in the secret itself: the exp claim of a JWT, the se
+// parameter of an Azure SAS token, or the date and lifetime of an AWS or
+// Google Cloud Storage signed URL
+func embeddedExpiry(secret string) (time.Time, string, bool) {
+	if match := jwtPattern.FindStringSubmatch(secret); match != nil {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(match[1], "="))
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		var claims struct {
+			Exp *float64 `json:"exp"`
+		}
+		if json.Unmarshal(payload, &claims) != nil || claims.Exp == nil {
+			return time.Time{}, "", false
+		}
+		return time.Unix(int64(*claims.Exp), 0), "JWT exp claim", true
+	}
+
+	query := secret
+	if i := strings.Index(query, "?"); i >= 0 {
+		query = query[i+1:]
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	if se := params.Get("se"); se != "" && params.Get("sig") != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z", "2006-01-02"} {
+			if expires, err := time.Parse(layout, se); err == nil {
+				return expires, "Azure SAS token se parameter", true
+			}
+		}
+	}
+	for _, provider := range []string{"Amz", "Goog"} {
+		signed, err := time.Parse("20060102T150405Z", params.Get("X-"+provider+"-Date"))
+		if err != nil {
+			continue
+		}
+		seconds, err := strconv.Atoi(params.Get("X-" + provider + "-Expires"))
+		if err != nil {
+			continue
+		}
+		name := map[string]string{"Amz": "AWS", "Goog": "Google Cloud Storage"}[provider]
+		return signed.Add(time.Duration(seconds) * time.Second), name + " signed URL", true
+	}
+	return time.Time{}, "", false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Destinations supported by -export
+var exportKinds = []string{"bigquery", "postgres", "mongodb", "redis"}
+
+// Settings for an export destination
+type exportOptions struct {
+	table  string // destination table, or stream key for redis
+	dsn    string // connection string for database destinations
+	maxLen int64  // approximate stream length cap for redis, 0 for none
+}
+
+// Create the export destination for -export
+func newExportSink(kind string, opts exportOptions) (resultSink, error) {
+	switch kind {
+	case "bigquery":
+		return newBigQuerySink(opts.table)
+	case "postgres":
+		return newPostgresSink(opts.dsn, opts.table)
+	case "mongodb":
+		return newMongoSink(opts.dsn, opts.table)
+	case "redis":
+		return newRedisStreamSink(opts.dsn, opts.table, opts.maxLen)
+	}
+	return nil, fmt.Errorf("unsupported export %q (expected %s)", kind, strings.Join(exportKinds, ", "))
+}
+
+// Number of rows buffered before a streaming insert
+const exportBatchSize = 500
+
+// Streams flattened matches into a BigQuery table, creating it on first use
+type bigQuerySink struct {
+	client   *bigquery.Client
+	inserter *bigquery.Inserter
+	mu       sync.Mutex
+	batch    []*bigquery.StructSaver
+}
+
+// Connect to BigQuery with the default credential chain
+func newBigQuerySink(table string) (*bigQuerySink, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("-table must be project.dataset.table for bigquery, got %q", table)
+	}
+
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	ref := client.Dataset(parts[1]).Table(parts[2])
+	if _, err := ref.Metadata(ctx); err != nil {
+		schema, err := bigquery.InferSchema(flatFinding{})
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		if err := ref.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("creating table %s: %w", table, err)
+		}
+	}
+	return &bigQuerySink{client: client, inserter: ref.Inserter()}, nil
+}
+
+func (b *bigQuerySink) write(data JSONData, sourceFile string, sourceLine int) error {
+	row := flattenFinding(data, sourceFile, sourceLine)
+	// The fingerprint as insert ID lets BigQuery drop retried duplicates
+	saver := &bigquery.StructSaver{Struct: &row, InsertID: fingerprint(data, occurrenceFields)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batch = append(b.batch, saver)
+	if len(b.batch) < exportBatchSize {
+		return nil
+	}
+	return b.flush()
+}
+
+// Insert the buffered rows; the caller holds the lock
+func (b *bigQuerySink) flush() error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	batch := b.batch
+	b.batch = nil
+	return b.inserter.Put(context.Background(), batch)
+}
+
+func (b *bigQuerySink) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.flush()
+	if closeErr := b.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Stores the original finding documents with derived fields in a MongoDB collection
+type mongoSink struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	mu         sync.Mutex
+	batch      []mongo.WriteModel
+}
+
+// Connect to MongoDB; the table is database.collection
+func newMongoSink(dsn, table string) (*mongoSink, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-dsn is required for mongodb")
+	}
+	if table == "" {
+		table = "trufflehog.findings"
+	}
+	database, collection, ok := strings.Cut(table, ".")
+	if !ok || database == "" || collection == "" {
+		return nil, fmt.Errorf("-table must be database.collection for mongodb, got %q", table)
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(dsn))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("connecting to mongodb: %w", err)
+	}
+	return &mongoSink{client: client, collection: client.Database(database).Collection(collection)}, nil
+}
+
+func (m *mongoSink) write(data JSONData, sourceFile string, sourceLine int) error {
+	fp := fingerprint(data, occurrenceFields)
+	f := flattenFinding(data, sourceFile, sourceLine)
+	// Keyed by fingerprint so re-exports replace the earlier document
+	document := bson.M{
+		"_id":           fp,
+		"finding":       data,
+		"fingerprint":   fp,
+		"source_file":   sourceFile,
+		"source_line":   sourceLine,
+		"detector_name": f.DetectorName,
+		"verified":      f.Verified,
+		"repository":    f.Repository,
+		"commit":        f.Commit,
+		"file":          f.File,
+		"line":          f.Line,
+		"link":          f.Link,
+		"exported_at":   time.Now().UTC(),
+	}
+	model := mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": fp}).SetReplacement(document).SetUpsert(true)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batch = append(m.batch, model)
+	if len(m.batch) < exportBatchSize {
+		return nil
+	}
+	return m.flush()
+}
+
+// Write the buffered documents; the caller holds the lock
+func (m *mongoSink) flush() error {
+	if len(m.batch) == 0 {
+		return nil
+	}
+	batch := m.batch
+	m.batch = nil
+	_, err := m.collection.BulkWrite(context.Background(), batch, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (m *mongoSink) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.flush()
+	if closeErr := m.client.Disconnect(context.Background()); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Columns of the PostgreSQL findings table besides the fingerprint key, with their types
+var postgresColumns = []struct{ name, kind string }{
+	{"detector_name", "TEXT"}, {"detector_type", "BIGINT"}, {"decoder_name", "TEXT"}, {"verified", "BOOLEAN"},
+	{"raw", "TEXT"}, {"raw_v2", "TEXT"}, {"redacted", "TEXT"}, {"source_name", "TEXT"}, {"source_type", "BIGINT"},
+	{"repository", "TEXT"}, {"commit", "TEXT"}, {"file", "TEXT"}, {"line", "BIGINT"}, {"email", "TEXT"},
+	{"timestamp", "TEXT"}, {"link", "TEXT"}, {"source_file", "TEXT"}, {"source_line", "BIGINT"}, {"finding", "JSONB"},
+	{"first_seen", "TIMESTAMPTZ NOT NULL DEFAULT now()"}, {"last_seen", "TIMESTAMPTZ NOT NULL DEFAULT now()"},
+}
+
+// Upserts matches into a PostgreSQL table keyed by fingerprint
+type postgresSink struct {
+	db     *sql.DB
+	upsert *sql.Stmt
+}
+
+// Connect and create or migrate the findings table
+func newPostgresSink(dsn, table string) (*postgresSink, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-dsn is required for postgres")
+	}
+	if table == "" {
+		table = "trufflehog_findings"
+	}
+	// Quote each part of an optional schema-qualified name
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	quoted := strings.Join(parts, ".")
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := migratePostgres(db, quoted); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	names := []string{"fingerprint"}
+	placeholders := []string{"$1"}
+	var updates []string
+	for i, column := range postgresColumns {
+		if column.name == "first_seen" || column.name == "last_seen" {
+			continue
+		}
+		names = append(names, pq.QuoteIdentifier(column.name))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+2))
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%[1]s", pq.QuoteIdentifier(column.name)))
+	}
+	upsert, err := db.Prepare(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (fingerprint) DO UPDATE SET %s, last_seen = now()`,
+		quoted, strings.Join(names, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", ")))
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &postgresSink{db: db, upsert: upsert}, nil
+}
+
+// Create the table, adding any columns missing from tables created by older versions
+func migratePostgres(db *sql.DB, table string) error {
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (fingerprint TEXT PRIMARY KEY)`, table)); err != nil {
+		return fmt.Errorf("creating table %s: %w", table, err)
+	}
+	for _, column := range postgresColumns {
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s`, table, pq.QuoteIdentifier(column.name), column.kind)); err != nil {
+			return fmt.Errorf("migrating table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (p *postgresSink) write(data JSONData, sourceFile string, sourceLine int) error {
+	f := flattenFinding(data, sourceFile, sourceLine)
+	finding, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = p.upsert.Exec(fingerprint(data, occurrenceFields), f.DetectorName, f.DetectorType, f.DecoderName, f.Verified,
+		f.Raw, f.RawV2, f.Redacted, f.SourceName, f.SourceType, f.Repository, f.Commit, f.File, f.Line, f.Email,
+		f.Timestamp, f.Link, f.SourceFile, f.SourceLine, string(finding))
+	return err
+}
+
+func (p *postgresSink) Close() error {
+	p.upsert.Close()
+	return p.db.Close()
+}
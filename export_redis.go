@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Publishes matches to a Redis Stream as they are found
+type redisStreamSink struct {
+	client *redis.Client
+	key    string
+	maxLen int64
+}
+
+// Connect to Redis; the table is the stream key
+func newRedisStreamSink(dsn, key string, maxLen int64) (*redisStreamSink, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-dsn is required for redis")
+	}
+	if key == "" {
+		key = "trufflehog:findings"
+	}
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+	return &redisStreamSink{client: client, key: key, maxLen: maxLen}, nil
+}
+
+func (r *redisStreamSink) write(data JSONData, sourceFile string, sourceLine int) error {
+	f := flattenFinding(data, sourceFile, sourceLine)
+	finding, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	args := &redis.XAddArgs{
+		Stream: r.key,
+		Values: map[string]interface{}{
+			"fingerprint": fingerprint(data, occurrenceFields),
+			"detector":    f.DetectorName,
+			"verified":    f.Verified,
+			"repository":  f.Repository,
+			"source_file": sourceFile,
+			"source_line": sourceLine,
+			"finding":     string(finding),
+		},
+	}
+	// Approximate trimming lets Redis drop whole stream nodes, which is much cheaper
+	if r.maxLen > 0 {
+		args.MaxLen = r.maxLen
+		args.Approx = true
+	}
+	return r.client.XAdd(context.Background(), args).Err()
+}
+
+func (r *redisStreamSink) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Score from which a finding counts as a likely false positive
+const likelyFalsePositive = 0.5
+
+// Words found in placeholder secrets rather than real ones
+var placeholderWords = []string{
+	"changeme", "change_me", "change-me", "placeholder", "dummy", "example", "sample", "fake",
+	"your_", "your-", "yourkey", "xxxxxx", "redacted", "insert", "replace", "todo", "notasecret", "password123",
+}
+
+// Path segments of tests and fixtures, where secrets are usually made up
+var fixturePathParts = []string{
+	"/test/", "/tests/", "/testdata/", "/test_data/", "/fixtures/", "/fixture/", "/__tests__/", "/__mocks__/",
+	"/mocks/", "/spec/", "/examples/", "/example/", "/samples/", "/docs/",
+}
+
+// File name parts of tests
+var testFileParts = []string{"_test.", ".test.", ".spec.", "_spec.", "test_"}
+
+// How likely a finding is to be a false positive, and why
+type fpScore struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// Score a finding by the signs of a made-up secret: placeholder words,
+// documentation example keys, sequential or repeated characters and test
+// fixture paths. Each sign adds its weight to the chance that the finding
+// is a false positive.
+func scoreFalsePositive(data JSONData) fpScore {
+	var score fpScore
+	add := func(weight float64, reason string) {
+		score.Score += (1 - score.Score) * weight
+		score.Reasons = append(score.Reasons, reason)
+	}
+
+	secret := searcher.Secret(data)
+	lower := strings.ToLower(secret)
+	switch {
+	case strings.Contains(secret, "EXAMPLE"):
+		add(0.8, "documented example key")
+	case strings.Contains(secret, "${") || strings.Contains(secret, "{{") || strings.HasPrefix(secret, "<"):
+		add(0.6, "template placeholder")
+	default:
+		for _, word := range placeholderWords {
+			if strings.Contains(lower, word) {
+				add(0.6, fmt.Sprintf("placeholder value %q", word))
+				break
+			}
+		}
+	}
+	if run := sequentialRun(lower); run >= 6 {
+		add(0.4, fmt.Sprintf("%d sequential characters", run))
+	} else if run := repeatedRun(secret); run >= 6 {
+		add(0.4, fmt.Sprintf("a character repeated %d times", run))
+	}
+
+	file := strings.ToLower(flattenFinding(data, "", 0).File)
+	if file != "" {
+		padded := "/" + file
+		for _, part := range fixturePathParts {
+			if strings.Contains(padded, part) {
+				add(0.3, "test fixture path")
+				return score
+			}
+		}
+		name := padded[strings.LastIndex(padded, "/")+1:]
+		for _, part := range testFileParts {
+			if strings.Contains(name, part) {
+				add(0.3, "test file")
+				break
+			}
+		}
+	}
+	return score
+}
+
+// Longest run of consecutive ascending characters, as in abcdef or 123456
+func sequentialRun(s string) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i] == s[i-1]+1 && isAlphanumeric(s[i]) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// Longest run of one character repeated, as in 000000 or xxxxxxx
+func repeatedRun(s string) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i] == s[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
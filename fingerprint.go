@@ -0,0 +1,24 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Fields identifying one exact finding occurrence (same secret at the same location)
+var occurrenceFields = []string{"DetectorName", "Raw", "RawV2", "repository", "commit", "file", "line"}
+
+// Fingerprint a finding by hashing the values of the given fields
+func fingerprint(data JSONData, fields []string) string {
+	hasher := sha256.New()
+	for _, field := range fields {
+		value, _ := searcher.Lookup(data, field)
+		hasher.Write([]byte(field))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(searcher.String(value)))
+		hasher.Write([]byte{0})
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Formats accepted by -input-format: trufflehog's JSON output, the tool's own
+// result files, and the output of other scanners read through an adapter
+var inputFormats = []string{"trufflehog", "self", "trufflehog-legacy", "trufflehog3", "ggshield", "semgrep"}
+
+// Turns one entry of another scanner's output into a finding shaped like
+// trufflehog's, so every filter, field and output works on it unchanged
+type findingAdapter func(entry JSONData) (JSONData, error)
+
+var findingAdapters = map[string]findingAdapter{
+	"trufflehog-legacy": adaptTrufflehogLegacy,
+	"trufflehog3":       adaptTrufflehog3,
+	"semgrep":           adaptSemgrep,
+}
+
+// A result of semgrep --json, such as a finding of the secrets rulesets.
+// Semgrep reports the source line rather than the secret, so the line is the
+// finding's Raw and is masked like a secret.
+func adaptSemgrep(entry JSONData) (JSONData, error) {
+	checkID, _ := entry["check_id"].(string)
+	extra, _ := entry["extra"].(map[string]interface{})
+	if checkID == "" || extra == nil {
+		return nil, fmt.Errorf("not a semgrep result: missing check_id or extra")
+	}
+	// The rule name is the last part of its id, e.g. detected-aws-access-key-id-value
+	detector := checkID[strings.LastIndex(checkID, ".")+1:]
+	metadata := map[string]interface{}{"file": entry["path"]}
+	if start, ok := entry["start"].(map[string]interface{}); ok {
+		metadata["line"] = start["line"]
+	}
+	validation, _ := extra["validation_state"].(string)
+	return JSONData{
+		"SourceMetadata": map[string]interface{}{"Data": map[string]interface{}{"Filesystem": withoutEmpty(metadata)}},
+		"SourceName":     "semgrep",
+		"DetectorName":   detector,
+		"DecoderName":    "PLAIN",
+		"Verified":       validation == "CONFIRMED_VALID",
+		"Raw":            strings.TrimSpace(searcher.String(extra["lines"])),
+		"ExtraData": withoutEmpty(map[string]interface{}{
+			"check_id":         checkID,
+			"message":          extra["message"],
+			"severity":         extra["severity"],
+			"validation_state": extra["validation_state"],
+		}),
+	}, nil
+}
+
+// Turns a whole report of another scanner into findings shaped like
+// trufflehog's, for formats nesting their findings in a single document
+type documentAdapter func(document JSONData) ([]JSONData, error)
+
+var documentAdapters = map[string]documentAdapter{
+	"ggshield": adaptGGShield,
+}
+
+// Read the reports of a document format and return its findings as JSON
+// lines, numbered in report order; other formats are read as they are
+func adaptStream(format string, r io.Reader) (io.Reader, error) {
+	adapt := documentAdapters[format]
+	if adapt == nil {
+		return r, nil
+	}
+	decoder := json.NewDecoder(r)
+	var lines bytes.Buffer
+	for {
+		var document JSONData
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			return &lines, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s report: %w", format, err)
+		}
+		findings, err := adapt(document)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			line, err := json.Marshal(finding)
+			if err != nil {
+				return nil, err
+			}
+			lines.Write(append(line, '\n'))
+		}
+	}
+}
+
+// An entry of trufflehog 2 --json or trufflehog 3 --json-legacy output:
+// the reason, the strings found and the commit they were found in
+func adaptTrufflehogLegacy(entry JSONData) (JSONData, error) {
+	reason, _ := entry["reason"].(string)
+	found, ok := entry["stringsFound"].([]interface{})
+	if reason == "" || !ok {
+		return nil, fmt.Errorf("not a trufflehog legacy finding: missing reason or stringsFound")
+	}
+	finding := JSONData{
+		"SourceMetadata": map[string]interface{}{"Data": map[string]interface{}{"Git": withoutEmpty(map[string]interface{}{
+			"commit":    entry["commitHash"],
+			"file":      entry["path"],
+			"timestamp": entry["date"],
+			"branch":    entry["branch"],
+			"message":   entry["commit"],
+		})}},
+		"SourceName":   "trufflehog (legacy)",
+		"DetectorName": reason,
+		"DecoderName":  "PLAIN",
+		"Verified":     false,
+		"Raw":          "",
+	}
+	values := make([]string, len(found))
+	for i, value := range found {
+		values[i] = searcher.String(value)
+	}
+	if len(values) > 0 {
+		finding["Raw"] = values[0]
+	}
+	// Every string of the same commit goes to RawV2, masked like Raw
+	if len(values) > 1 {
+		finding["RawV2"] = strings.Join(values, "\n")
+	}
+	return finding, nil
+}
+
+// An issue of trufflehog3 JSON output: the rule that fired, the secret and
+// where it was found, in the git history or in the working tree
+func adaptTrufflehog3(entry JSONData) (JSONData, error) {
+	rule, _ := entry["rule"].(map[string]interface{})
+	secret, _ := entry["secret"].(string)
+	if rule == nil || secret == "" {
+		return nil, fmt.Errorf("not a trufflehog3 issue: missing rule or secret")
+	}
+	detector, _ := rule["message"].(string)
+	if detector == "" {
+		detector, _ = rule["id"].(string)
+	}
+	metadata := map[string]interface{}{"file": entry["path"]}
+	if line, err := strconv.Atoi(searcher.String(entry["line"])); err == nil {
+		metadata["line"] = float64(line) // a number as decoded from JSON
+	}
+	source := "Filesystem"
+	if commit, _ := entry["commit"].(string); commit != "" {
+		source = "Git"
+		metadata["commit"] = commit
+		metadata["email"] = entry["author"]
+		metadata["timestamp"] = entry["date"]
+		metadata["branch"] = entry["branch"]
+		metadata["message"] = entry["message"]
+	}
+	return JSONData{
+		"SourceMetadata": map[string]interface{}{"Data": map[string]interface{}{source: withoutEmpty(metadata)}},
+		"SourceName":     "trufflehog3",
+		"DetectorName":   detector,
+		"DecoderName":    "PLAIN",
+		"Verified":       false,
+		"Raw":            secret,
+		"ExtraData":      withoutEmpty(map[string]interface{}{"rule_id": rule["id"], "severity": rule["severity"]}),
+	}, nil
+}
+
+// A ggshield --json report, of a path or of a range of commits: one finding
+// per incident, with every occurrence of the secret. A valid secret counts
+// as verified.
+func adaptGGShield(document JSONData) ([]JSONData, error) {
+	scans, ok := document["scans"].([]interface{})
+	if !ok {
+		if _, ok := document["entities_with_incidents"]; !ok {
+			return nil, fmt.Errorf("not a ggshield report: missing entities_with_incidents or scans")
+		}
+		return ggshieldIncidents(document, nil), nil
+	}
+	var findings []JSONData
+	for _, scan := range scans {
+		if scan, ok := scan.(map[string]interface{}); ok {
+			info, _ := scan["extra_info"].(map[string]interface{})
+			commit := map[string]interface{}{"commit": scan["id"]}
+			if info != nil {
+				commit["timestamp"] = info["date"]
+				// Shaped like the "Name <address>" of trufflehog's git metadata
+				author, _ := info["author"].(string)
+				email, _ := info["email"].(string)
+				if author != "" && email != "" {
+					commit["email"] = fmt.Sprintf("%s <%s>", author, email)
+				} else {
+					commit["email"] = email
+				}
+			}
+			findings = append(findings, ggshieldIncidents(scan, commit)...)
+		}
+	}
+	return findings, nil
+}
+
+// The incidents of a ggshield scan; commit holds the git metadata of a
+// commit scan, nil for a scan of files
+func ggshieldIncidents(scan map[string]interface{}, commit map[string]interface{}) []JSONData {
+	var findings []JSONData
+	entities, _ := scan["entities_with_incidents"].([]interface{})
+	for _, entity := range entities {
+		entity, _ := entity.(map[string]interface{})
+		incidents, _ := entity["incidents"].([]interface{})
+		for _, incident := range incidents {
+			incident, ok := incident.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			metadata := map[string]interface{}{"file": entity["filename"]}
+			source := "Filesystem"
+			if commit != nil {
+				source = "Git"
+				for key, value := range commit {
+					metadata[key] = value
+				}
+			}
+			var matches []string
+			occurrences, _ := incident["occurrences"].([]interface{})
+			for _, occurrence := range occurrences {
+				occurrence, _ := occurrence.(map[string]interface{})
+				if match := searcher.String(occurrence["match"]); match != "" && !containsString(matches, match) {
+					matches = append(matches, match)
+				}
+				if line, ok := occurrence["line_start"].(float64); ok && metadata["line"] == nil {
+					metadata["line"] = line
+				}
+			}
+			detector, _ := incident["type"].(string)
+			validity, _ := incident["validity"].(string)
+			finding := JSONData{
+				"SourceMetadata": map[string]interface{}{"Data": map[string]interface{}{source: withoutEmpty(metadata)}},
+				"SourceName":     "ggshield",
+				"DetectorName":   detector,
+				"DecoderName":    "PLAIN",
+				"Verified":       validity == "valid",
+				"Raw":            "",
+				"ExtraData": withoutEmpty(map[string]interface{}{
+					"validity":     incident["validity"],
+					"policy":       incident["policy"],
+					"incident_url": incident["incident_url"],
+					"ignore_sha":   incident["ignore_sha"],
+				}),
+			}
+			if len(matches) > 0 {
+				finding["Raw"] = matches[0]
+			}
+			// The parts of a multi-part secret, such as an AWS key id and its secret key
+			if len(matches) > 1 {
+				finding["RawV2"] = strings.Join(matches, "\n")
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// Drop the fields an entry did not have
+func withoutEmpty(fields map[string]interface{}) map[string]interface{} {
+	for key, value := range fields {
+		if value == nil || value == "" {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Kinds of nodes of the -graph export, in the order they are written
+const (
+	graphSecret     = "secret"
+	graphRepository = "repository"
+	graphAuthor     = "author"
+)
+
+// A secret, repository or author of the -graph export
+type graphNode struct {
+	id       string
+	kind     string
+	label    string
+	verified bool // secrets only
+}
+
+// An undirected relation, weighted by the number of matches behind it
+type graphEdge struct {
+	from, to string
+	count    int
+}
+
+// Secrets linked to the repositories holding them and to the authors who
+// committed them. A secret connected to many repositories or authors shows
+// credential sharing.
+type relationGraph struct {
+	nodes map[string]*graphNode
+	edges map[[2]string]*graphEdge
+}
+
+// Build the graph of the matches. Secrets are masked unless redact is nil
+// (-show-secrets); findings shown by -show-ignored are left out.
+func buildRelationGraph(matches []*searchMatch, redact []string) *relationGraph {
+	g := &relationGraph{nodes: map[string]*graphNode{}, edges: map[[2]string]*graphEdge{}}
+	for _, m := range matches {
+		secret := searcher.Secret(m.data)
+		if m.ignoredBy != "" || secret == "" {
+			continue
+		}
+		data := m.data
+		if redact != nil {
+			data = searcher.Redact(data, redact)
+		}
+		flat := flattenFinding(data, m.sourceFile, m.sourceLine)
+		secretNode := g.node(graphSecret, secret, flat.DetectorName+"\n"+searcher.Secret(data))
+		secretNode.verified = secretNode.verified || flat.Verified
+		if flat.Repository != "" {
+			// SSH and https clones of a repository are one node, without the credentials of their URLs
+			repository := webRepository(flat.Repository)
+			g.link(secretNode, g.node(graphRepository, repository, repository))
+		}
+		if flat.Email != "" {
+			g.link(g.node(graphAuthor, flat.Email, flat.Email), secretNode)
+		}
+	}
+	return g
+}
+
+// The node of a value, added on first use. Nodes are identified by a hash
+// of their value so secrets never appear in identifiers.
+func (g *relationGraph) node(kind, value, label string) *graphNode {
+	id := kind[:1] + strings.TrimPrefix(hashValue(kind+"\x00"+value), "sha256:")[:12]
+	node, ok := g.nodes[id]
+	if !ok {
+		node = &graphNode{id: id, kind: kind, label: label}
+		g.nodes[id] = node
+	}
+	return node
+}
+
+func (g *relationGraph) link(from, to *graphNode) {
+	key := [2]string{from.id, to.id}
+	edge, ok := g.edges[key]
+	if !ok {
+		edge = &graphEdge{from: from.id, to: to.id}
+		g.edges[key] = edge
+	}
+	edge.count++
+}
+
+// Nodes ordered by kind and label, so the same matches give the same file
+func (g *relationGraph) sortedNodes() []*graphNode {
+	order := map[string]int{graphSecret: 0, graphRepository: 1, graphAuthor: 2}
+	nodes := make([]*graphNode, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].kind != nodes[j].kind {
+			return order[nodes[i].kind] < order[nodes[j].kind]
+		}
+		if nodes[i].label != nodes[j].label {
+			return nodes[i].label < nodes[j].label
+		}
+		return nodes[i].id < nodes[j].id
+	})
+	return nodes
+}
+
+func (g *relationGraph) sortedEdges() []*graphEdge {
+	edges := make([]*graphEdge, 0, len(g.edges))
+	for _, edge := range g.edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+	return edges
+}
+
+// Write the graph in the Graphviz DOT language
+func (g *relationGraph) writeDOT(w io.Writer) {
+	shapes := map[string]string{graphSecret: "box", graphRepository: "folder", graphAuthor: "ellipse"}
+	fmt.Fprintln(w, "graph findings {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, node := range g.sortedNodes() {
+		attributes := fmt.Sprintf("label=%s, shape=%s", dotQuote(node.label), shapes[node.kind])
+		if node.verified {
+			attributes += ", color=red"
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", node.id, attributes)
+	}
+	for _, edge := range g.sortedEdges() {
+		if edge.count > 1 {
+			fmt.Fprintf(w, "  %s -- %s [label=\"%d\"];\n", edge.from, edge.to, edge.count)
+		} else {
+			fmt.Fprintf(w, "  %s -- %s;\n", edge.from, edge.to)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// Write the graph as a Mermaid flowchart
+func (g *relationGraph) writeMermaid(w io.Writer) {
+	fmt.Fprintln(w, "graph LR")
+	for _, node := range g.sortedNodes() {
+		label := mermaidQuote(node.label)
+		switch node.kind {
+		case graphSecret:
+			fmt.Fprintf(w, "  %s[%s]\n", node.id, label)
+		case graphRepository:
+			fmt.Fprintf(w, "  %s[(%s)]\n", node.id, label)
+		default:
+			fmt.Fprintf(w, "  %s([%s])\n", node.id, label)
+		}
+		if node.verified {
+			fmt.Fprintf(w, "  style %s stroke:#d00,stroke-width:2px\n", node.id)
+		}
+	}
+	for _, edge := range g.sortedEdges() {
+		if edge.count > 1 {
+			fmt.Fprintf(w, "  %s ---|%d| %s\n", edge.from, edge.count, edge.to)
+		} else {
+			fmt.Fprintf(w, "  %s --- %s\n", edge.from, edge.to)
+		}
+	}
+}
+
+// Quote a DOT label, keeping line breaks
+func dotQuote(label string) string {
+	label = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(label)
+	return `"` + label + `"`
+}
+
+// Quote a Mermaid label, keeping line breaks
+func mermaidQuote(label string) string {
+	label = strings.NewReplacer(`"`, "#quot;", "\n", "<br>").Replace(label)
+	return `"` + label + `"`
+}
+
+// Write the graph of the matches to a file: Mermaid for .mmd and .mermaid
+// files, Graphviz DOT otherwise
+func writeGraph(path string, matches []*searchMatch, redact []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	g := buildRelationGraph(matches, redact)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mmd", ".mermaid":
+		g.writeMermaid(w)
+	default:
+		g.writeDOT(w)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
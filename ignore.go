@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"gopkg.in/yaml.v3"
+)
+
+// Default ignore file, read from the working directory when it exists
+const defaultIgnoreFile = ".thsearcher-ignore"
+
+// A known false positive. Every field that is set must match: detector and
+// repository are globs, path is a glob like -include and raw is a regular
+// expression matched against the secret.
+type ignoreRule struct {
+	Detector   string `yaml:"detector"`
+	Repository string `yaml:"repository"`
+	Path       string `yaml:"path"`
+	Raw        string `yaml:"raw"`
+	Reason     string `yaml:"reason"`
+
+	detector   *regexp.Regexp
+	repository *regexp.Regexp
+	raw        *regexp.Regexp
+}
+
+// Load the rules of an ignore file followed by the ignore section of the configuration.
+// The file is either a YAML list of rules or one rule per line as key=value pairs:
+//
+//	# test fixtures
+//	detector=AWS path=testdata/** reason="fake keys"
+func loadIgnoreRules(path string, extra []ignoreRule) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	if path != "" {
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && path == defaultIgnoreFile:
+		case err != nil:
+			return nil, err
+		default:
+			if rules, err = parseIgnoreRules(content); err != nil {
+				return nil, fmt.Errorf("ignore file %s: %w", path, err)
+			}
+		}
+	}
+	rules = append(rules, extra...)
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("ignore rule %q: %w", rules[i].String(), err)
+		}
+	}
+	return rules, nil
+}
+
+// Parse ignore rules in either format
+func parseIgnoreRules(content []byte) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	if yaml.Unmarshal(content, &rules) == nil {
+		return rules, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseIgnoreLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// Parse a line of key=value pairs; values may be double-quoted to hold spaces
+func parseIgnoreLine(line string) (ignoreRule, error) {
+	var rule ignoreRule
+	for line != "" {
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			return rule, fmt.Errorf("expected key=value, got %q", line)
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return rule, fmt.Errorf("unterminated quote in %q", rest)
+			}
+			value, line = rest[1:end+1], rest[end+2:]
+		} else {
+			value, line, _ = strings.Cut(rest, " ")
+		}
+		line = strings.TrimSpace(line)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "detector":
+			rule.Detector = value
+		case "repository", "repo":
+			rule.Repository = value
+		case "path":
+			rule.Path = value
+		case "raw":
+			rule.Raw = value
+		case "reason":
+			rule.Reason = value
+		default:
+			return rule, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return rule, nil
+}
+
+// Compile the patterns of a rule
+func (r *ignoreRule) compile() error {
+	if r.Detector == "" && r.Repository == "" && r.Path == "" && r.Raw == "" {
+		return errors.New("a rule needs at least one of detector, repository, path or raw")
+	}
+	var err error
+	if r.Detector != "" {
+		r.detector = globPattern(r.Detector, true)
+	}
+	if r.Repository != "" {
+		r.repository = globPattern(r.Repository, false)
+	}
+	if r.Raw != "" {
+		if r.raw, err = regexp.Compile(r.Raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A glob where * matches anything, as an anchored regular expression
+func globPattern(glob string, ignoreCase bool) *regexp.Regexp {
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(glob), `\*`, ".*") + "$"
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// Whether the rule covers a finding
+func (r *ignoreRule) matches(data JSONData) bool {
+	text := func(field string) string {
+		value, _ := searcher.Lookup(data, field)
+		return searcher.String(value)
+	}
+	if r.detector != nil && !r.detector.MatchString(text("DetectorName")) {
+		return false
+	}
+	if r.repository != nil && !r.repository.MatchString(text("repository")) {
+		return false
+	}
+	if r.Path != "" && !matchGlob(r.Path, text("file")) {
+		return false
+	}
+	if r.raw != nil && !r.raw.MatchString(searcher.Secret(data)) {
+		return false
+	}
+	return true
+}
+
+// The rule as written in the line format
+func (r *ignoreRule) String() string {
+	var parts []string
+	for _, field := range []struct{ key, value string }{
+		{"detector", r.Detector}, {"repository", r.Repository}, {"path", r.Path}, {"raw", r.Raw}, {"reason", r.Reason},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value := field.value
+		if strings.ContainsAny(value, " \t") {
+			value = `"` + value + `"`
+		}
+		parts = append(parts, field.key+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// First rule covering a finding, or nil
+func matchIgnoreRules(rules []ignoreRule, data JSONData) *ignoreRule {
+	for i := range rules {
+		if rules[i].matches(data) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// -changed-since value comparing files with the previous run of the same search
+const changedSinceLastRun = "last-run"
+
+// Size and modification time of a file when it was last searched
+type searchedFile struct {
+	size  int64
+	mtime int64 // unix nanoseconds
+}
+
+// Skips the local files -changed-since considers unchanged and records the
+// files searched, per search, in the findings database
+type changeTracker struct {
+	db     *sql.DB
+	search string
+	since  time.Time // zero for last-run
+	known  map[string]searchedFile
+
+	mu       sync.Mutex
+	searched map[string]searchedFile
+	skipped  int
+}
+
+// Key of the searched_files rows of a search: the same input format and
+// match flags give the same key, whatever the output flags
+func searchKey(jobFlags []*searchFlags) string {
+	hasher := sha256.New()
+	for _, f := range jobFlags {
+		fmt.Fprintf(hasher, "%s\n%+v\n", f.inputFormat, *f.matchFlags)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// Load the files recorded by earlier runs of the search
+func openChangeTracker(dbPath, changedSince string, jobFlags []*searchFlags) (*changeTracker, error) {
+	t := &changeTracker{search: searchKey(jobFlags), known: map[string]searchedFile{}, searched: map[string]searchedFile{}}
+	if changedSince != changedSinceLastRun {
+		var err error
+		if t.since, err = parseTimeBound(changedSince); err != nil {
+			return nil, err
+		}
+	}
+	db, err := openDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", dbPath, err)
+	}
+	t.db = db
+
+	rows, err := db.Query("SELECT path, size, mtime FROM searched_files WHERE search = ?", t.search)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		var file searchedFile
+		if err := rows.Scan(&path, &file.size, &file.mtime); err != nil {
+			db.Close()
+			return nil, err
+		}
+		t.known[path] = file
+	}
+	if err := rows.Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Whether a source must be searched. Sources other than local files always are.
+func (t *changeTracker) changed(src inputSource) bool {
+	if src.path == "" {
+		return true
+	}
+	changed := src.mtime.After(t.since)
+	if t.since.IsZero() {
+		last, ok := t.known[absPath(src.path)]
+		changed = !ok || last.size != src.size || last.mtime != src.mtime.UnixNano()
+	}
+	if !changed {
+		t.mu.Lock()
+		t.skipped++
+		t.mu.Unlock()
+	}
+	return changed
+}
+
+// Remember a local file that was searched completely
+func (t *changeTracker) done(src inputSource) {
+	if src.path == "" {
+		return
+	}
+	t.mu.Lock()
+	t.searched[absPath(src.path)] = searchedFile{size: src.size, mtime: src.mtime.UnixNano()}
+	t.mu.Unlock()
+}
+
+// Record the searched files for the next run and close the database
+func (t *changeTracker) Close() error {
+	defer t.db.Close()
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now().Unix()
+	for path, file := range t.searched {
+		if _, err := tx.Exec(`INSERT INTO searched_files(search, path, size, mtime, searched_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(search, path) DO UPDATE SET size = excluded.size, mtime = excluded.mtime, searched_at = excluded.searched_at`,
+			t.search, path, file.size, file.mtime, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Absolute form of a path, so runs from other directories share the state
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Full-text index over the findings table, kept in sync by triggers so later
+// db import and purge runs update it too
+const indexSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS findings_fts USING fts5(
+	detector, repository, file, commit_hash, data,
+	content='findings', content_rowid='id'
+);
+CREATE TRIGGER IF NOT EXISTS findings_fts_insert AFTER INSERT ON findings BEGIN
+	INSERT INTO findings_fts(rowid, detector, repository, file, commit_hash, data)
+	VALUES (new.id, new.detector, new.repository, new.file, new.commit_hash, new.data);
+END;
+CREATE TRIGGER IF NOT EXISTS findings_fts_delete AFTER DELETE ON findings BEGIN
+	INSERT INTO findings_fts(findings_fts, rowid, detector, repository, file, commit_hash, data)
+	VALUES ('delete', old.id, old.detector, old.repository, old.file, old.commit_hash, old.data);
+END;
+`
+
+// Create the full-text index, filling it with the findings imported before it existed
+func ensureIndex(db *sql.DB) error {
+	if _, err := db.Exec(indexSchema); err != nil {
+		return err
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= 1 {
+		return nil
+	}
+	if _, err := db.Exec("INSERT INTO findings_fts(findings_fts) VALUES ('rebuild')"); err != nil {
+		return err
+	}
+	_, err := db.Exec("PRAGMA user_version = 1")
+	return err
+}
+
+// Ingest trufflehog output into the database and its full-text index
+func runIndex(args []string) {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory or file containing JSON trufflehog output (required)")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	corpusName := fs.String("corpus", "", "Corpus name recorded with the findings (default: name of the -i directory)")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	lock, err := lockDB(context.Background(), *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	if err := ensureIndex(db); err != nil {
+		fmt.Printf("Error creating the index: %v\n", err)
+		os.Exit(1)
+	}
+	importInputs(db, *inDir, *corpusName)
+}
+
+// Search the full-text index instead of the raw files
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	corpusName := fs.String("corpus", "", "Only search this corpus")
+	limit := fs.Int("limit", 100, "Maximum number of findings printed (0 for no limit)")
+	format := fs.String("o", "text", "Output format: 'text' or 'json'")
+	showSecrets := fs.Bool("show-secrets", false, "Print secret values in full instead of masking them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher query [flags] EXPRESSION")
+		fmt.Fprintln(fs.Output(), "EXPRESSION is an SQLite FTS5 query, e.g. 'acme AND detector:AWS' or '\"prod-db\"'")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Error: expected one query expression.")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Println("Error: -o must be 'text' or 'json'.")
+		os.Exit(1)
+	}
+	redact := searcher.DefaultRedactFields
+	if *showSecrets {
+		redact = nil
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(2)
+	}
+	defer db.Close()
+	if err := ensureIndex(db); err != nil {
+		fmt.Printf("Error creating the index: %v\n", err)
+		os.Exit(2)
+	}
+
+	rows, err := db.Query(`SELECT f.source_file, f.source_line, f.fingerprint, f.data
+		FROM findings_fts JOIN findings f ON f.id = findings_fts.rowid
+		WHERE findings_fts MATCH ? AND (? = '' OR f.corpus = ?)
+		ORDER BY rank LIMIT ?`, fs.Arg(0), *corpusName, *corpusName, queryLimit(*limit))
+	if err != nil {
+		fmt.Printf("Error querying the index: %v\n", err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+
+	matches := 0
+	for rows.Next() {
+		m := &searchMatch{}
+		var encoded string
+		if err := rows.Scan(&m.sourceFile, &m.sourceLine, &m.fingerprint, &encoded); err != nil {
+			fmt.Printf("Error reading the index: %v\n", err)
+			os.Exit(2)
+		}
+		if err := json.Unmarshal([]byte(encoded), &m.data); err != nil {
+			fmt.Printf("Error decoding finding: %v\n", err)
+			continue
+		}
+		m.location = fmt.Sprintf("line %d of %s", m.sourceLine, m.sourceFile)
+		m.link = permalink(m.data)
+		data := m.data
+		if redact != nil {
+			data = searcher.Redact(data, redact)
+		}
+		if *format == "json" {
+			writeJSONLine(os.Stdout, newJSONResult(m, data, false))
+		} else {
+			writeTextMatch(os.Stdout, m, data, false)
+		}
+		matches++
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("Error querying the index: %v\n", err)
+		os.Exit(2)
+	}
+	if matches == 0 {
+		os.Exit(1)
+	}
+}
+
+// SQLite reads a negative LIMIT as no limit
+func queryLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
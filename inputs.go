@@ -0,0 +1,361 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// A location holding trufflehog output: a file, an archive, stdin or a remote object
+type inputSource struct {
+	name  string                                           // shown in headers, relative to -i
+	path  string                                           // local file, empty for other sources
+	size  int64                                            // bytes of the raw content, 0 when unknown
+	mtime time.Time                                        // modification time of a local file
+	open  func(ctx context.Context) (io.ReadCloser, error) // raw content, possibly compressed or archived
+}
+
+// Selection of the files searched below an input directory or prefix
+type inputFilter struct {
+	recursive bool
+	include   []string
+	exclude   []string
+}
+
+// Archive extensions whose entries are searched one by one
+var archiveExts = []string{".tar", ".tar.gz", ".tgz", ".tar.zst", ".tar.xz", ".zip"}
+
+// Whether a file name looks like trufflehog output, possibly compressed
+func isFindingsFile(name string) bool {
+	ext := path.Ext(trimCompressionExt(name))
+	return ext == ".json" || ext == ".jsonl"
+}
+
+// Whether a file name is an archive of trufflehog output
+func isArchive(name string) bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a file, given by its slash-separated path relative to the input, is searched
+func (f inputFilter) accepts(rel string) bool {
+	if len(f.include) > 0 {
+		included := false
+		for _, pattern := range f.include {
+			if matchGlob(pattern, rel) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match a slash-separated path against a glob where ** matches any number of directories.
+// Patterns without a slash also match the base name.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") && matchSegments([]string{pattern}, []string{path.Base(name)}) {
+		return true
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Directory entries read at a time while listing -i
+const dirBatchSize = 1024
+
+// Call fn for every source of -i as it is found: stdin for "-", remote objects
+// for s3://, gs:// and http(s):// locations, otherwise a local file or the files
+// of a directory. Directories are read in batches and in directory order, so
+// neither the time to the first source nor memory grow with their size.
+// An error from fn stops the listing and is returned.
+func walkInputs(ctx context.Context, input string, filter inputFilter, fn func(inputSource) error) error {
+	if input == "-" {
+		return fn(inputSource{name: "stdin", open: func(context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(os.Stdin), nil
+		}})
+	}
+	if isRemote(input) {
+		sources, err := listRemote(ctx, input, filter)
+		if err != nil {
+			return err
+		}
+		for _, src := range sources {
+			if err := fn(src); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	info, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(localSource(input, filepath.Base(input)))
+	}
+	return walkDir(ctx, input, "", filter, fn)
+}
+
+// Call fn for the trufflehog output files of the directory rel below root,
+// descending into subdirectories with -r
+func walkDir(ctx context.Context, root, rel string, filter inputFilter, fn func(inputSource) error) error {
+	dir, err := os.Open(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, readErr := dir.ReadDir(dirBatchSize)
+		for _, entry := range entries {
+			entryRel := path.Join(rel, entry.Name())
+			if entry.IsDir() {
+				if filter.recursive && !excludedDir(filter, entryRel) {
+					if err := walkDir(ctx, root, entryRel, filter, fn); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if (isFindingsFile(entry.Name()) || isArchive(entry.Name())) && filter.accepts(entryRel) {
+				if err := fn(localSource(filepath.Join(root, filepath.FromSlash(entryRel)), entryRel)); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// List all sources of -i, in name order
+func listInputs(ctx context.Context, input string, filter inputFilter) ([]inputSource, error) {
+	var sources []inputSource
+	err := walkInputs(ctx, input, filter, func(src inputSource) error {
+		sources = append(sources, src)
+		return nil
+	})
+	sort.Slice(sources, func(i, j int) bool { return sources[i].name < sources[j].name })
+	return sources, err
+}
+
+// Whether an exclude pattern covers a whole directory
+func excludedDir(filter inputFilter, rel string) bool {
+	for _, pattern := range filter.exclude {
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func localSource(filePath, name string) inputSource {
+	info, err := os.Stat(filePath)
+	if err == nil && !info.Mode().IsRegular() {
+		// FIFOs and character devices are read once as they stream, like stdin
+		return inputSource{name: name, open: func(ctx context.Context) (io.ReadCloser, error) {
+			return openStream(ctx, filePath)
+		}}
+	}
+	src := inputSource{name: name, path: filePath}
+	if err == nil {
+		src.size, src.mtime = info.Size(), info.ModTime()
+	}
+	src.open = func(context.Context) (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}
+	return src
+}
+
+// Open a FIFO or character device. Opening a FIFO waits for a writer, which
+// is given up when ctx is cancelled.
+func openStream(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	type result struct {
+		file *os.File
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		file, err := os.Open(filePath)
+		opened <- result{file, err}
+	}()
+	select {
+	case r := <-opened:
+		return r.file, r.err
+	case <-ctx.Done():
+		// Stand in for the writer so the pending open returns
+		if writer, err := os.OpenFile(filePath, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			writer.Close()
+		}
+		go func() {
+			if r := <-opened; r.file != nil {
+				r.file.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Read a source, calling fn with the decompressed content of the source itself
+// or of every trufflehog output file inside it when it is an archive.
+// Cancelling ctx closes the source so blocked reads return.
+func readSource(ctx context.Context, src inputSource, filter inputFilter, fn func(name string, r io.Reader) error) error {
+	raw, err := src.open(ctx)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
+	defer raw.Close()
+
+	if isArchive(src.name) {
+		return readArchive(src.name, raw, filter, fn)
+	}
+	r, err := decompress(raw, src.name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return fn(src.name, r)
+}
+
+// Call fn for every trufflehog output file inside a tar or zip archive, named archive!entry
+func readArchive(name string, raw io.ReadCloser, filter inputFilter, fn func(name string, r io.Reader) error) error {
+	entry := func(entryName string, r io.Reader) error {
+		if !isFindingsFile(entryName) || !filter.accepts(entryName) {
+			return nil
+		}
+		content, err := decompress(io.NopCloser(r), entryName)
+		if err != nil {
+			return fmt.Errorf("%s!%s: %w", name, entryName, err)
+		}
+		defer content.Close()
+		return fn(name+"!"+entryName, content)
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		reader, spool, err := openZip(raw)
+		if err != nil {
+			return err
+		}
+		if spool != nil {
+			defer spool.Close()
+		}
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = entry(file.Name, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// .tgz is a gzip-compressed tar under another name
+	compressed := strings.Replace(name, ".tgz", ".tar.gz", 1)
+	stream, err := decompress(raw, compressed)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := entry(header.Name, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// Open a zip archive, spooling streams that cannot seek to a temporary file.
+// The spool file, if any, is returned for the caller to close.
+func openZip(raw io.ReadCloser) (*zip.Reader, *os.File, error) {
+	if file, ok := raw.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			reader, err := zip.NewReader(file, info.Size())
+			return reader, nil, err
+		}
+	}
+	spool, err := os.CreateTemp("", "trufflehog-searcher-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	os.Remove(spool.Name())
+	size, err := io.Copy(spool, raw)
+	if err == nil {
+		var reader *zip.Reader
+		if reader, err = zip.NewReader(spool, size); err == nil {
+			return reader, spool, nil
+		}
+	}
+	spool.Close()
+	return nil, nil, err
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Health information collected for a single file
+type lintResult struct {
+	path       string
+	hash       string
+	lines      int
+	findings   int
+	badLines   []int
+	empty      bool
+	truncated  bool
+	schemas    map[string]int
+	readErrMsg string
+}
+
+// Check an input directory for problems before it is searched
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory containing JSON trufflehog output files (required)")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	files, err := listInputFiles(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	var results []*lintResult
+	for _, file := range files {
+		results = append(results, lintFile(file))
+	}
+
+	if problems := printLintReport(results); problems > 0 {
+		os.Exit(1)
+	}
+}
+
+// Read a file once, hashing it and validating every line
+func lintFile(filePath string) *lintResult {
+	result := &lintResult{path: filePath, schemas: map[string]int{}}
+	fileHandle, err := openInput(filePath)
+	if err != nil {
+		result.readErrMsg = err.Error()
+		return result
+	}
+	defer fileHandle.Close()
+
+	hasher := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(fileHandle, hasher))
+	content := false
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			result.lines++
+			complete := line[len(line)-1] == '\n'
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				content = true
+				var jsonData JSONData
+				if jsonErr := json.Unmarshal(line, &jsonData); jsonErr != nil {
+					if complete {
+						result.badLines = append(result.badLines, result.lines)
+					} else {
+						result.truncated = true
+					}
+				} else {
+					result.findings++
+					result.schemas[schemaSignature(jsonData)]++
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.readErrMsg = err.Error()
+			break
+		}
+	}
+	result.empty = !content
+	result.hash = hex.EncodeToString(hasher.Sum(nil))
+	return result
+}
+
+// Describe the shape of a finding: its top-level keys and its source metadata kind
+func schemaSignature(data JSONData) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	source := "none"
+	if sourceData, ok := searcher.Get(data, "SourceMetadata.Data"); ok {
+		if sourceMap, ok := sourceData.(map[string]interface{}); ok {
+			var kinds []string
+			for kind := range sourceMap {
+				kinds = append(kinds, kind)
+			}
+			sort.Strings(kinds)
+			source = strings.Join(kinds, "+")
+		}
+	}
+	return source + " [" + strings.Join(keys, ",") + "]"
+}
+
+// Print the health report and return the number of problems found
+func printLintReport(results []*lintResult) int {
+	problems := 0
+	totalFindings := 0
+	schemas := map[string]int{}
+	schemaExample := map[string]string{}
+	hashes := map[string][]string{}
+
+	fmt.Println("Archive Health Report:")
+	fmt.Println(strings.Repeat("-", 40))
+	for _, result := range results {
+		name := filepath.Base(result.path)
+		totalFindings += result.findings
+		if result.readErrMsg != "" {
+			fmt.Printf("- %s: unreadable (%s)\n", name, result.readErrMsg)
+			problems++
+			continue
+		}
+		if result.empty {
+			fmt.Printf("- %s: empty file\n", name)
+			problems++
+		}
+		if result.truncated {
+			fmt.Printf("- %s: truncated last line (line %d)\n", name, result.lines)
+			problems++
+		}
+		if len(result.badLines) > 0 {
+			fmt.Printf("- %s: %d non-JSON line(s), first at line %d\n", name, len(result.badLines), result.badLines[0])
+			problems++
+		}
+		for schema, count := range result.schemas {
+			if _, seen := schemaExample[schema]; !seen {
+				schemaExample[schema] = name
+			}
+			schemas[schema] += count
+		}
+		if !result.empty {
+			hashes[result.hash] = append(hashes[result.hash], name)
+		}
+	}
+
+	var duplicates []string
+	for _, names := range hashes {
+		if len(names) > 1 {
+			duplicates = append(duplicates, strings.Join(names, ", "))
+		}
+	}
+	sort.Strings(duplicates)
+	for _, names := range duplicates {
+		fmt.Printf("- duplicate files (identical content): %s\n", names)
+		problems++
+	}
+
+	if len(schemas) > 1 {
+		shapes := make([]string, 0, len(schemas))
+		for schema := range schemas {
+			shapes = append(shapes, schema)
+		}
+		sort.Slice(shapes, func(i, j int) bool { return schemas[shapes[i]] > schemas[shapes[j]] })
+		fmt.Printf("- mixed schemas: %d distinct finding shapes\n", len(schemas))
+		for _, schema := range shapes {
+			fmt.Printf("    %d finding(s) like %s, e.g. in %s\n", schemas[schema], schema, schemaExample[schema])
+		}
+		problems++
+	}
+
+	if problems == 0 {
+		fmt.Println("No problems found.")
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("Files: %d, findings: %d, problems: %d\n", len(results), totalFindings, problems)
+	return problems
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often a run waiting for the database lock tries again
+const lockRetryInterval = 250 * time.Millisecond
+
+// Returned by tryLockFile when another process holds the lock
+var errLockHeld = errors.New("lock held by another process")
+
+// Advisory lock serializing the runs that write to a findings database.
+// The lock is taken on a file next to the database and released when the
+// process exits, even when it is killed.
+type dbLock struct {
+	file *os.File // nil when the process already held the lock
+	path string
+}
+
+// Databases this process holds the lock of, counted by lockDB, so a run
+// holding it can write again, like the terminal UI of a -cache search
+var heldLocks = struct {
+	sync.Mutex
+	count map[string]int
+}{count: map[string]int{}}
+
+// Lock the findings database for this run. When another run holds it, wait up
+// to wait for it to finish (not at all for 0) before giving up.
+func lockDB(ctx context.Context, dbPath string, wait time.Duration) (*dbLock, error) {
+	key, err := filepath.Abs(dbPath)
+	if err != nil {
+		key = dbPath
+	}
+	heldLocks.Lock()
+	if heldLocks.count[key] > 0 {
+		heldLocks.count[key]++
+		heldLocks.Unlock()
+		return &dbLock{path: key}, nil
+	}
+	heldLocks.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(dbPath+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		err := tryLockFile(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLockHeld) {
+			file.Close()
+			return nil, fmt.Errorf("locking database %s: %w", dbPath, err)
+		}
+		if !time.Now().Before(deadline) {
+			holder := lockHolder(dbPath + ".lock")
+			file.Close()
+			if wait > 0 {
+				return nil, fmt.Errorf("database %s is still in use by another run after %s (%s)", dbPath, wait, holder)
+			}
+			return nil, fmt.Errorf("database %s is in use by another run (%s); pass -wait to wait for it", dbPath, holder)
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	// Record the holder for the runs that find the database locked
+	file.Truncate(0)
+	fmt.Fprintf(file, "pid %d since %s: %s\n", os.Getpid(), time.Now().Format(time.DateTime), strings.Join(os.Args, " "))
+	heldLocks.Lock()
+	heldLocks.count[key]++
+	heldLocks.Unlock()
+	return &dbLock{file: file, path: key}, nil
+}
+
+// Release the lock
+func (l *dbLock) Close() error {
+	heldLocks.Lock()
+	heldLocks.count[l.path]--
+	heldLocks.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Who holds a lock, as recorded in its file
+func lockHolder(path string) string {
+	content, err := os.ReadFile(path)
+	if holder := strings.TrimSpace(string(content)); err == nil && holder != "" {
+		return holder
+	}
+	return "holder unknown"
+}
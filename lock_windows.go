@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Locked byte range, past the holder recorded in the file so others can still read it
+var lockRange = windows.Overlapped{OffsetHigh: 1}
+
+// Take an exclusive lock on a file without blocking
+func tryLockFile(file *os.File) error {
+	ol := lockRange
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	ol := lockRange
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &ol)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// What a search read and how, recorded by -manifest so a search used as
+// evidence can be run again later and checked with -require-manifest
+type runManifest struct {
+	Tool      string           `json:"tool"`
+	Version   string           `json:"version"`
+	GoVersion string           `json:"go_version"`
+	CreatedAt string           `json:"created_at"`
+	Args      []string         `json:"args"`
+	Config    manifestFile     `json:"config"`
+	Searches  []manifestSearch `json:"searches"`
+	Inputs    []manifestFile   `json:"inputs"`
+	Outputs   []manifestFile   `json:"outputs,omitempty"`
+}
+
+// One search of the run, a preset of a multi-preset run
+type manifestSearch struct {
+	Preset  string            `json:"preset,omitempty"`
+	Flags   map[string]string `json:"flags"`
+	Options searcher.Options  `json:"options"`
+	Files   []manifestFile    `json:"files,omitempty"` // files named by the flags, such as -terms-file
+}
+
+type manifestFile struct {
+	Flag   string `json:"flag,omitempty"` // the flag naming the file, compared instead of its path
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // empty when the file does not exist
+}
+
+// Flags naming where a run reads its input and writes its results, or only
+// tuning its speed; a reproduction may set them differently
+var manifestLocalFlags = map[string]bool{
+	"i": true, "config": true, "db": true, "output-file": true, "out-dir": true, "report": true, "graph": true,
+	"metrics-json": true, "manifest": true, "require-manifest": true, "progress": true,
+	"t": true, "dt": true, "line-workers": true, "cpu": true, "tune": true, "wait": true,
+}
+
+// Flags naming files whose content changes the results
+var manifestFileFlags = []string{"terms-file", "scope-file", "ignore-file", "baseline", "policy"}
+
+// Version of this build: the module version, or the commit it was built from
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision":
+			version += " " + setting.Value
+		case setting.Key == "vcs.modified" && setting.Value == "true":
+			version += "-dirty"
+		}
+	}
+	return version
+}
+
+// Record the searches of a run and hash the configuration and every input
+func newRunManifest(ctx context.Context, presets []string, jobFlags []*searchFlags, walk func(fn func(inputSource) error) error) (*runManifest, error) {
+	m := &runManifest{
+		Tool:      "trufflehog-searcher",
+		Version:   toolVersion(),
+		GoVersion: runtime.Version(),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Args:      os.Args[1:],
+	}
+	var err error
+	if m.Config, err = hashManifestFile(jobFlags[0].configPath); err != nil {
+		return nil, err
+	}
+	for i, f := range jobFlags {
+		opts, err := f.options()
+		if err != nil {
+			return nil, err
+		}
+		search := manifestSearch{Preset: presets[i], Flags: f.resolved, Options: opts}
+		for _, name := range manifestFileFlags {
+			path := f.resolved[name]
+			if info, err := os.Stat(path); path == "" || err != nil || info.IsDir() {
+				continue
+			}
+			file, err := hashManifestFile(path)
+			if err != nil {
+				return nil, err
+			}
+			file.Flag = name
+			search.Files = append(search.Files, file)
+		}
+		m.Searches = append(m.Searches, search)
+	}
+
+	err = walk(func(src inputSource) error {
+		if src.path == "" && src.name == "stdin" {
+			return errors.New("standard input cannot be recorded in a manifest")
+		}
+		reader, err := src.open(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", src.name, err)
+		}
+		defer reader.Close()
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, reader)
+		if err != nil {
+			return fmt.Errorf("%s: %w", src.name, err)
+		}
+		m.Inputs = append(m.Inputs, manifestFile{Name: src.name, Size: size, SHA256: hex.EncodeToString(hasher.Sum(nil))})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Inputs, func(i, j int) bool { return m.Inputs[i].Name < m.Inputs[j].Name })
+	return m, nil
+}
+
+// Hash a file named in the manifest; a missing file is recorded without a hash
+func hashManifestFile(path string) (manifestFile, error) {
+	file := manifestFile{Name: path}
+	info, err := os.Stat(path)
+	if path == "" || errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	file.Size = info.Size()
+	file.SHA256, err = hashFile(path)
+	return file, err
+}
+
+// Hash the result files of the run, once they are written
+func (m *runManifest) addOutputs(jobFlags []*searchFlags) error {
+	for _, f := range jobFlags {
+		if f.outputFile == "" || f.outputFile == os.DevNull {
+			continue
+		}
+		file, err := hashManifestFile(f.outputFile)
+		if err != nil {
+			return err
+		}
+		m.Outputs = append(m.Outputs, file)
+	}
+	return nil
+}
+
+func (m *runManifest) write(path string) error {
+	return os.WriteFile(path, marshalIndented(m), 0o644)
+}
+
+func readRunManifest(path string) (*runManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &runManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Differences between a recorded run and this one that change its results:
+// flags, options, configuration, files named by flags and inputs. Paths,
+// output locations and speed settings may differ.
+func (m *runManifest) differences(current *runManifest) []string {
+	var diffs []string
+	if m.Config.SHA256 != current.Config.SHA256 {
+		diffs = append(diffs, fmt.Sprintf("configuration file %s has changed", current.Config.Name))
+	}
+	if len(m.Searches) != len(current.Searches) {
+		return append(diffs, fmt.Sprintf("%d search(es) recorded, %d run", len(m.Searches), len(current.Searches)))
+	}
+	for i, recorded := range m.Searches {
+		run := current.Searches[i]
+		label := "search"
+		if run.Preset != "" {
+			label = "preset " + run.Preset
+		}
+		if recorded.Preset != run.Preset {
+			diffs = append(diffs, fmt.Sprintf("%s: recorded as preset %q", label, recorded.Preset))
+		}
+		flagged := len(diffs)
+		for _, name := range flagNames(recorded.Flags, run.Flags) {
+			if manifestLocalFlags[name] || recorded.Flags[name] == run.Flags[name] {
+				continue
+			}
+			diffs = append(diffs, fmt.Sprintf("%s: -%s is %q, recorded %q", label, name, run.Flags[name], recorded.Flags[name]))
+		}
+		// With the same flags, different options come from the files they read
+		if len(diffs) == flagged && marshalOptions(run.Options) != marshalOptions(recorded.Options) {
+			diffs = append(diffs, fmt.Sprintf("%s: the search options differ, e.g. the content of -terms-file or -scope-file", label))
+		}
+		diffs = append(diffs, fileDifferences(label+": file", recorded.Files, run.Files)...)
+	}
+	return append(diffs, fileDifferences("input", m.Inputs, current.Inputs)...)
+}
+
+// Files added, removed or changed between two lists
+func fileDifferences(kind string, recorded, current []manifestFile) []string {
+	key := func(file manifestFile) string {
+		if file.Flag != "" {
+			return "-" + file.Flag
+		}
+		return file.Name
+	}
+	hashes := map[string]string{}
+	for _, file := range current {
+		hashes[key(file)] = file.SHA256
+	}
+	seen := map[string]bool{}
+	var diffs []string
+	for _, file := range recorded {
+		seen[key(file)] = true
+		hash, ok := hashes[key(file)]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s %s is missing", kind, file.Name))
+		case hash != file.SHA256:
+			diffs = append(diffs, fmt.Sprintf("%s %s has changed", kind, file.Name))
+		}
+	}
+	for _, file := range current {
+		if !seen[key(file)] {
+			diffs = append(diffs, fmt.Sprintf("%s %s was not recorded", kind, file.Name))
+		}
+	}
+	return diffs
+}
+
+func marshalOptions(opts searcher.Options) string {
+	encoded, _ := json.Marshal(opts)
+	return string(encoded)
+}
+
+// Names of the flags of either map, sorted
+func flagNames(a, b map[string]string) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check this run against the manifest of -require-manifest before searching
+func requireManifest(path string, current *runManifest) error {
+	recorded, err := readRunManifest(path)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+	if recorded.Version != current.Version {
+		fmt.Fprintf(os.Stderr, "Warning: %s was written by version %s, this is %s\n", path, recorded.Version, current.Version)
+	}
+	if diffs := recorded.differences(current); len(diffs) > 0 {
+		return fmt.Errorf("this run does not match %s:\n  %s", path, strings.Join(diffs, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Combine many JSON lines files into one, dropping duplicate findings
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory containing JSON trufflehog output files (required)")
+	outFile := fs.String("o", "", "Output file (default: stdout)")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	files, err := listInputFiles(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		// Never read the output back in as an input
+		if absOut, err := filepath.Abs(*outFile); err == nil {
+			var inputs []string
+			for _, file := range files {
+				if absFile, err := filepath.Abs(file); err != nil || absFile != absOut {
+					inputs = append(inputs, file)
+				}
+			}
+			files = inputs
+		}
+		fileHandle, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Printf("Error creating file %s: %v\n", *outFile, err)
+			os.Exit(1)
+		}
+		defer fileHandle.Close()
+		out = fileHandle
+	}
+
+	writer := bufio.NewWriter(out)
+	seen := map[string]bool{}
+	written, duplicates := 0, 0
+	for _, file := range files {
+		fileHandle, err := openInput(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file %s: %v\n", file, err)
+			continue
+		}
+		name := filepath.Base(file)
+		err = scanRecords(fileHandle, func(record *searcher.Record) {
+			key := fingerprint(record.Data, occurrenceFields)
+			if seen[key] {
+				duplicates++
+				return
+			}
+			seen[key] = true
+			writeMergedLine(writer, record)
+			written++
+		}, func(lineNum int, err error) {
+			fmt.Fprintf(os.Stderr, "Error parsing JSON at line %d in file %s: %v\n", lineNum, name, err)
+		})
+		fileHandle.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", name, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d files: %d unique findings written, %d duplicates removed\n", len(files), written, duplicates)
+}
+
+// Write the kept finding as it was read, so its bytes, and the digests of
+// tools hashing them, stay those of the input. Findings spanning several
+// lines of a JSON array are compacted onto one; only whitespace changes.
+func writeMergedLine(w *bufio.Writer, record *searcher.Record) {
+	line := record.Raw
+	if line == nil {
+		// Decoded while reading, without its raw JSON
+		line, _ = json.Marshal(record.Data)
+	} else if bytes.ContainsAny(line, "\r\n") {
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, line) == nil {
+			line = compacted.Bytes()
+		}
+	}
+	w.Write(line)
+	w.WriteByte('\n')
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Payload formats of -notify-format
+var notifyFormats = []string{"generic", "slack"}
+
+// Findings included in full in a notification
+const notifySamples = 10
+
+// Summary of a batch of matches POSTed to -notify-webhook. Secrets of the
+// sample findings are always masked, whatever -show-secrets says.
+type notification struct {
+	Count        int            `json:"count"`
+	Detectors    map[string]int `json:"detectors"`
+	Repositories map[string]int `json:"repositories"`
+	Samples      []JSONData     `json:"samples"`
+}
+
+func newNotification(matches []*searchMatch) notification {
+	n := notification{Count: len(matches), Detectors: map[string]int{}, Repositories: map[string]int{}}
+	for _, m := range matches {
+		flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+		n.Detectors[orNone(flat.DetectorName)]++
+		n.Repositories[orNone(flat.Repository)]++
+		if len(n.Samples) < notifySamples {
+			n.Samples = append(n.Samples, searcher.Redact(m.data, searcher.DefaultRedactFields))
+		}
+	}
+	return n
+}
+
+// POST a summary of the matches to a webhook, as generic JSON or as a Slack message
+func sendNotification(ctx context.Context, url, format string, matches []*searchMatch) error {
+	n := newNotification(matches)
+	if format != "slack" {
+		return postJSON(ctx, url, nil, n)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "*trufflehog-searcher: %d new match(es)*\n", n.Count)
+	fmt.Fprintf(&text, "Detectors: %s\n", formatCounts(n.Detectors))
+	fmt.Fprintf(&text, "Repositories: %s\n", formatCounts(n.Repositories))
+	for _, m := range matches[:min(len(matches), notifySamples)] {
+		flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+		fmt.Fprintf(&text, "• %s in %s", orNone(flat.DetectorName), orNone(flat.Repository))
+		if flat.File != "" {
+			fmt.Fprintf(&text, " %s:%d", flat.File, flat.Line)
+		}
+		fmt.Fprintf(&text, " (%s)\n", searcher.Mask(searcher.Secret(m.data)))
+	}
+	return postJSON(ctx, url, nil, map[string]string{"text": text.String()})
+}
+
+// Counts as "a (3), b (1)", largest first
+func formatCounts(counts map[string]int) string {
+	var parts []string
+	for _, entry := range sortedCounts(counts) {
+		parts = append(parts, fmt.Sprintf("%s (%d)", entry.value, entry.count))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Output layouts supported by --layout
+var outputLayouts = []string{"repo", "detector"}
+
+// Destination receiving every match of a search. Implementations must be safe for concurrent use.
+type resultSink interface {
+	write(data JSONData, sourceFile string, sourceLine int) error
+	Close() error
+}
+
+// One matched finding as written to result files
+type resultRecord struct {
+	SourceFile string   `json:"source_file"`
+	SourceLine int      `json:"source_line"`
+	Finding    JSONData `json:"finding"`
+
+	// Set by -o json and -out-dir, computed before the secrets were masked
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Writes matched findings into one JSON lines file per repository or detector
+type layoutWriter struct {
+	dir    string
+	layout string
+	redact []string // nil with -show-secrets
+	mu     sync.Mutex
+	files  map[string]*os.File
+}
+
+// Create the output directory for a layout writer
+func newLayoutWriter(dir, layout string, redact []string) (*layoutWriter, error) {
+	if layout != "repo" && layout != "detector" {
+		return nil, fmt.Errorf("unsupported layout %q (expected %s)", layout, strings.Join(outputLayouts, " or "))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &layoutWriter{dir: dir, layout: layout, redact: redact, files: map[string]*os.File{}}, nil
+}
+
+// Append a finding to the file of its repository or detector, with secrets
+// masked as in the output
+func (l *layoutWriter) write(data JSONData, sourceFile string, sourceLine int) error {
+	field := "repository"
+	if l.layout == "detector" {
+		field = "DetectorName"
+	}
+	value, _ := searcher.Lookup(data, field)
+	name := layoutFileName(searcher.String(value))
+
+	record := resultRecord{SourceFile: sourceFile, SourceLine: sourceLine, Finding: data, Fingerprint: fingerprint(data, occurrenceFields)}
+	if l.redact != nil {
+		record.Finding = searcher.Redact(data, l.redact)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, ok := l.files[name]
+	if !ok {
+		file, err = os.Create(filepath.Join(l.dir, name+".json"))
+		if err != nil {
+			return err
+		}
+		l.files[name] = file
+	}
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Close every result file
+func (l *layoutWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var firstErr error
+	for _, file := range l.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Turn a repository URL or detector name into a safe file name,
+// e.g. https://github.com/acme/api.git becomes github.com_acme_api
+func layoutFileName(value string) string {
+	if i := strings.Index(value, "://"); i >= 0 {
+		value = value[i+3:]
+	}
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "/"), ".git")
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, value)
+	name = strings.Trim(name, "._")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Something a worker reports about a source, in input order
+type outputEvent struct {
+	header  string       // a file (or archive entry) starts
+	message string       // diagnostic such as a parse error
+	match   *searchMatch // a matched finding
+	flush   bool         // end of a batch in -watch mode
+}
+
+// Events of one source for one job
+type sourceOutput struct {
+	events chan outputEvent
+}
+
+// Matches buffered per source before the printer blocks the worker
+const sourceOutputBuffer = 256
+
+func newSourceOutput() *sourceOutput {
+	return &sourceOutput{events: make(chan outputEvent, sourceOutputBuffer)}
+}
+
+// Writes the results of one job. A single goroutine drains the sources in
+// order, so output is never interleaved and aggregations need no locking.
+type printer struct {
+	job       *searchJob
+	flags     *searchFlags
+	metrics   *runMetrics
+	w         io.Writer
+	file      *os.File // -output-file
+	diag      io.Writer
+	stderr    io.Writer
+	writer    findingWriter // csv, sarif and parquet
+	fields    []string      // -fields projection
+	csv       *csv.Writer   // -fields with -o csv
+	redact    []string      // nil with -show-secrets
+	multiTerm bool          // several terms were searched, so matches show which ones hit
+
+	matched   int
+	collected []*searchMatch // kept for -group-by, -anomalies, -report and -tui
+	groups    *spillStore    // -group-by under -max-memory, instead of collected
+	pending   []*searchMatch // not yet sent to -notify-webhook
+	stats     *searchStats
+	histogram map[string]int // match counts per value of the -histogram field
+	ranked    *topMatches    // -rank
+	dedupe    *dedupeSet
+	expires   time.Time // earliest expiry still ahead among the matches
+	done      chan struct{}
+}
+
+// Where the results and the diagnostics of a run are written
+type outputStreams struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Open the output of a job
+func newPrinter(job *searchJob, metrics *runMetrics, multiTerm bool, streams outputStreams) (*printer, error) {
+	f := job.flags
+	p := &printer{job: job, flags: f, metrics: metrics, w: streams.stdout, diag: streams.stderr, stderr: streams.stderr, multiTerm: multiTerm, done: make(chan struct{})}
+	if f.outputFile != "" {
+		file, err := os.Create(f.outputFile)
+		if err != nil {
+			return nil, err
+		}
+		p.file, p.w = file, file
+	}
+	// Diagnostics stay inline with plain text results, as they always were
+	if f.format == "text" && f.outputFile == "" && f.fields == "" && p.streaming() {
+		p.diag = streams.stdout
+	}
+	if !f.showSecrets {
+		p.redact = splitList(f.redactFields)
+	}
+	p.fields = splitList(f.fields)
+	switch {
+	case len(p.fields) > 0 && f.format == "csv":
+		p.csv = csv.NewWriter(p.w)
+		if err := p.csv.Write(p.fields); err != nil {
+			return nil, err
+		}
+	case f.format == "csv" || f.format == "sarif" || f.format == "parquet":
+		writer, err := newFindingWriter(f.format, p.w)
+		if err != nil {
+			return nil, err
+		}
+		p.writer = writer
+	}
+	if f.stats {
+		p.stats = newSearchStats()
+		p.stats.perWorker = f.statsVerbose
+	}
+	if f.dedupe {
+		p.dedupe = newDedupeSet(splitList(f.dedupeFields))
+	}
+	if f.maxMemory > 0 {
+		if f.groupBy != "" {
+			p.groups = newSpillStore(int64(f.maxMemory))
+		}
+		if f.dedupe {
+			p.dedupe.spill = newSpillStore(int64(f.maxMemory))
+		}
+	}
+	if f.histogram != "" {
+		p.histogram = map[string]int{}
+	}
+	if f.rank {
+		p.ranked = newTopMatches(f.top)
+	}
+	return p, nil
+}
+
+// Whether matches are written as they arrive rather than summarized at the end
+func (p *printer) streaming() bool {
+	f := p.flags
+	return !f.quiet && !f.count && !f.stats && !f.dedupe && f.groupBy == "" && f.histogram == "" && !f.anomalies && !f.rank && !f.bySeverity && !f.tui
+}
+
+// Drain the sources in order on a new goroutine
+func (p *printer) start(queue <-chan *sourceOutput) {
+	go func() {
+		defer close(p.done)
+		for out := range queue {
+			for event := range out.events {
+				p.handle(event)
+			}
+		}
+	}()
+}
+
+// Wait until every source has been drained
+func (p *printer) wait() {
+	<-p.done
+}
+
+func (p *printer) handle(event outputEvent) {
+	switch {
+	case event.header != "":
+		if p.streaming() && p.flags.format == "text" && p.fields == nil {
+			fmt.Fprintf(p.w, "\n--- Searching in file: %s ---\n", event.header)
+		}
+	case event.message != "":
+		if !p.flags.quiet {
+			fmt.Fprintln(p.diag, event.message)
+		}
+	case event.match != nil:
+		p.add(event.match)
+	case event.flush:
+		p.notify(context.Background())
+	}
+}
+
+// Record a match and write it when streaming
+func (p *printer) add(m *searchMatch) {
+	if m.ignoredBy == "" {
+		p.matched++
+	}
+	for _, warning := range m.warnings {
+		fmt.Fprintln(p.diag, warning)
+	}
+	if (p.flags.groupBy != "" && p.groups == nil) || p.flags.anomalies || (p.flags.bySeverity && !p.flags.rank) || p.flags.report != "" || p.flags.graph != "" || p.flags.tui {
+		p.collected = append(p.collected, m)
+	}
+	if p.groups != nil {
+		value, _ := searcher.Lookup(m.data, p.flags.groupBy)
+		if err := p.groups.add(orNone(searcher.String(value)), m.downgrade(), m); err != nil {
+			fmt.Fprintf(p.diag, "Error spilling matches to disk: %v\n", err)
+		}
+	}
+	if p.flags.notifyWebhook != "" && m.ignoredBy == "" {
+		p.pending = append(p.pending, m)
+	}
+	if p.stats != nil {
+		p.stats.add(m)
+	}
+	if p.dedupe != nil {
+		if err := p.dedupe.add(m); err != nil {
+			fmt.Fprintf(p.diag, "Error spilling matches to disk: %v\n", err)
+		}
+	}
+	if p.ranked != nil && m.ignoredBy == "" {
+		p.ranked.add(m)
+	}
+	if p.histogram != nil && m.ignoredBy == "" {
+		value, _ := searcher.Lookup(m.data, p.flags.histogram)
+		p.histogram[orNone(searcher.String(value))]++
+	}
+	if m.expiry.Status == "valid" {
+		if expires, err := time.Parse(time.RFC3339, m.expiry.ExpiresAt); err == nil && (p.expires.IsZero() || expires.Before(p.expires)) {
+			p.expires = expires
+		}
+	}
+	if p.streaming() {
+		p.emit(m)
+	}
+}
+
+// The finding as shown to people, with secrets masked unless -show-secrets
+func (p *printer) display(data JSONData) JSONData {
+	if p.redact == nil {
+		return data
+	}
+	return searcher.Redact(data, p.redact)
+}
+
+// Write one match in the output format
+func (p *printer) emit(m *searchMatch) {
+	data := p.display(m.data)
+	var err error
+	switch {
+	case len(p.fields) > 0:
+		err = p.emitFields(m, data)
+	case p.flags.format == "text":
+		writeTextMatch(p.w, m, data, p.multiTerm)
+	case p.flags.format == "json":
+		err = writeJSONLine(p.w, newJSONResult(m, data, p.multiTerm))
+	default:
+		err = p.writer.WriteFinding(data, m.sourceFile, m.sourceLine)
+	}
+	if err != nil {
+		fmt.Fprintf(p.diag, "Error writing result: %v\n", err)
+	}
+}
+
+// Write only the -fields of a match
+func (p *printer) emitFields(m *searchMatch, data JSONData) error {
+	values := make([]interface{}, len(p.fields))
+	for i, field := range p.fields {
+		values[i], _ = searcher.Lookup(data, field)
+	}
+	switch p.flags.format {
+	case "json":
+		projection := make(map[string]interface{}, len(p.fields))
+		for i, field := range p.fields {
+			projection[field] = values[i]
+		}
+		return writeJSONLine(p.w, projection)
+	case "csv":
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = searcher.String(value)
+		}
+		return p.csv.Write(row)
+	}
+	columns := make([]string, len(values))
+	for i, value := range values {
+		// Keep one finding per line even for values spanning lines
+		columns[i] = strings.NewReplacer("\t", " ", "\n", " ", "\r", "").Replace(searcher.String(value))
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(columns, "\t"))
+	return err
+}
+
+// Print a match in the original human-readable layout
+func writeTextMatch(w io.Writer, m *searchMatch, data JSONData, multiTerm bool) {
+	fmt.Fprintf(w, "\n--- Related Data at %s ---\n", m.location)
+	if code, name, known := detectorType(m.data); known {
+		fmt.Fprintf(w, "--- Detector type: %s (%d) ---\n", name, code)
+	}
+	if multiTerm {
+		fmt.Fprintf(w, "--- Matched terms: %s ---\n", strings.Join(m.terms, ", "))
+	}
+	if m.policy {
+		fmt.Fprintf(w, "--- Policy: %s ---\n", m.decision)
+	}
+	if m.status != "" {
+		note := ""
+		if m.note != "" {
+			note = " (" + m.note + ")"
+		}
+		fmt.Fprintf(w, "--- Triage: %s%s, fingerprint %s ---\n", m.status, note, m.fingerprint)
+	}
+	if m.ignoredBy != "" {
+		fmt.Fprintf(w, "--- Ignored by rule: %s ---\n", m.ignoredBy)
+	}
+	if m.anomaly != "" {
+		fmt.Fprintf(w, "--- Anomaly: %s ---\n", m.anomaly)
+	}
+	if m.risk > 0 {
+		fmt.Fprintf(w, "--- Risk score: %.1f ---\n", m.risk)
+	}
+	if fp := m.falsePositive; fp.Score > 0 {
+		fmt.Fprintf(w, "--- False positive score: %.2f (%s) ---\n", fp.Score, strings.Join(fp.Reasons, ", "))
+	}
+	if m.expiry.Status == "expired" || m.expiry.Status == "valid" {
+		fmt.Fprintf(w, "--- Expiry: %s ---\n", m.expiry)
+	}
+	if m.thirdParty != "" {
+		fmt.Fprintf(w, "--- Third-party code: %s ---\n", m.thirdParty)
+	}
+	if m.fieldPath != "" {
+		fmt.Fprintf(w, "--- Field: %s ---\n", m.fieldPath)
+	}
+	if m.explain != nil {
+		fmt.Fprintln(w, "--- Explain ---")
+		for _, check := range m.explain {
+			fmt.Fprintf(w, "  %s\n", check)
+		}
+	}
+	if m.link != "" {
+		fmt.Fprintf(w, "--- Link: %s ---\n", m.link)
+	}
+	if m.blame != nil {
+		writeBlame(w, m.blame)
+	}
+	writePrettyJSON(w, data)
+}
+
+// A match as written by -o json. The source_file, source_line and finding
+// fields make the output readable again with -input-format self.
+type jsonResult struct {
+	SourceFile    string           `json:"source_file"`
+	SourceLine    int              `json:"source_line"`
+	Fingerprint   string           `json:"fingerprint"`
+	DetectorType  string           `json:"detector_type_name,omitempty"`
+	Terms         []string         `json:"matched_terms,omitempty"`
+	Field         string           `json:"matched_field,omitempty"`
+	Policy        *policyDecision  `json:"policy,omitempty"`
+	Status        string           `json:"triage_status,omitempty"`
+	Note          string           `json:"triage_note,omitempty"`
+	IgnoredBy     string           `json:"ignored_by,omitempty"`
+	Anomaly       string           `json:"anomaly,omitempty"`
+	RiskScore     float64          `json:"risk_score,omitempty"`
+	FalsePositive *fpScore         `json:"false_positive,omitempty"`
+	Expiry        *expiryInfo      `json:"expiry,omitempty"`
+	ThirdParty    string           `json:"third_party,omitempty"`
+	Explain       []searcher.Check `json:"explain,omitempty"`
+	Link          string           `json:"permalink,omitempty"`
+	Blame         *blameInfo       `json:"blame,omitempty"`
+	Finding       JSONData         `json:"finding"`
+}
+
+func newJSONResult(m *searchMatch, data JSONData, multiTerm bool) jsonResult {
+	result := jsonResult{
+		SourceFile: m.sourceFile, SourceLine: m.sourceLine, Fingerprint: m.fingerprint, Field: m.fieldPath, Status: m.status, Note: m.note,
+		IgnoredBy: m.ignoredBy, Anomaly: m.anomaly, RiskScore: m.risk, ThirdParty: m.thirdParty, Explain: m.explain, Link: m.link, Blame: m.blame, Finding: data,
+	}
+	if _, name, known := detectorType(m.data); known {
+		result.DetectorType = name
+	}
+	if multiTerm {
+		result.Terms = m.terms
+	}
+	if m.policy {
+		decision := m.decision
+		result.Policy = &decision
+	}
+	if m.falsePositive.Score > 0 {
+		fp := m.falsePositive
+		result.FalsePositive = &fp
+	}
+	if m.expiry.Status == "expired" || m.expiry.Status == "valid" {
+		expiry := m.expiry
+		result.Expiry = &expiry
+	}
+	return result
+}
+
+// Write a value as one line of JSON
+func writeJSONLine(w io.Writer, value interface{}) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// Print the JSON object in a pretty format
+func printPrettyJSON(data JSONData) {
+	writePrettyJSON(os.Stdout, data)
+}
+
+// Write the JSON object in a pretty format
+func writePrettyJSON(w io.Writer, data JSONData) {
+	prettyData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "Error pretty-printing JSON: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(prettyData))
+}
+
+// Write the summaries, reports and notifications once every source is done
+func (p *printer) finish(ctx context.Context) error {
+	f := p.flags
+	// Third-party code and likely false positives go last wherever the
+	// matches are ordered at the end
+	sort.SliceStable(p.collected, func(i, j int) bool {
+		return p.collected[i].downgrade() < p.collected[j].downgrade()
+	})
+	var anomalies []anomaly
+	if f.anomalies {
+		p.collected, anomalies = markAnomalies(p.collected, f.anomalyFactor)
+	}
+	switch {
+	case f.quiet:
+	case f.count:
+		fmt.Fprintln(p.w, p.matched)
+	case f.stats:
+		p.stats.write(p.w, f.format, p.metrics)
+	case f.dedupe:
+		p.writeDedupe()
+	case f.groupBy != "":
+		p.writeGroups()
+	case f.histogram != "":
+		p.writeHistogram()
+	case f.anomalies:
+		p.writeAnomalies(anomalies)
+	case f.rank && f.bySeverity:
+		p.writeSeverity(p.ranked.sorted())
+	case f.rank:
+		for _, m := range p.ranked.sorted() {
+			p.emit(m)
+		}
+	case f.bySeverity:
+		var matches []*searchMatch
+		for _, m := range p.collected {
+			if m.ignoredBy == "" {
+				matches = append(matches, m)
+			}
+		}
+		p.writeSeverity(matches)
+	}
+
+	if p.job.baseline != nil && f.showResolved && !f.quiet && !f.count {
+		p.writeResolved()
+	}
+	if ignored := p.job.ignored.Load(); ignored > 0 && !f.quiet {
+		hint := " (-show-ignored reveals them)"
+		if f.showIgnored {
+			hint = ""
+		}
+		fmt.Fprintf(p.stderr, "%d finding(s) suppressed by ignore rules or as known example secrets%s\n", ignored, hint)
+	}
+	if hidden := p.job.hiddenFP.Load(); hidden > 0 && !f.quiet {
+		fmt.Fprintf(p.stderr, "%d likely false positive(s) hidden by -hide-likely-fp\n", hidden)
+	}
+	if hidden := p.job.hiddenTP.Load(); hidden > 0 && !f.quiet {
+		fmt.Fprintf(p.stderr, "%d finding(s) left out by -third-party %s\n", hidden, f.thirdParty)
+	}
+
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if p.writer != nil {
+		keep(p.writer.Close())
+	}
+	if p.csv != nil {
+		p.csv.Flush()
+		keep(p.csv.Error())
+	}
+	if p.file != nil {
+		keep(p.file.Close())
+	}
+	if p.groups != nil {
+		p.groups.close()
+	}
+	if p.dedupe != nil && p.dedupe.spill != nil {
+		p.dedupe.spill.close()
+	}
+	if f.report != "" {
+		keep(writeHTMLReport(f.report, p.collected, anomalies, p.redact, time.Now()))
+	}
+	if f.graph != "" {
+		keep(writeGraph(f.graph, p.collected, p.redact))
+	}
+	p.notify(ctx)
+	return firstErr
+}
+
+// Send the pending matches to -notify-webhook
+func (p *printer) notify(ctx context.Context) {
+	if p.flags.notifyWebhook == "" || len(p.pending) == 0 {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	if err := sendNotification(sendCtx, p.flags.notifyWebhook, p.flags.notifyFormat, p.pending); err != nil {
+		fmt.Fprintf(p.stderr, "Error notifying %s: %v\n", p.flags.notifyWebhook, err)
+	}
+	p.pending = nil
+}
+
+// List the baseline findings matching the search that the input no longer has
+func (p *printer) writeResolved() {
+	resolved := p.job.baseline.resolved()
+	switch p.flags.format {
+	case "text":
+		fmt.Fprintf(p.w, "\n--- %d finding(s) of the baseline resolved ---\n", len(resolved))
+		for _, finding := range resolved {
+			writePrettyJSON(p.w, p.display(finding.data))
+		}
+	case "json":
+		for _, finding := range resolved {
+			writeJSONLine(p.w, map[string]interface{}{"resolved": true, "fingerprint": finding.fingerprint, "finding": p.display(finding.data)})
+		}
+	default:
+		fmt.Fprintf(p.stderr, "%d finding(s) of the baseline resolved\n", len(resolved))
+	}
+}
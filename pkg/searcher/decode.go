@@ -0,0 +1,521 @@
+package searcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Record is one entry read from trufflehog output
+type Record struct {
+	Line int                    // line number for JSON lines input, otherwise the 1-based position in the stream
+	Raw  []byte                 // JSON encoding of the finding, nil when it was decoded while reading
+	Data map[string]interface{} // decoded finding, set by Decode
+	Err  error                  // why the entry could not be read or decoded, an ErrCorruptInput
+}
+
+// Decode parses the raw JSON of the record once, setting Data or Err
+func (r *Record) Decode() error {
+	if r.Data != nil || r.Err != nil {
+		return r.Err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(r.Raw, &data); err != nil {
+		r.Err = corruptInput(err)
+		return r.Err
+	}
+	if data == nil {
+		r.Err = corruptInput(errors.New("finding is not a JSON object"))
+		return r.Err
+	}
+	r.Data = data
+	return nil
+}
+
+// Keys under which wrappers around trufflehog put the list of findings
+var envelopeKeys = map[string]bool{"results": true, "findings": true}
+
+// Input layouts detected by the Decoder
+const (
+	layoutUnknown = iota
+	layoutLines   // one finding per line
+	layoutStream  // arrays, envelopes or multi-line objects
+)
+
+// Decoder reads findings from trufflehog output in any of the supported layouts
+type Decoder struct {
+	reader       *bufio.Reader
+	maxLineBytes int
+	layout       int
+	line         int
+	position     int
+
+	stream   *json.Decoder
+	states   []int                  // nesting of arrays and envelope objects being streamed
+	object   map[string]interface{} // fields of an object read key by key
+	streamed bool                   // the current object held findings, so it is not one itself
+	failed   bool
+}
+
+// States of the stream layout
+const (
+	inArray = iota
+	inObject
+	inEnvelope
+)
+
+// NewDecoder reads from r. Lines longer than maxLineBytes are reported as
+// errors without being buffered; 0 accepts any length.
+func NewDecoder(r io.Reader, maxLineBytes int) *Decoder {
+	return &Decoder{reader: bufio.NewReaderSize(r, 64*1024), maxLineBytes: maxLineBytes}
+}
+
+// Next returns the next record, or io.EOF at the end of the input. Problems
+// with single entries are reported in Record.Err; other errors end the input.
+func (d *Decoder) Next() (*Record, error) {
+	if d.layout == layoutUnknown {
+		if err := d.detect(); err != nil {
+			return nil, err
+		}
+	}
+	if d.layout == layoutLines {
+		return d.nextLine()
+	}
+	return d.nextStreamed()
+}
+
+// Pick the layout from the start of the input. A first line holding a whole
+// object means JSON lines; an array, or an object spread over several lines,
+// means a stream of JSON values.
+func (d *Decoder) detect() error {
+	d.layout = layoutLines
+	for {
+		head, err := d.reader.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if head[0] == '\n' {
+			d.reader.ReadByte()
+			d.line++
+			continue
+		}
+		if head[0] == ' ' || head[0] == '\t' || head[0] == '\r' {
+			d.reader.ReadByte()
+			continue
+		}
+		if bom, _ := d.reader.Peek(3); bytes.Equal(bom, []byte{0xef, 0xbb, 0xbf}) {
+			d.reader.Discard(3)
+			continue
+		}
+		break
+	}
+
+	head, _ := d.reader.Peek(1)
+	switch head[0] {
+	case '[':
+		d.startStream()
+	case '{':
+		line, complete := d.peekLine()
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.Equal(trimmed, []byte("{")):
+			d.startStream()
+		case complete && json.Valid(trimmed) && isEnvelope(trimmed):
+			d.startStream()
+		case !complete && envelopeKeys[firstKey(trimmed)]:
+			// A first line too long to look at whole is a finding unless it opens an envelope
+			d.startStream()
+		}
+	}
+	return nil
+}
+
+// The first key of an object, read from its start
+func firstKey(object []byte) string {
+	rest := bytes.TrimLeft(bytes.TrimPrefix(object, []byte("{")), " \t\r\n")
+	if !bytes.HasPrefix(rest, []byte(`"`)) {
+		return ""
+	}
+	end := bytes.IndexByte(rest[1:], '"')
+	if end < 0 {
+		return ""
+	}
+	return string(rest[1 : end+1])
+}
+
+// Look at the first line without consuming it. Reports whether the whole
+// line fit in the read buffer. More input is only waited for while the
+// buffered part holds no line end, so a stream still being written is not
+// held up until the next line arrives.
+func (d *Decoder) peekLine() ([]byte, bool) {
+	size := d.reader.Buffered()
+	for {
+		buf, err := d.reader.Peek(size)
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			return buf[:i], true
+		}
+		if err != nil {
+			// The whole input is a single line
+			return buf, err == io.EOF
+		}
+		if size == d.reader.Size() {
+			return buf, false
+		}
+		size = min(d.reader.Buffered()+1, d.reader.Size())
+	}
+}
+
+// Whether a single-line object wraps a list of findings instead of being one
+func isEnvelope(object []byte) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(object, &fields) != nil {
+		return false
+	}
+	if _, ok := fields["DetectorName"]; ok {
+		return false
+	}
+	for key := range envelopeKeys {
+		if value, ok := fields[key]; ok && bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Decoder) startStream() {
+	d.layout = layoutStream
+	d.stream = json.NewDecoder(d.reader)
+}
+
+// Read the next line as one record
+func (d *Decoder) nextLine() (*Record, error) {
+	for {
+		line, tooLong, err := d.readLine()
+		if err != nil && (err != io.EOF || (len(line) == 0 && !tooLong)) {
+			return nil, err
+		}
+		d.line++
+		if tooLong {
+			return &Record{Line: d.line, Err: corruptInput(fmt.Errorf("line exceeds %d bytes", d.maxLineBytes))}, nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+		return &Record{Line: d.line, Raw: line}, nil
+	}
+}
+
+// Read one line of any length, dropping lines over the limit instead of buffering them
+func (d *Decoder) readLine() ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := d.reader.ReadSlice('\n')
+		if !tooLong {
+			if d.maxLineBytes > 0 && len(line)+len(chunk) > d.maxLineBytes+1 {
+				tooLong, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return line, tooLong, err
+	}
+}
+
+// Read the next finding of a stream of JSON values
+func (d *Decoder) nextStreamed() (*Record, error) {
+	if d.failed {
+		return nil, io.EOF
+	}
+	for {
+		state := -1
+		if len(d.states) > 0 {
+			state = d.states[len(d.states)-1]
+		}
+
+		// Elements of an array, or of the findings list of an envelope
+		if state == inArray || state == inEnvelope {
+			if d.stream.More() {
+				var raw json.RawMessage
+				if err := d.stream.Decode(&raw); err != nil {
+					return d.streamError(err)
+				}
+				d.position++
+				return &Record{Line: d.position, Raw: raw}, nil
+			}
+			if _, err := d.stream.Token(); err != nil {
+				return d.streamError(err)
+			}
+			d.states = d.states[:len(d.states)-1]
+			continue
+		}
+
+		// Fields of a top-level object, read one by one so an envelope's list is streamed
+		if state == inObject {
+			if !d.stream.More() {
+				if _, err := d.stream.Token(); err != nil {
+					return d.streamError(err)
+				}
+				d.states = d.states[:len(d.states)-1]
+				object, streamed := d.object, d.streamed
+				d.object, d.streamed = nil, false
+				if streamed {
+					continue
+				}
+				d.position++
+				return &Record{Line: d.position, Data: object}, nil
+			}
+			token, err := d.stream.Token()
+			if err != nil {
+				return d.streamError(err)
+			}
+			key, _ := token.(string)
+			token, err = d.stream.Token()
+			if err != nil {
+				return d.streamError(err)
+			}
+			if delim, ok := token.(json.Delim); ok && delim == '[' && envelopeKeys[key] {
+				d.streamed = true
+				d.states = append(d.states, inEnvelope)
+				continue
+			}
+			value, err := d.valueFrom(token)
+			if err != nil {
+				return d.streamError(err)
+			}
+			d.object[key] = value
+			continue
+		}
+
+		// Top level: the next value starts an array or an object
+		token, err := d.stream.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return d.streamError(err)
+		}
+		switch token {
+		case json.Delim('['):
+			d.states = append(d.states, inArray)
+		case json.Delim('{'):
+			d.states = append(d.states, inObject)
+			d.object = map[string]interface{}{}
+		default:
+			d.position++
+			return &Record{Line: d.position, Err: corruptInput(fmt.Errorf("unexpected %v at top level", token))}, nil
+		}
+	}
+}
+
+// Decode the rest of a value whose first token was already read
+func (d *Decoder) valueFrom(token json.Token) (interface{}, error) {
+	switch token {
+	case json.Delim('{'):
+		object := map[string]interface{}{}
+		for d.stream.More() {
+			keyToken, err := d.stream.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := d.stream.Decode(&value); err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			object[key] = value
+		}
+		_, err := d.stream.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for d.stream.More() {
+			var value interface{}
+			if err := d.stream.Decode(&value); err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := d.stream.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// A syntax error leaves a stream unreadable; report it once and stop
+func (d *Decoder) streamError(err error) (*Record, error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	d.failed = true
+	d.position++
+	return &Record{Line: d.position, Err: corruptInput(fmt.Errorf("%w (rest of the input skipped)", err))}, nil
+}
+
+// Whether raw is valid JSON
+func validJSON(raw []byte) bool {
+	return json.Valid(raw)
+}
+
+// ScanOptions control how Scan reads a stream
+type ScanOptions struct {
+	Workers      int  // goroutines decoding and processing records, 1 when zero
+	Unordered    bool // emit results as soon as they are ready instead of in input order
+	MaxLineBytes int  // longest JSON line accepted, 0 for no limit
+}
+
+// Records handed to a worker at once, to keep channel overhead low
+const scanBatchSize = 256
+
+// Scan reads every record of r, calling process for each of them (on
+// opts.Workers goroutines) and emit with the result (on the caller's
+// goroutine). Scanning stops at the first error returned by emit, when ctx
+// is done, or when r fails.
+func Scan[T any](ctx context.Context, r io.Reader, opts ScanOptions, process func(*Record) T, emit func(*Record, T) error) error {
+	decoder := NewDecoder(r, opts.MaxLineBytes)
+	if opts.Workers <= 1 {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			record, err := decoder.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := emit(record, process(record)); err != nil {
+				return err
+			}
+		}
+	}
+
+	type batch struct {
+		seq     int
+		records []*Record
+		results []T
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := make(chan *batch, opts.Workers)
+	done := make(chan *batch, opts.Workers)
+	var readErr error
+
+	// Reader: cut the input into batches
+	go func() {
+		defer close(pending)
+		seq := 0
+		current := &batch{}
+		for {
+			record, err := decoder.Next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				break
+			}
+			current.records = append(current.records, record)
+			if len(current.records) == scanBatchSize {
+				select {
+				case pending <- current:
+				case <-ctx.Done():
+					return
+				}
+				seq++
+				current = &batch{seq: seq}
+			}
+		}
+		if len(current.records) > 0 {
+			select {
+			case pending <- current:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	// Workers: process whole batches
+	var workers sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range pending {
+				b.results = make([]T, len(b.records))
+				for j, record := range b.records {
+					b.results[j] = process(record)
+				}
+				select {
+				case done <- b:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	// Emitter: in input order unless unordered output was asked for
+	waiting := map[int]*batch{}
+	next := 0
+	var emitErr error
+	emitBatch := func(b *batch) {
+		for j, record := range b.records {
+			if emitErr != nil {
+				return
+			}
+			if emitErr = emit(record, b.results[j]); emitErr != nil {
+				cancel()
+			}
+		}
+	}
+	for b := range done {
+		if emitErr != nil {
+			continue
+		}
+		if opts.Unordered {
+			emitBatch(b)
+			continue
+		}
+		waiting[b.seq] = b
+		for {
+			ready, ok := waiting[next]
+			if !ok {
+				break
+			}
+			delete(waiting, next)
+			next++
+			emitBatch(ready)
+		}
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return readErr
+}
@@ -0,0 +1,40 @@
+package searcher
+
+import (
+	"strconv"
+	"strings"
+)
+
+//go:generate go run gen_detectortypes.go
+
+// DetectorType returns the DetectorType code of a finding and its name; ok
+// is false when the finding has no code
+func DetectorType(data map[string]interface{}) (code int64, name string, ok bool) {
+	value, ok := data["DetectorType"].(float64)
+	if !ok {
+		return 0, "", false
+	}
+	code = int64(value)
+	if name, known := DetectorTypes[code]; known {
+		return code, name, true
+	}
+	name, _ = data["DetectorName"].(string)
+	return code, name, true
+}
+
+// DetectorType returns the DetectorType code of a finding and its name,
+// looked up in Options.DetectorTypeNames before DetectorTypes
+func (s *Searcher) DetectorType(data map[string]interface{}) (code int64, name string, ok bool) {
+	code, name, ok = DetectorType(data)
+	if custom, known := s.opts.DetectorTypeNames[code]; ok && known {
+		name = custom
+	}
+	return code, name, ok
+}
+
+// Whether the detector type of a finding is one of the codes or names of
+// Options.DetectorTypes
+func (s *Searcher) hasDetectorType(data map[string]interface{}) bool {
+	code, name, ok := s.DetectorType(data)
+	return ok && (s.detectorTypes[strconv.FormatInt(code, 10)] || s.detectorTypes[strings.ToLower(name)])
+}
@@ -0,0 +1,1076 @@
+// Code generated by gen_detectortypes.go from github.com/trufflesecurity/trufflehog/v3@v3.97.4 proto/detector_type.proto; DO NOT EDIT.
+
+package searcher
+
+// DetectorTypes names the codes of trufflehog's DetectorType enum
+// (detector_typepb.DetectorType), deprecated ones included, as of v3.97.4.
+// Codes missing here are named by the DetectorName of their findings.
+// Callers may add codes, e.g. those of custom detectors, before searching.
+var DetectorTypes = map[int64]string{
+	0:    "Alibaba",
+	1:    "AMQP",
+	2:    "AWS",
+	3:    "Azure",
+	4:    "Circle",
+	5:    "Coinbase",
+	6:    "GCP",
+	7:    "Generic",
+	8:    "Github",
+	9:    "Gitlab",
+	10:   "JDBC",
+	11:   "RazorPay",
+	12:   "SendGrid",
+	13:   "Slack",
+	14:   "Square",
+	15:   "PrivateKey",
+	16:   "Stripe",
+	17:   "URI",
+	18:   "Dropbox",
+	19:   "Heroku",
+	20:   "Mailchimp",
+	21:   "Okta",
+	22:   "OneLogin",
+	23:   "PivotalTracker",
+	25:   "SquareApp",
+	26:   "Twilio",
+	27:   "Test",
+	29:   "TravisCI",
+	30:   "SlackWebhook",
+	31:   "PaypalOauth",
+	32:   "PagerDutyApiKey",
+	33:   "Firebase",
+	34:   "Mailgun",
+	35:   "HubSpot",
+	36:   "GitHubApp",
+	37:   "CircleCI",
+	38:   "WpEngine",
+	39:   "DatadogToken",
+	40:   "FacebookOAuth",
+	41:   "AsanaPersonalAccessToken",
+	42:   "AmplitudeApiKey",
+	43:   "BitLyAccessToken",
+	44:   "CalendlyApiKey",
+	45:   "ZapierWebhook",
+	46:   "YoutubeApiKey",
+	47:   "SalesforceOauth2",
+	48:   "TwitterApiSecret",
+	49:   "NpmToken",
+	50:   "NewRelicPersonalApiKey",
+	51:   "AirtableApiKey",
+	52:   "AkamaiToken",
+	53:   "AmazonMWS",
+	54:   "KubeConfig",
+	55:   "Auth0oauth",
+	56:   "Bitfinex",
+	57:   "Clarifai",
+	58:   "CloudflareGlobalApiKey",
+	59:   "CloudflareCaKey",
+	60:   "Confluent",
+	61:   "ContentfulDelivery",
+	62:   "DatabricksToken",
+	63:   "DigitalOceanSpaces",
+	64:   "DigitalOceanToken",
+	65:   "DiscordBotToken",
+	66:   "DiscordWebhook",
+	67:   "EtsyApiKey",
+	68:   "FastlyPersonalToken",
+	69:   "GoogleOauth2",
+	70:   "ReCAPTCHA",
+	71:   "GoogleApiKey",
+	72:   "Hunter",
+	73:   "IbmCloudUserKey",
+	74:   "Netlify",
+	75:   "Vonage",
+	76:   "EquinixOauth",
+	77:   "Paystack",
+	78:   "PlaidToken",
+	79:   "PlaidKey",
+	80:   "Plivo",
+	81:   "Postmark",
+	82:   "PubNubPublishKey",
+	83:   "PubNubSubscriptionKey",
+	84:   "PusherChannelKey",
+	85:   "ScalewayKey",
+	86:   "SendinBlueV2",
+	87:   "SentryToken",
+	88:   "ShodanKey",
+	89:   "SnykKey",
+	90:   "SpotifyKey",
+	91:   "TelegramBotToken",
+	92:   "TencentCloudKey",
+	93:   "TerraformCloudPersonalToken",
+	94:   "TrelloApiKey",
+	95:   "ZendeskApi",
+	96:   "MaxMindLicense",
+	97:   "AirtableMetadataApiKey",
+	98:   "AsanaOauth",
+	99:   "RapidApi",
+	100:  "CloudflareApiToken",
+	101:  "Webex",
+	102:  "FirebaseCloudMessaging",
+	103:  "ContentfulPersonalAccessToken",
+	104:  "MapBox",
+	105:  "MailJetBasicAuth",
+	106:  "MailJetSMS",
+	107:  "HubSpotApiKey",
+	108:  "HubSpotOauth",
+	109:  "SslMate",
+	110:  "Auth0ManagementApiToken",
+	111:  "MessageBird",
+	112:  "ElasticEmail",
+	113:  "FigmaPersonalAccessToken",
+	114:  "MicrosoftTeamsWebhook",
+	115:  "GitHubOld",
+	116:  "VultrApiKey",
+	117:  "Pepipost",
+	118:  "Postman",
+	119:  "CloudsightKey",
+	120:  "JiraToken",
+	121:  "NexmoApiKey",
+	122:  "SegmentApiKey",
+	123:  "SumoLogicKey",
+	124:  "PushBulletApiKey",
+	125:  "AirbrakeProjectKey",
+	126:  "AirbrakeUserKey",
+	127:  "PendoIntegrationKey",
+	128:  "SplunkOberservabilityToken",
+	129:  "LokaliseToken",
+	130:  "Calendarific",
+	131:  "Jumpcloud",
+	133:  "IpStack",
+	134:  "Notion",
+	135:  "DroneCI",
+	136:  "AdobeIO",
+	137:  "TwelveData",
+	138:  "D7Network",
+	139:  "ScrapingBee",
+	140:  "KeenIO",
+	141:  "Wakatime",
+	142:  "Buildkite",
+	143:  "Verimail",
+	144:  "Zerobounce",
+	145:  "Mailboxlayer",
+	146:  "Fastspring",
+	147:  "Paddle",
+	148:  "Sellfy",
+	149:  "FixerIO",
+	150:  "ButterCMS",
+	151:  "Taxjar",
+	152:  "Avalara",
+	153:  "Helpscout",
+	154:  "ElasticPath",
+	155:  "Zeplin",
+	156:  "Intercom",
+	157:  "Mailmodo",
+	158:  "CannyIo",
+	159:  "Pipedrive",
+	160:  "Vercel",
+	161:  "PosthogApp",
+	162:  "SinchMessage",
+	163:  "Ayrshare",
+	164:  "HelpCrunch",
+	165:  "LiveAgent",
+	166:  "Beamer",
+	167:  "WeChatAppKey",
+	168:  "LineMessaging",
+	169:  "UberServerToken",
+	170:  "AlgoliaAdminKey",
+	171:  "FullContact",
+	172:  "Mandrill",
+	173:  "Flutterwave",
+	174:  "MattermostPersonalToken",
+	175:  "Cloudant",
+	176:  "LineNotify",
+	177:  "LinearAPI",
+	178:  "Ubidots",
+	179:  "Anypoint",
+	180:  "Dwolla",
+	181:  "ArtifactoryAccessToken",
+	182:  "Surge",
+	183:  "Sparkpost",
+	184:  "GoCardless",
+	185:  "Codacy",
+	186:  "Kraken",
+	187:  "Checkout",
+	188:  "Kairos",
+	189:  "ClockworkSMS",
+	190:  "Atlassian",
+	191:  "LaunchDarkly",
+	192:  "Coveralls",
+	193:  "Linode",
+	194:  "WePay",
+	195:  "PlanetScale",
+	196:  "Doppler",
+	197:  "Agora",
+	198:  "Samsara",
+	199:  "FrameIO",
+	200:  "RubyGems",
+	201:  "OpenAI",
+	202:  "SurveySparrow",
+	203:  "Simvoly",
+	204:  "Survicate",
+	205:  "Omnisend",
+	206:  "Groovehq",
+	207:  "Newsapi",
+	208:  "Chatbot",
+	209:  "ClickSendsms",
+	210:  "Getgist",
+	211:  "CustomerIO",
+	212:  "ApiDeck",
+	213:  "Nftport",
+	214:  "Copper",
+	215:  "Close",
+	216:  "Myfreshworks",
+	217:  "Salesflare",
+	218:  "Webflow",
+	219:  "Duda",
+	220:  "Yext",
+	221:  "ContentStack",
+	222:  "StoryblokAccessToken",
+	223:  "GraphCMS",
+	224:  "Checkmarket",
+	225:  "Convertkit",
+	226:  "CustomerGuru",
+	227:  "Kaleyra",
+	228:  "Mailerlite",
+	229:  "Qualaroo",
+	230:  "SatismeterProjectkey",
+	231:  "SatismeterWritekey",
+	232:  "Simplesat",
+	233:  "SurveyAnyplace",
+	234:  "SurveyBot",
+	235:  "Webengage",
+	236:  "ZonkaFeedback",
+	237:  "Delighted",
+	238:  "Feedier",
+	239:  "Abyssale",
+	240:  "Magnetic",
+	241:  "Nytimes",
+	242:  "Polygon",
+	243:  "Powrbot",
+	244:  "ProspectIO",
+	245:  "Skrappio",
+	246:  "Monday",
+	247:  "Smartsheets",
+	248:  "Wrike",
+	249:  "Float",
+	250:  "Imagekit",
+	251:  "Integromat",
+	252:  "Salesblink",
+	253:  "Bored",
+	254:  "Campayn",
+	255:  "Clinchpad",
+	256:  "CompanyHub",
+	257:  "Debounce",
+	258:  "Dyspatch",
+	259:  "Guardianapi",
+	260:  "Harvest",
+	261:  "Moosend",
+	262:  "OpenWeather",
+	263:  "Siteleaf",
+	264:  "Squarespace",
+	265:  "FlowFlu",
+	266:  "Nimble",
+	267:  "LessAnnoyingCRM",
+	268:  "Nethunt",
+	269:  "Apptivo",
+	270:  "CapsuleCRM",
+	271:  "Insightly",
+	272:  "Kylas",
+	273:  "OnepageCRM",
+	274:  "User",
+	275:  "ProspectCRM",
+	276:  "ReallySimpleSystems",
+	277:  "Airship",
+	278:  "Artsy",
+	279:  "Yandex",
+	280:  "Clockify",
+	281:  "Dnscheck",
+	282:  "EasyInsight",
+	283:  "Ethplorer",
+	284:  "Everhour",
+	285:  "Fulcrum",
+	286:  "GeoIpifi",
+	287:  "Jotform",
+	288:  "Refiner",
+	289:  "Timezoneapi",
+	290:  "TogglTrack",
+	291:  "Vpnapi",
+	292:  "Workstack",
+	293:  "Apollo",
+	294:  "Eversign",
+	295:  "Juro",
+	296:  "KarmaCRM",
+	297:  "Metrilo",
+	298:  "Pandadoc",
+	299:  "RevampCRM",
+	300:  "Salescookie",
+	301:  "Alconost",
+	302:  "Blogger",
+	303:  "Accuweather",
+	304:  "Opengraphr",
+	305:  "Rawg",
+	306:  "Riotgames",
+	307:  "Clientary",
+	308:  "Stormglass",
+	309:  "Tomtom",
+	310:  "Twitch",
+	311:  "Documo",
+	312:  "Cloudways",
+	313:  "Veevavault",
+	314:  "KiteConnect",
+	315:  "ShopeeOpenPlatform",
+	316:  "TeamViewer",
+	317:  "Bulbul",
+	318:  "CentralStationCRM",
+	319:  "Teamgate",
+	320:  "Axonaut",
+	321:  "Tyntec",
+	322:  "Appcues",
+	323:  "Autoklose",
+	324:  "Cloudplan",
+	325:  "Dotdigital",
+	326:  "GetEmail",
+	327:  "GetEmails",
+	328:  "Kontent",
+	329:  "Leadfeeder",
+	330:  "Raven",
+	331:  "RocketReach",
+	332:  "Uplead",
+	333:  "Brandfetch",
+	334:  "Clearbit",
+	335:  "Crowdin",
+	336:  "Mapquest",
+	337:  "Noticeable",
+	338:  "Onbuka",
+	339:  "Todoist",
+	340:  "Storychief",
+	341:  "LinkedIn",
+	342:  "YouSign",
+	343:  "Docker",
+	344:  "Telesign",
+	345:  "Spoonacular",
+	346:  "Aerisweather",
+	347:  "Alphavantage",
+	348:  "Imgur",
+	349:  "Imagga",
+	350:  "SMSApi",
+	351:  "Distribusion",
+	352:  "Blablabus",
+	353:  "WordsApi",
+	354:  "Currencylayer",
+	355:  "Html2Pdf",
+	356:  "IPGeolocation",
+	357:  "Owlbot",
+	358:  "Cloudmersive",
+	359:  "Dynalist",
+	360:  "ExchangeRateAPI",
+	361:  "HolidayAPI",
+	362:  "Ipapi",
+	363:  "Marketstack",
+	364:  "Nutritionix",
+	365:  "Swell",
+	366:  "ClickupPersonalToken",
+	367:  "Nitro",
+	368:  "Rev",
+	369:  "RunRunIt",
+	370:  "Typeform",
+	371:  "Mixpanel",
+	372:  "Tradier",
+	373:  "Verifier",
+	374:  "Vouchery",
+	375:  "Alegra",
+	376:  "Audd",
+	377:  "Baremetrics",
+	378:  "Coinlib",
+	379:  "ExchangeRatesAPI",
+	380:  "CurrencyScoop",
+	381:  "FXMarket",
+	382:  "CurrencyCloud",
+	383:  "GetGeoAPI",
+	384:  "Abstract",
+	385:  "Billomat",
+	386:  "Dovico",
+	387:  "Bitbar",
+	388:  "Bugsnag",
+	389:  "AssemblyAI",
+	390:  "AdafruitIO",
+	391:  "Apify",
+	392:  "CoinGecko",
+	393:  "CryptoCompare",
+	394:  "Fullstory",
+	395:  "HelloSign",
+	396:  "Loyverse",
+	397:  "NetCore",
+	398:  "SauceLabs",
+	399:  "AlienVault",
+	401:  "Apiflash",
+	402:  "Coinlayer",
+	403:  "CurrentsAPI",
+	404:  "DataGov",
+	405:  "Enigma",
+	406:  "FinancialModelingPrep",
+	407:  "Geocodio",
+	408:  "HereAPI",
+	409:  "Macaddress",
+	410:  "OOPSpam",
+	411:  "ProtocolsIO",
+	412:  "ScraperAPI",
+	413:  "SecurityTrails",
+	414:  "TomorrowIO",
+	415:  "WorldCoinIndex",
+	416:  "FacePlusPlus",
+	417:  "Voicegain",
+	418:  "Deepgram",
+	419:  "VisualCrossing",
+	420:  "Finnhub",
+	421:  "Tiingo",
+	422:  "RingCentral",
+	423:  "Finage",
+	424:  "Edamam",
+	425:  "HypeAuditor",
+	426:  "Gengo",
+	427:  "Front",
+	428:  "Fleetbase",
+	429:  "Bubble",
+	430:  "Bannerbear",
+	431:  "Adzuna",
+	432:  "BitcoinAverage",
+	433:  "CommerceJS",
+	434:  "DetectLanguage",
+	435:  "FakeJSON",
+	436:  "Graphhopper",
+	437:  "Lexigram",
+	438:  "LinkPreview",
+	439:  "Numverify",
+	440:  "ProxyCrawl",
+	441:  "ZipCodeAPI",
+	442:  "Cometchat",
+	443:  "Keygen",
+	444:  "Mixcloud",
+	445:  "TatumIO",
+	446:  "Tmetric",
+	447:  "Lastfm",
+	448:  "Browshot",
+	449:  "JSONbin",
+	450:  "LocationIQ",
+	451:  "ScreenshotAPI",
+	452:  "WeatherStack",
+	453:  "Amadeus",
+	454:  "FourSquare",
+	455:  "Flickr",
+	456:  "ClickHelp",
+	457:  "Ambee",
+	458:  "Api2Cart",
+	459:  "Hypertrack",
+	460:  "KakaoTalk",
+	461:  "RiteKit",
+	462:  "Shutterstock",
+	463:  "Text2Data",
+	464:  "YouNeedABudget",
+	465:  "Cricket",
+	466:  "Filestack",
+	467:  "Gyazo",
+	468:  "Mavenlink",
+	469:  "Sheety",
+	470:  "Sportsmonk",
+	471:  "Stockdata",
+	472:  "Unsplash",
+	473:  "Allsports",
+	474:  "CalorieNinja",
+	475:  "WalkScore",
+	476:  "Strava",
+	477:  "Cicero",
+	478:  "IPQuality",
+	479:  "ParallelDots",
+	480:  "Roaring",
+	481:  "Mailsac",
+	482:  "Whoxy",
+	483:  "WorldWeather",
+	484:  "ApiFonica",
+	485:  "Aylien",
+	486:  "Geocode",
+	487:  "IconFinder",
+	488:  "Ipify",
+	489:  "LanguageLayer",
+	490:  "Lob",
+	491:  "OnWaterIO",
+	492:  "Pastebin",
+	493:  "PdfLayer",
+	494:  "Pixabay",
+	495:  "ReadMe",
+	496:  "VatLayer",
+	497:  "VirusTotal",
+	498:  "AirVisual",
+	499:  "Currencyfreaks",
+	500:  "Duffel",
+	501:  "FlatIO",
+	502:  "M3o",
+	503:  "Mesibo",
+	504:  "Openuv",
+	505:  "Snipcart",
+	506:  "Besttime",
+	507:  "Happyscribe",
+	508:  "Humanity",
+	509:  "Impala",
+	510:  "Loginradius",
+	511:  "AutoPilot",
+	512:  "Bitmex",
+	513:  "ClustDoc",
+	514:  "Messari",
+	515:  "PdfShift",
+	516:  "Poloniex",
+	517:  "RestpackHtmlToPdfAPI",
+	518:  "RestpackScreenshotAPI",
+	519:  "ShutterstockOAuth",
+	520:  "SkyBiometry",
+	521:  "AbuseIPDB",
+	522:  "AletheiaApi",
+	523:  "BlitApp",
+	524:  "Censys",
+	525:  "Cloverly",
+	526:  "CountryLayer",
+	527:  "FileIO",
+	528:  "FlightApi",
+	529:  "Geoapify",
+	530:  "IPinfoDB",
+	531:  "MediaStack",
+	532:  "NasdaqDataLink",
+	533:  "OpenCageData",
+	534:  "Paymongo",
+	535:  "PositionStack",
+	536:  "Rebrandly",
+	537:  "ScreenshotLayer",
+	538:  "Stytch",
+	539:  "Unplugg",
+	540:  "UPCDatabase",
+	541:  "UserStack",
+	542:  "Geocodify",
+	543:  "Newscatcher",
+	544:  "Nicereply",
+	545:  "Partnerstack",
+	546:  "Route4me",
+	547:  "Scrapeowl",
+	548:  "ScrapingDog",
+	549:  "Streak",
+	550:  "Veriphone",
+	551:  "Webscraping",
+	552:  "Zenscrape",
+	553:  "Zenserp",
+	554:  "CoinApi",
+	555:  "Gitter",
+	556:  "Host",
+	557:  "Iexcloud",
+	558:  "Restpack",
+	559:  "ScraperBox",
+	560:  "ScrapingAnt",
+	561:  "SerpStack",
+	562:  "SmartyStreets",
+	563:  "TicketMaster",
+	564:  "AviationStack",
+	565:  "BombBomb",
+	566:  "Commodities",
+	567:  "Dfuse",
+	568:  "EdenAI",
+	569:  "Glassnode",
+	570:  "Guru",
+	571:  "Hive",
+	572:  "Hiveage",
+	573:  "Kickbox",
+	574:  "Passbase",
+	575:  "PostageApp",
+	576:  "PureStake",
+	577:  "Qubole",
+	578:  "CarbonInterface",
+	579:  "Intrinio",
+	580:  "QuickMetrics",
+	581:  "ScrapeStack",
+	582:  "TechnicalAnalysisApi",
+	583:  "Urlscan",
+	584:  "BaseApiIO",
+	585:  "DailyCO",
+	586:  "TLy",
+	587:  "Shortcut",
+	588:  "Appfollow",
+	589:  "Thinkific",
+	590:  "Feedly",
+	591:  "Stitchdata",
+	592:  "Fetchrss",
+	593:  "Signupgenius",
+	594:  "Signaturit",
+	595:  "Optimizely",
+	596:  "OcrSpace",
+	597:  "WeatherBit",
+	598:  "BuddyNS",
+	599:  "ZipAPI",
+	600:  "ZipBooks",
+	601:  "Onedesk",
+	602:  "Bugherd",
+	603:  "Blazemeter",
+	604:  "Autodesk",
+	605:  "Tru",
+	606:  "UnifyID",
+	607:  "Trimble",
+	608:  "Smooch",
+	609:  "Semaphore",
+	610:  "Telnyx",
+	611:  "Signalwire",
+	612:  "Textmagic",
+	613:  "Serphouse",
+	614:  "Planyo",
+	615:  "Simplybook",
+	616:  "Vyte",
+	617:  "Nylas",
+	618:  "Squareup",
+	619:  "Dandelion",
+	620:  "DataFire",
+	621:  "DeepAI",
+	622:  "MeaningCloud",
+	623:  "NeutrinoApi",
+	624:  "Storecove",
+	625:  "Shipday",
+	626:  "Sentiment",
+	627:  "StreamChatMessaging",
+	628:  "TeamworkCRM",
+	629:  "TeamworkDesk",
+	630:  "TeamworkSpaces",
+	631:  "TheOddsApi",
+	632:  "Apacta",
+	633:  "GetSandbox",
+	634:  "Happi",
+	635:  "Oanda",
+	636:  "FastForex",
+	637:  "APIMatic",
+	638:  "VersionEye",
+	639:  "EagleEyeNetworks",
+	640:  "ThousandEyes",
+	641:  "SelectPDF",
+	642:  "Flightstats",
+	643:  "ChecIO",
+	644:  "Manifest",
+	645:  "ApiScience",
+	646:  "AppSynergy",
+	647:  "Caflou",
+	648:  "Caspio",
+	649:  "ChecklyHQ",
+	650:  "CloudElements",
+	651:  "DronaHQ",
+	652:  "Enablex",
+	653:  "Fmfw",
+	654:  "GoodDay",
+	655:  "Luno",
+	656:  "Meistertask",
+	657:  "Mindmeister",
+	658:  "PeopleDataLabs",
+	659:  "ScraperSite",
+	660:  "Scrapfly",
+	661:  "SimplyNoted",
+	662:  "TravelPayouts",
+	663:  "WebScraper",
+	664:  "Convier",
+	665:  "Courier",
+	666:  "Ditto",
+	667:  "Findl",
+	668:  "Lendflow",
+	669:  "Moderation",
+	670:  "Opendatasoft",
+	671:  "Podio",
+	672:  "Rockset",
+	673:  "Rownd",
+	674:  "Shotstack",
+	675:  "Swiftype",
+	676:  "Twitter",
+	677:  "Honey",
+	678:  "Freshdesk",
+	679:  "Upwave",
+	680:  "Fountain",
+	681:  "Freshbooks",
+	682:  "Mite",
+	683:  "Deputy",
+	684:  "Beebole",
+	685:  "Cashboard",
+	686:  "Kanban",
+	687:  "Worksnaps",
+	688:  "MyIntervals",
+	689:  "InvoiceOcean",
+	690:  "Sherpadesk",
+	691:  "Mrticktock",
+	692:  "Chatfule",
+	693:  "Aeroworkflow",
+	694:  "Emailoctopus",
+	695:  "Fusebill",
+	696:  "Geckoboard",
+	697:  "Gosquared",
+	698:  "Moonclerk",
+	699:  "Paymoapp",
+	700:  "Mixmax",
+	701:  "Processst",
+	702:  "Repairshopr",
+	703:  "Goshippo",
+	704:  "Sigopt",
+	705:  "Sugester",
+	706:  "Viewneo",
+	707:  "BoostNote",
+	708:  "CaptainData",
+	709:  "Checkvist",
+	710:  "Cliengo",
+	711:  "Cloze",
+	712:  "FormIO",
+	713:  "FormBucket",
+	714:  "GoCanvas",
+	715:  "MadKudu",
+	716:  "NozbeTeams",
+	717:  "Papyrs",
+	718:  "SuperNotesAPI",
+	719:  "Tallyfy",
+	720:  "ZenkitAPI",
+	721:  "CloudImage",
+	722:  "UploadCare",
+	723:  "Borgbase",
+	724:  "Pipedream",
+	725:  "Sirv",
+	726:  "Diffbot",
+	727:  "EightxEight",
+	728:  "Sendoso",
+	729:  "Printfection",
+	730:  "Authorize",
+	731:  "PandaScore",
+	732:  "Paymo",
+	733:  "AvazaPersonalAccessToken",
+	734:  "PlanviewLeanKit",
+	735:  "Livestorm",
+	736:  "KuCoin",
+	737:  "MetaAPI",
+	738:  "NiceHash",
+	739:  "CexIO",
+	740:  "Klipfolio",
+	741:  "Dynatrace",
+	742:  "MollieAPIKey",
+	743:  "MollieAccessToken",
+	744:  "BasisTheory",
+	745:  "Nordigen",
+	746:  "FlagsmithEnvironmentKey",
+	747:  "FlagsmithToken",
+	748:  "Mux",
+	749:  "Column",
+	750:  "Sendbird",
+	751:  "SendbirdOrganizationAPI",
+	752:  "Midise",
+	753:  "Mockaroo",
+	754:  "Image4",
+	755:  "Pinata",
+	756:  "BrowserStack",
+	757:  "CrossBrowserTesting",
+	758:  "Loadmill",
+	759:  "TestingBot",
+	760:  "KnapsackPro",
+	761:  "Qase",
+	762:  "Dareboost",
+	763:  "GTMetrix",
+	764:  "Holistic",
+	765:  "Parsers",
+	766:  "ScrutinizerCi",
+	767:  "SonarCloud",
+	768:  "APITemplate",
+	769:  "ConversionTools",
+	770:  "CraftMyPDF",
+	771:  "ExportSDK",
+	772:  "GlitterlyAPI",
+	773:  "Hybiscus",
+	774:  "Miro",
+	775:  "Statuspage",
+	776:  "Statuspal",
+	777:  "Teletype",
+	778:  "TimeCamp",
+	779:  "Userflow",
+	780:  "Wistia",
+	781:  "SportRadar",
+	782:  "UptimeRobot",
+	783:  "Codequiry",
+	784:  "ExtractorAPI",
+	785:  "Signable",
+	786:  "MagicBell",
+	787:  "Stormboard",
+	788:  "Apilayer",
+	789:  "Disqus",
+	790:  "Woopra",
+	791:  "Paperform",
+	792:  "Gumroad",
+	793:  "Paydirtapp",
+	794:  "Detectify",
+	795:  "Statuscake",
+	796:  "Jumpseller",
+	797:  "LunchMoney",
+	798:  "Rosette",
+	799:  "Yelp",
+	800:  "Atera",
+	801:  "EcoStruxureIT",
+	802:  "Aha",
+	803:  "Parsehub",
+	804:  "PackageCloud",
+	805:  "Cloudsmith",
+	806:  "Flowdash",
+	807:  "Flowdock",
+	808:  "Fibery",
+	809:  "Typetalk",
+	810:  "VoodooSMS",
+	811:  "ZulipChat",
+	812:  "Formcraft",
+	813:  "Iexapis",
+	814:  "Reachmail",
+	815:  "Chartmogul",
+	816:  "Appointedd",
+	817:  "Wit",
+	818:  "RechargePayments",
+	819:  "Diggernaut",
+	820:  "MonkeyLearn",
+	821:  "Duply",
+	822:  "Postbacks",
+	823:  "Collect2",
+	824:  "ZenRows",
+	825:  "Zipcodebase",
+	826:  "Tefter",
+	827:  "Twist",
+	828:  "BraintreePayments",
+	829:  "CloudConvert",
+	830:  "Grafana",
+	831:  "ConvertApi",
+	832:  "Transferwise",
+	833:  "Bulksms",
+	834:  "Databox",
+	835:  "Onesignal",
+	836:  "Rentman",
+	837:  "Parseur",
+	838:  "Docparser",
+	839:  "Formsite",
+	840:  "Tickettailor",
+	841:  "Lemlist",
+	842:  "Prodpad",
+	843:  "Formstack",
+	844:  "Codeclimate",
+	845:  "Codemagic",
+	846:  "Vbout",
+	847:  "Nightfall",
+	848:  "FlightLabs",
+	849:  "SpeechTextAI",
+	850:  "PollsAPI",
+	851:  "SimFin",
+	852:  "Scalr",
+	853:  "Kanbantool",
+	854:  "Brightlocal",
+	855:  "Hotwire",
+	856:  "Instabot",
+	857:  "Timekit",
+	858:  "Interseller",
+	859:  "Mojohelpdesk",
+	860:  "Createsend",
+	861:  "Getresponse",
+	862:  "Dynadot",
+	863:  "Demio",
+	864:  "Tokeet",
+	865:  "Myexperiment",
+	866:  "Copyscape",
+	867:  "Besnappy",
+	868:  "Salesmate",
+	869:  "Heatmapapi",
+	870:  "Websitepulse",
+	871:  "Uclassify",
+	872:  "Convert",
+	873:  "PDFmyURL",
+	874:  "Api2Convert",
+	875:  "Opsgenie",
+	876:  "Gemini",
+	877:  "Honeycomb",
+	878:  "KalturaAppToken",
+	879:  "KalturaSession",
+	880:  "BitGo",
+	881:  "Optidash",
+	882:  "Imgix",
+	883:  "ImageToText",
+	884:  "Page2Images",
+	885:  "Quickbase",
+	886:  "Redbooth",
+	887:  "Nubela",
+	888:  "Infobip",
+	889:  "Uproc",
+	890:  "Supportbee",
+	891:  "Aftership",
+	892:  "Edusign",
+	893:  "Teamup",
+	894:  "Workday",
+	895:  "MongoDB",
+	896:  "NGC",
+	897:  "DigitalOceanV2",
+	898:  "SQLServer",
+	899:  "FTP",
+	900:  "Redis",
+	901:  "LDAP",
+	902:  "Shopify",
+	903:  "RabbitMQ",
+	904:  "CustomRegex",
+	905:  "Etherscan",
+	906:  "Infura",
+	907:  "Alchemy",
+	908:  "BlockNative",
+	909:  "Moralis",
+	910:  "BscScan",
+	911:  "CoinMarketCap",
+	912:  "Percy",
+	913:  "TinesWebhook",
+	914:  "Pulumi",
+	915:  "SupabaseToken",
+	916:  "NuGetApiKey",
+	917:  "Aiven",
+	918:  "Prefect",
+	919:  "Docusign",
+	920:  "Couchbase",
+	921:  "Dockerhub",
+	922:  "TrufflehogEnterprise",
+	923:  "EnvoyApiKey",
+	924:  "GitHubOauth2",
+	925:  "Salesforce",
+	926:  "HuggingFace",
+	927:  "Snowflake",
+	928:  "Sourcegraph",
+	929:  "Tailscale",
+	930:  "Web3Storage",
+	931:  "AzureStorage",
+	932:  "PlanetScaleDb",
+	933:  "Anthropic",
+	934:  "Ramp",
+	935:  "Klaviyo",
+	936:  "SourcegraphCody",
+	937:  "Voiceflow",
+	938:  "Privacy",
+	939:  "IPInfo",
+	940:  "Ip2location",
+	941:  "Instamojo",
+	942:  "Portainer",
+	943:  "PortainerToken",
+	944:  "Loggly",
+	945:  "OpenVpn",
+	946:  "VagrantCloudPersonalToken",
+	947:  "BetterStack",
+	948:  "ZeroTier",
+	949:  "AppOptics",
+	950:  "Metabase",
+	951:  "CoinbaseWaaS",
+	952:  "LemonSqueezy",
+	953:  "Budibase",
+	954:  "DenoDeploy",
+	955:  "Stripo",
+	956:  "ReplyIO",
+	957:  "AzureBatch",
+	958:  "AzureContainerRegistry",
+	959:  "AWSSessionKey",
+	960:  "Coda",
+	961:  "LogzIO",
+	962:  "Eventbrite",
+	963:  "GrafanaServiceAccount",
+	964:  "RequestFinance",
+	965:  "Overloop",
+	966:  "Ngrok",
+	967:  "Replicate",
+	968:  "Postgres",
+	969:  "AzureActiveDirectoryApplicationSecret",
+	970:  "AzureCacheForRedisAccessKey",
+	971:  "AzureCosmosDBKeyIdentifiable",
+	972:  "AzureDevopsPersonalAccessToken",
+	973:  "AzureFunctionKey",
+	974:  "AzureMLWebServiceClassicIdentifiableKey",
+	975:  "AzureSasToken",
+	976:  "AzureSearchAdminKey",
+	977:  "AzureSearchQueryKey",
+	978:  "AzureManagementCertificate",
+	979:  "AzureSQL",
+	980:  "FlyIO",
+	981:  "BuiltWith",
+	982:  "JupiterOne",
+	983:  "GCPApplicationDefaultCredentials",
+	984:  "Wiz",
+	985:  "Pagarme",
+	986:  "Onfleet",
+	987:  "Intra42",
+	988:  "Groq",
+	989:  "TwitterConsumerkey",
+	990:  "Eraser",
+	991:  "LarkSuite",
+	992:  "LarkSuiteApiKey",
+	993:  "EndorLabs",
+	994:  "ElevenLabs",
+	995:  "Netsuite",
+	996:  "RobinhoodCrypto",
+	997:  "NVAPI",
+	998:  "PyPI",
+	999:  "RailwayApp",
+	1000: "Meraki",
+	1001: "SaladCloudApiKey",
+	1002: "Box",
+	1003: "BoxOauth",
+	1004: "ApiMetrics",
+	1005: "WeightsAndBiases",
+	1006: "ZohoCRM",
+	1007: "AzureOpenAI",
+	1008: "GoDaddy",
+	1009: "Flexport",
+	1010: "TwitchAccessToken",
+	1011: "TwilioApiKey",
+	1012: "Sanity",
+	1013: "AzureRefreshToken",
+	1014: "AirtableOAuth",
+	1015: "AirtablePersonalAccessToken",
+	1016: "StoryblokPersonalAccessToken",
+	1017: "SentryOrgToken",
+	1018: "AzureApiManagementRepositoryKey",
+	1019: "AzureAPIManagementSubscriptionKey",
+	1020: "Harness",
+	1021: "Langfuse",
+	1022: "BingSubscriptionKey",
+	1023: "XAI",
+	1024: "AzureDirectManagementKey",
+	1025: "AzureAppConfigConnectionString",
+	1026: "DeepSeek",
+	1027: "StripePaymentIntent",
+	1028: "LangSmith",
+	1029: "BitbucketAppPassword",
+	1030: "Hasura",
+	1031: "SalesforceRefreshToken",
+	1032: "AnypointOAuth2",
+	1033: "WebexBot",
+	1034: "TableauPersonalAccessToken",
+	1035: "Rootly",
+	1036: "HashiCorpVaultAuth",
+	1037: "PhraseAccessToken",
+	1038: "Photoroom",
+	1039: "JWT",
+	1040: "OpenAIAdmin",
+	1041: "GoogleGeminiAPIKey",
+	1042: "ArtifactoryReferenceToken",
+	1043: "DatadogApikey",
+	1044: "ShopifyOAuth",
+	1045: "BitbucketDataCenter",
+	1046: "JiraDataCenterPAT",
+	1047: "ConfluenceDataCenter",
+	1048: "Cloudinary",
+	1049: "Pinecone",
+	1050: "GitLabOauth2",
+	1051: "SpectralOps",
+	1052: "AWSAppSync",
+	1053: "BrainTrustApiKey",
+	1054: "PgAnalyzeReadKey",
+	1055: "RedHatPyxis",
+	1056: "OctopusDeploy",
+	1057: "OpenRouter",
+	1058: "NewRelicInsightsInsertKey",
+	1059: "DuffelToken",
+	1060: "Shippo",
+	1061: "HashiCorpVaultBatchToken",
+	1062: "HashiCorpVaultToken",
+	1063: "Duo",
+	1064: "NewRelicLicenseKey",
+	1065: "NewRelicBrowserKey",
+	1066: "NewRelicUserKey",
+	1067: "NewRelicInsightsQueryKey",
+	1068: "NewRelicMobileAppToken",
+	1069: "SolarWindsObservability",
+}
@@ -0,0 +1,30 @@
+package searcher
+
+import "errors"
+
+// Kinds of errors of the package, to be told apart with errors.Is
+var (
+	// ErrBadQuery is returned by New for options that cannot be searched
+	// with, such as an invalid query expression or regular expression
+	ErrBadQuery = errors.New("bad query")
+	// ErrCorruptInput is the kind of Record.Err, and of the errors Stream
+	// reports to onError, for an entry that is not a readable finding
+	ErrCorruptInput = errors.New("corrupt input")
+)
+
+// An error of one of the kinds above, with the message of the error it wraps
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+func badQuery(err error) error {
+	return &kindError{kind: ErrBadQuery, err: err}
+}
+
+func corruptInput(err error) error {
+	return &kindError{kind: ErrCorruptInput, err: err}
+}
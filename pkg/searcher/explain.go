@@ -0,0 +1,170 @@
+package searcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check is one predicate of a search evaluated against a finding
+type Check struct {
+	Name   string `json:"check"`            // e.g. "detector", `term "acme"` or "clause DetectorName=AWS"
+	Passed bool   `json:"passed"`           // whether the finding satisfied it
+	Detail string `json:"detail,omitempty"` // the value compared, or the field a term matched in
+}
+
+func (c Check) String() string {
+	result := "pass"
+	if !c.Passed {
+		result = "fail"
+	}
+	if c.Detail == "" {
+		return fmt.Sprintf("[%s] %s", result, c.Name)
+	}
+	return fmt.Sprintf("[%s] %s: %s", result, c.Name, c.Detail)
+}
+
+// Explain evaluates every predicate of the search against a finding, in the
+// order Match does, without stopping at the first that fails. Each term says
+// which field it matched in and each clause of the query is given on its own,
+// so a complex query can be debugged against the findings it matches or misses.
+func (s *Searcher) Explain(data map[string]interface{}) []Check {
+	var checks []Check
+	add := func(name string, passed bool, detail string) {
+		checks = append(checks, Check{Name: name, Passed: passed, Detail: detail})
+	}
+
+	if s.opts.Verified != nil {
+		verified, _ := data["Verified"].(bool)
+		add("verified", verified == *s.opts.Verified, fmt.Sprintf("Verified is %t", verified))
+	}
+	if s.detectors != nil {
+		detector, _ := data["DetectorName"].(string)
+		add("detector", s.detectors[strings.ToLower(detector)], orMissing(detector))
+	}
+	if s.detectorTypes != nil {
+		code, name, ok := s.DetectorType(data)
+		detail := "(missing)"
+		if ok {
+			detail = fmt.Sprintf("%d (%s)", code, name)
+		}
+		add("detector type", s.hasDetectorType(data), detail)
+	}
+	if s.repos != nil {
+		add("repository scope", s.inRepositories(data), orMissing(RepositoryPath(repository(data))))
+	}
+	if s.orgs != nil {
+		org := Organization(RepositoryPath(repository(data)))
+		add("organization", s.orgs[strings.ToLower(org)], orMissing(org))
+	}
+	for _, field := range s.opts.MissingFields {
+		path, found := Resolve(data, field)
+		add("missing field "+field, !found, path)
+	}
+	if !s.opts.Since.IsZero() || !s.opts.Until.IsZero() {
+		value, _ := Lookup(data, "timestamp")
+		timestamp, ok := ParseTime(value)
+		passed := ok && (s.opts.Since.IsZero() || !timestamp.Before(s.opts.Since)) && (s.opts.Until.IsZero() || !timestamp.After(s.opts.Until))
+		add("time window", passed, orMissing(String(value)))
+	}
+	if s.opts.MinEntropy > 0 || s.opts.MinLength > 0 || s.opts.MaxLength > 0 || s.opts.Charset != "" {
+		secret := Secret(data)
+		if s.opts.MinLength > 0 {
+			add(fmt.Sprintf("min length %d", s.opts.MinLength), secret != "" && len(secret) >= s.opts.MinLength, fmt.Sprintf("%d characters", len(secret)))
+		}
+		if s.opts.MaxLength > 0 {
+			add(fmt.Sprintf("max length %d", s.opts.MaxLength), secret != "" && len(secret) <= s.opts.MaxLength, fmt.Sprintf("%d characters", len(secret)))
+		}
+		if s.opts.MinEntropy > 0 {
+			entropy := Entropy(secret)
+			add(fmt.Sprintf("min entropy %g", s.opts.MinEntropy), secret != "" && entropy >= s.opts.MinEntropy, fmt.Sprintf("%.2f bits per character", entropy))
+		}
+		if s.opts.Charset != "" {
+			add("charset "+s.opts.Charset, MatchesCharset(secret, s.opts.Charset), "")
+		}
+	}
+
+	var scope interface{} = data
+	path := ""
+	if s.opts.Field != "" {
+		resolved, ok := Resolve(data, s.opts.Field)
+		add("field "+s.opts.Field, ok, resolved)
+		if !ok {
+			return checks
+		}
+		scope, _ = Get(data, resolved)
+		path = resolved
+	}
+	for i, matcher := range s.terms {
+		found, ok := s.matchingPath(path, scope, matcher)
+		add(fmt.Sprintf("term %q", s.opts.Terms[i]), ok, found)
+	}
+	for i, matcher := range s.not {
+		found, ok := s.matchingPath(path, scope, matcher)
+		add(fmt.Sprintf("not %q", s.opts.Not[i]), !ok, found)
+	}
+	if s.query != nil {
+		s.explainQuery(s.query, data, false, &checks)
+		add("query", s.query.eval(s, data), s.opts.Query)
+	}
+	return checks
+}
+
+// Add a check for every clause and bare term of a query. Operands of NOT are
+// given negated, so a check passes when it lets the finding through.
+func (s *Searcher) explainQuery(node queryNode, data map[string]interface{}, negated bool, checks *[]Check) {
+	prefix := "query "
+	if negated {
+		prefix = "query NOT "
+	}
+	switch n := node.(type) {
+	case *andNode:
+		s.explainQuery(n.left, data, negated, checks)
+		s.explainQuery(n.right, data, negated, checks)
+	case *orNode:
+		s.explainQuery(n.left, data, negated, checks)
+		s.explainQuery(n.right, data, negated, checks)
+	case *notNode:
+		s.explainQuery(n.operand, data, !negated, checks)
+	case *termNode:
+		found, ok := s.matchingPath("", data, n.matcher)
+		*checks = append(*checks, Check{Name: fmt.Sprintf("%sterm %q", prefix, n.term), Passed: ok != negated, Detail: found})
+	case *clauseNode:
+		value, _ := Lookup(data, n.field)
+		*checks = append(*checks, Check{Name: prefix + "clause " + n.field + n.op + n.value, Passed: n.eval(s, data) != negated, Detail: orMissing(String(value))})
+	}
+}
+
+// Path of the first value below scope the matcher accepts, in key order
+func (s *Searcher) matchingPath(path string, scope interface{}, matcher valueMatcher) (string, bool) {
+	switch v := scope.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if found, ok := s.matchingPath(child, v[key], matcher); ok {
+				return found, true
+			}
+		}
+		return "", false
+	case []interface{}:
+		for i, item := range v {
+			if found, ok := s.matchingPath(fmt.Sprintf("%s[%d]", path, i), item, matcher); ok {
+				return found, true
+			}
+		}
+		return "", false
+	}
+	if s.valueMatches(scope, matcher) {
+		return path, true
+	}
+	return "", false
+}
+
+func orMissing(value string) string {
+	if value == "" {
+		return "(missing)"
+	}
+	return value
+}
@@ -0,0 +1,150 @@
+package searcher
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Get returns the value at a dot-separated path such as
+// "SourceMetadata.Data.Github.file". Paths starting with "rawv2." address the
+// components of a composite RawV2 credential, see ParseRawV2.
+func Get(data map[string]interface{}, path string) (interface{}, bool) {
+	if component, ok := strings.CutPrefix(path, "rawv2."); ok {
+		return rawV2Field(data, component)
+	}
+	parts := strings.Split(path, ".")
+	current := data
+	for i, part := range parts {
+		value, exists := current[part]
+		if !exists {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		subMap, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = subMap
+	}
+	return nil, false
+}
+
+// Lookup resolves a field the way searches do. The path is tried as given,
+// then below the metadata of the source that produced the finding
+// (SourceMetadata.Data.<Source>, e.g. Github, Gitlab, Filesystem, S3 or
+// Docker), and a plain field name is finally looked for anywhere in the finding.
+func Lookup(data map[string]interface{}, field string) (interface{}, bool) {
+	path, ok := Resolve(data, field)
+	if !ok {
+		return nil, false
+	}
+	return Get(data, path)
+}
+
+// Resolve returns the full path Lookup would read a field from
+func Resolve(data map[string]interface{}, field string) (string, bool) {
+	if field == "" {
+		return "", false
+	}
+	if _, ok := Get(data, field); ok {
+		return field, true
+	}
+	if strings.HasPrefix(field, "rawv2.") {
+		return "", false
+	}
+	if sources, ok := data["SourceMetadata"].(map[string]interface{}); ok {
+		if byKind, ok := sources["Data"].(map[string]interface{}); ok {
+			for _, kind := range sortedKeys(byKind) {
+				if metadata, ok := byKind[kind].(map[string]interface{}); ok {
+					if _, ok := Get(metadata, field); ok {
+						return "SourceMetadata.Data." + kind + "." + field, true
+					}
+				}
+			}
+		}
+	}
+	if !strings.Contains(field, ".") {
+		return findLeaf(data, "", field)
+	}
+	return "", false
+}
+
+// Breadth-first search for the first field with the given name, in key order
+func findLeaf(data map[string]interface{}, prefix, name string) (string, bool) {
+	level := []struct {
+		path   string
+		object map[string]interface{}
+	}{{prefix, data}}
+	for len(level) > 0 {
+		var next = level[:0:0]
+		for _, entry := range level {
+			for _, key := range sortedKeys(entry.object) {
+				path := key
+				if entry.path != "" {
+					path = entry.path + "." + key
+				}
+				if key == name {
+					return path, true
+				}
+				if child, ok := entry.object[key].(map[string]interface{}); ok {
+					next = append(next, struct {
+						path   string
+						object map[string]interface{}
+					}{path, child})
+				}
+			}
+		}
+		level = next
+	}
+	return "", false
+}
+
+// Walk calls fn for every leaf value of a finding with its dot path. Arrays
+// are leaves.
+func Walk(data map[string]interface{}, fn func(path string, value interface{})) {
+	walk("", data, fn)
+}
+
+func walk(prefix string, data map[string]interface{}, fn func(path string, value interface{})) {
+	for _, key := range sortedKeys(data) {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if child, ok := data[key].(map[string]interface{}); ok && len(child) > 0 {
+			walk(path, child, fn)
+			continue
+		}
+		fn(path, data[key])
+	}
+}
+
+// String renders a JSON value as text: strings as they are, numbers without
+// exponent, booleans as true/false and anything else as JSON
+func String(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package searcher
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Secret returns the secret of a finding: Raw, or RawV2 when Raw is empty
+func Secret(data map[string]interface{}) string {
+	if raw, _ := data["Raw"].(string); raw != "" {
+		return raw
+	}
+	raw, _ := data["RawV2"].(string)
+	return raw
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// MatchesCharset reports whether s only uses the characters of a charset:
+// "hex" (0-9, a-f, A-F) or "base64" (standard or URL-safe alphabet, optionally padded)
+func MatchesCharset(s, charset string) bool {
+	if s == "" {
+		return false
+	}
+	switch charset {
+	case "hex":
+		for _, r := range s {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+		return true
+	case "base64":
+		trimmed := strings.TrimRight(s, "=")
+		if len(s)-len(trimmed) > 2 {
+			return false
+		}
+		for _, r := range trimmed {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("+/-_", r)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Timestamp layouts found in trufflehog output and accepted in queries
+var timeLayouts = []string{
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses a timestamp value: the "2006-01-02 15:04:05 -0700" form
+// trufflehog git sources emit, RFC 3339, a plain date, or unix seconds
+func ParseTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return time.Time{}, false
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(seconds, 0), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,98 @@
+//go:build ignore
+
+// Generates detectortypes_gen.go from the DetectorType enum of trufflehog's
+// proto/detector_type.proto, read from the module cache at the pinned
+// version (downloaded with go mod download when needed):
+//
+//	go generate ./pkg/searcher
+//	go run gen_detectortypes.go -version v3.98.0
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const trufflehogModule = "github.com/trufflesecurity/trufflehog/v3"
+
+// One value of the enum, e.g. "  AWS = 2;" or "  Nytimes = 241 [deprecated = true];"
+var enumValue = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(\d+)\s*(\[[^\]]*\])?\s*;`)
+
+func main() {
+	version := flag.String("version", "v3.97.4", "Version of trufflehog to read the enum from")
+	output := flag.String("o", "detectortypes_gen.go", "File to write")
+	flag.Parse()
+
+	download, err := exec.Command("go", "mod", "download", "-json", trufflehogModule+"@"+*version).Output()
+	if err != nil {
+		log.Fatalf("downloading %s@%s: %v", trufflehogModule, *version, err)
+	}
+	var module struct{ Dir string }
+	if err := json.Unmarshal(download, &module); err != nil {
+		log.Fatal(err)
+	}
+	proto, err := os.Open(filepath.Join(module.Dir, "proto", "detector_type.proto"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer proto.Close()
+
+	names := map[int64]string{}
+	scanner := bufio.NewScanner(proto)
+	for scanner.Scan() {
+		match := enumValue.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		code, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if previous, ok := names[code]; ok {
+			log.Fatalf("code %d is both %s and %s", code, previous, match[1])
+		}
+		names[code] = match[1]
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if len(names) == 0 {
+		log.Fatalf("no DetectorType values in %s", proto.Name())
+	}
+	codes := make([]int64, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gen_detectortypes.go from %s@%s proto/detector_type.proto; DO NOT EDIT.\n\n", trufflehogModule, *version)
+	fmt.Fprintf(&out, "package searcher\n\n")
+	fmt.Fprintf(&out, "// DetectorTypes names the codes of trufflehog's DetectorType enum\n")
+	fmt.Fprintf(&out, "// (detector_typepb.DetectorType), deprecated ones included, as of %s.\n", *version)
+	fmt.Fprintf(&out, "// Codes missing here are named by the DetectorName of their findings.\n")
+	fmt.Fprintf(&out, "// Callers may add codes, e.g. those of custom detectors, before searching.\n")
+	fmt.Fprintf(&out, "var DetectorTypes = map[int64]string{\n")
+	for _, code := range codes {
+		fmt.Fprintf(&out, "\t%d: %q,\n", code, names[code])
+	}
+	fmt.Fprintf(&out, "}\n")
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, formatted, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
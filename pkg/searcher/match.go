@@ -0,0 +1,323 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizations lists the names accepted in Options.Normalize:
+//
+//	space   strip newlines (real or escaped) and quotes, collapse whitespace runs
+//	unquote remove surrounding quotes and resolve backslash escapes
+//	url     decode percent-encoding
+//	base64  also compare the decoded text of values that are valid base64
+var Normalizations = []string{"space", "unquote", "url", "base64"}
+
+// Transformations applied to values and terms before comparing them
+type normalizer struct {
+	lower   bool
+	space   bool
+	unquote bool
+	url     bool
+	base64  bool
+}
+
+func newNormalizer(names []string, caseSensitive bool) (normalizer, error) {
+	n := normalizer{lower: !caseSensitive}
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "space":
+			n.space = true
+		case "unquote":
+			n.unquote = true
+		case "url":
+			n.url = true
+		case "base64":
+			n.base64 = true
+		case "":
+		default:
+			return n, fmt.Errorf("unsupported normalization %q (expected %s)", name, strings.Join(Normalizations, ", "))
+		}
+	}
+	return n, nil
+}
+
+// Normalize a search term
+func (n normalizer) term(term string) string {
+	return n.apply(term)
+}
+
+// The forms of a value compared against the terms
+func (n normalizer) values(value string) []string {
+	values := []string{n.apply(value)}
+	if n.base64 {
+		if decoded, ok := decodeBase64(value); ok {
+			values = append(values, n.apply(decoded))
+		}
+	}
+	return values
+}
+
+func (n normalizer) apply(value string) string {
+	if n.unquote {
+		value = unquote(value)
+	}
+	if n.url {
+		if decoded, err := url.QueryUnescape(value); err == nil {
+			value = decoded
+		} else if decoded, err := url.PathUnescape(value); err == nil {
+			value = decoded
+		}
+	}
+	if n.space {
+		value = normalizeSpace(value)
+	}
+	if n.lower {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// Remove formatting differences from a value: newlines (real or escaped) and quotes are
+// stripped and remaining whitespace runs collapse to a single space
+func normalizeSpace(value string) string {
+	value = strings.NewReplacer("\\r", "", "\\n", "", "\r", "", "\n", "", "\"", "", "'", "", "`", "").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Strip one pair of surrounding quotes and resolve backslash escapes
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if first == last && (first == '"' || first == '\'' || first == '`') {
+			value = value[1 : len(value)-1]
+		}
+	}
+	if strings.Contains(value, "\\") {
+		if unescaped, err := strconv.Unquote(`"` + strings.ReplaceAll(value, `"`, `\"`) + `"`); err == nil {
+			value = unescaped
+		}
+	}
+	return value
+}
+
+// Decode a value that looks like base64 into printable text
+func decodeBase64(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 {
+		return "", false
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		decoded, err := encoding.DecodeString(value)
+		if err != nil || !utf8.Valid(decoded) {
+			continue
+		}
+		for _, r := range string(decoded) {
+			if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+				return "", false
+			}
+		}
+		return string(decoded), true
+	}
+	return "", false
+}
+
+// Check every scalar value below scope. Numbers and booleans are compared in
+// their JSON text form, so "-f Verified -s true" and "-f line -s 42" match.
+func (s *Searcher) valueMatches(scope interface{}, matcher valueMatcher) bool {
+	switch v := scope.(type) {
+	case string:
+		for _, value := range s.norm.values(v) {
+			if matcher(value) {
+				return true
+			}
+		}
+	case float64, bool:
+		return matcher(String(v))
+	case []interface{}:
+		for _, item := range v {
+			if s.valueMatches(item, matcher) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if s.valueMatches(item, matcher) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check whether two terms occur within the given number of characters of each other, in either order
+func termsNear(value, first, second string, within int) bool {
+	firstPositions := termPositions(value, first)
+	if len(firstPositions) == 0 {
+		return false
+	}
+	for _, secondStart := range termPositions(value, second) {
+		secondEnd := secondStart + len(second)
+		for _, firstStart := range firstPositions {
+			firstEnd := firstStart + len(first)
+			gap := 0
+			if secondStart >= firstEnd {
+				gap = secondStart - firstEnd
+			} else if firstStart >= secondEnd {
+				gap = firstStart - secondEnd
+			}
+			if gap <= within {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Find the start offsets of every occurrence of term in value
+func termPositions(value, term string) []int {
+	var positions []int
+	for offset := 0; offset <= len(value); {
+		index := strings.Index(value[offset:], term)
+		if index < 0 {
+			break
+		}
+		positions = append(positions, offset+index)
+		offset += index + 1
+	}
+	return positions
+}
+
+// Longest value compared window by window in fuzzy mode; longer values only compare their tokens
+const fuzzyWindowLimit = 1024
+
+// Check whether the value, one of its tokens or a term-sized window of it is
+// at least threshold similar to the term
+func fuzzyMatch(value, term string, threshold float64) bool {
+	if term == "" {
+		return true
+	}
+	if similarity(value, term) >= threshold {
+		return true
+	}
+	for _, token := range strings.FieldsFunc(value, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune(",;:\"'()[]{}<>", r) }) {
+		if similarity(token, term) >= threshold {
+			return true
+		}
+	}
+	if len(value) > fuzzyWindowLimit {
+		return false
+	}
+	valueRunes, size := []rune(value), utf8.RuneCountInString(term)
+	for start := 0; start+size <= len(valueRunes); start++ {
+		if similarity(string(valueRunes[start:start+size]), term) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Similarity between 0 and 1 derived from the Levenshtein distance
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// The raw JSON of a matching finding must contain the search terms verbatim,
+// which holds for plain contains/exact searches of terms JSON never escapes.
+// Returns nil when lines cannot be skipped without decoding them.
+func (s *Searcher) buildPrefilter() [][]byte {
+	if len(s.opts.Terms) == 0 || len(s.opts.Normalize) > 0 {
+		return nil
+	}
+	if s.opts.Mode != ModeContains && s.opts.Mode != ModeExact {
+		return nil
+	}
+	if strings.HasPrefix(s.opts.Field, "rawv2.") {
+		return nil
+	}
+	var prefilter [][]byte
+	for _, term := range s.opts.Terms {
+		if !verbatimInJSON(term) {
+			return nil
+		}
+		if !s.opts.CaseSensitive {
+			term = strings.ToLower(term)
+		}
+		prefilter = append(prefilter, []byte(term))
+	}
+	return prefilter
+}
+
+// Whether a term appears unchanged in the JSON encoding of any value containing it
+func verbatimInJSON(term string) bool {
+	if term == "" {
+		return false
+	}
+	numeric := true
+	for i := 0; i < len(term); i++ {
+		c := term[i]
+		if c < 0x20 || c >= 0x7f || strings.IndexByte("\"\\<>&", c) >= 0 {
+			return false
+		}
+		if !strings.ContainsRune("0123456789.-+eE", rune(c)) {
+			numeric = false
+		}
+	}
+	// Numbers may be written differently in the input than they are compared
+	return !numeric
+}
+
+// Check whether a raw entry may match, without decoding it
+func (s *Searcher) candidate(raw []byte) bool {
+	if s.prefilter == nil {
+		return true
+	}
+	if !s.opts.CaseSensitive {
+		raw = bytes.ToLower(raw)
+	}
+	for _, term := range s.prefilter {
+		found := bytes.Contains(raw, term)
+		if found && !s.opts.All {
+			return true
+		}
+		if !found && s.opts.All {
+			return false
+		}
+	}
+	return s.opts.All
+}
@@ -0,0 +1,100 @@
+package searcher
+
+import "time"
+
+// Option configures a Searcher built by New. Options is an Option setting
+// every field at once; the With* functions set one of them.
+type Option interface {
+	apply(opts *Options)
+}
+
+func (o Options) apply(opts *Options) {
+	*opts = o
+}
+
+type optionFunc func(opts *Options)
+
+func (f optionFunc) apply(opts *Options) {
+	f(opts)
+}
+
+// WithTerms adds search terms; a finding matches when any of them matches
+func WithTerms(terms ...string) Option {
+	return optionFunc(func(opts *Options) { opts.Terms = append(opts.Terms, terms...) })
+}
+
+// WithAllTerms requires every term to match instead of any
+func WithAllTerms() Option {
+	return optionFunc(func(opts *Options) { opts.All = true })
+}
+
+// WithMode sets how terms are compared with the values, one of Modes
+func WithMode(mode string) Option {
+	return optionFunc(func(opts *Options) { opts.Mode = mode })
+}
+
+// WithField searches a single field, resolved with Lookup, instead of every value
+func WithField(field string) Option {
+	return optionFunc(func(opts *Options) { opts.Field = field })
+}
+
+// WithQuery adds a boolean query expression, see Options.Query
+func WithQuery(query string) Option {
+	return optionFunc(func(opts *Options) { opts.Query = query })
+}
+
+// WithNot adds terms that must not occur in the searched values
+func WithNot(terms ...string) Option {
+	return optionFunc(func(opts *Options) { opts.Not = append(opts.Not, terms...) })
+}
+
+// WithCaseSensitive compares values and terms as they are instead of lowercased
+func WithCaseSensitive() Option {
+	return optionFunc(func(opts *Options) { opts.CaseSensitive = true })
+}
+
+// WithVerified keeps only verified (true) or unverified (false) findings
+func WithVerified(verified bool) Option {
+	return optionFunc(func(opts *Options) { opts.Verified = &verified })
+}
+
+// WithDetectors keeps only the findings of these detectors, case-insensitive
+func WithDetectors(detectors ...string) Option {
+	return optionFunc(func(opts *Options) { opts.Detectors = append(opts.Detectors, detectors...) })
+}
+
+// WithDetectorTypeNames names DetectorType codes besides DetectorTypes
+func WithDetectorTypeNames(names map[int64]string) Option {
+	return optionFunc(func(opts *Options) { opts.DetectorTypeNames = names })
+}
+
+// WithDetectorTypes keeps only these DetectorType codes or names
+func WithDetectorTypes(types ...string) Option {
+	return optionFunc(func(opts *Options) { opts.DetectorTypes = append(opts.DetectorTypes, types...) })
+}
+
+// WithRepositories keeps only the findings of repositories matching these patterns, e.g. acme/*
+func WithRepositories(patterns ...string) Option {
+	return optionFunc(func(opts *Options) { opts.Repositories = append(opts.Repositories, patterns...) })
+}
+
+// WithOrganizations keeps only the findings of repositories of these organizations
+func WithOrganizations(orgs ...string) Option {
+	return optionFunc(func(opts *Options) { opts.Organizations = append(opts.Organizations, orgs...) })
+}
+
+// WithTimeWindow keeps only the findings committed between since and until;
+// a zero time leaves that side open
+func WithTimeWindow(since, until time.Time) Option {
+	return optionFunc(func(opts *Options) { opts.Since, opts.Until = since, until })
+}
+
+// WithMinEntropy keeps only secrets of at least this Shannon entropy, in bits per character
+func WithMinEntropy(bits float64) Option {
+	return optionFunc(func(opts *Options) { opts.MinEntropy = bits })
+}
+
+// WithMaxLineBytes sets the longest JSON line accepted when reading findings
+func WithMaxLineBytes(n int) Option {
+	return optionFunc(func(opts *Options) { opts.MaxLineBytes = n })
+}
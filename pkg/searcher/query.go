@@ -0,0 +1,330 @@
+package searcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A parsed query expression
+type queryNode interface {
+	prepare(s *Searcher) error
+	eval(s *Searcher, data map[string]interface{}) bool
+}
+
+// Parse a boolean query expression, see Options.Query and the package documentation
+func parseQuery(query string) (queryNode, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("query: unexpected %q", p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota // field name, value or bare term
+	tokenQuoted
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+}
+
+// Comparison operators, longest first so prefixes do not shadow them
+var queryOperators = []string{"!=", "!~", "=~", ">=", "<=", "=", "~", ">", "<"}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{tokenOpen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{tokenClose, ")"})
+			i++
+		case strings.HasPrefix(query[i:], "&&"):
+			tokens = append(tokens, queryToken{tokenAnd, "&&"})
+			i += 2
+		case strings.HasPrefix(query[i:], "||"):
+			tokens = append(tokens, queryToken{tokenOr, "||"})
+			i += 2
+		case c == '"' || c == '\'':
+			end := i + 1
+			var value strings.Builder
+			for ; end < len(query) && query[end] != c; end++ {
+				if query[end] == '\\' && end+1 < len(query) {
+					end++
+				}
+				value.WriteByte(query[end])
+			}
+			if end >= len(query) {
+				return nil, fmt.Errorf("query: unterminated quote at offset %d", i)
+			}
+			tokens = append(tokens, queryToken{tokenQuoted, value.String()})
+			i = end + 1
+		default:
+			if op := operatorAt(query[i:]); op != "" {
+				tokens = append(tokens, queryToken{tokenOperator, op})
+				i += len(op)
+				continue
+			}
+			if c == '!' {
+				tokens = append(tokens, queryToken{tokenNot, "!"})
+				i++
+				continue
+			}
+			end := i
+			for end < len(query) && !unicode.IsSpace(rune(query[end])) && !strings.ContainsRune("()\"'", rune(query[end])) && operatorAt(query[end:]) == "" && query[end] != '!' {
+				end++
+			}
+			word := query[i:end]
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{tokenAnd, word})
+			case "OR":
+				tokens = append(tokens, queryToken{tokenOr, word})
+			case "NOT":
+				tokens = append(tokens, queryToken{tokenNot, word})
+			default:
+				tokens = append(tokens, queryToken{tokenWord, word})
+			}
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func operatorAt(s string) string {
+	for _, op := range queryOperators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// Recursive descent parser; NOT binds tighter than AND, which binds tighter than OR
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token != nil && token.kind == tokenOr; token = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token != nil && token.kind != tokenOr && token.kind != tokenClose; token = p.peek() {
+		if token.kind == tokenAnd {
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	token := p.peek()
+	if token == nil {
+		return nil, fmt.Errorf("query: unexpected end of expression")
+	}
+	switch token.kind {
+	case tokenNot:
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	case tokenOpen:
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokenClose {
+			return nil, fmt.Errorf("query: missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case tokenWord, tokenQuoted:
+		p.pos++
+		if op := p.peek(); op != nil && op.kind == tokenOperator {
+			p.pos++
+			value := p.peek()
+			if value == nil || (value.kind != tokenWord && value.kind != tokenQuoted) {
+				return nil, fmt.Errorf("query: missing value after %s%s", token.text, op.text)
+			}
+			p.pos++
+			return &clauseNode{field: token.text, op: op.text, value: value.text}, nil
+		}
+		return &termNode{term: token.text}, nil
+	}
+	return nil, fmt.Errorf("query: unexpected %q", token.text)
+}
+
+type andNode struct{ left, right queryNode }
+
+func (n *andNode) prepare(s *Searcher) error {
+	if err := n.left.prepare(s); err != nil {
+		return err
+	}
+	return n.right.prepare(s)
+}
+
+func (n *andNode) eval(s *Searcher, data map[string]interface{}) bool {
+	return n.left.eval(s, data) && n.right.eval(s, data)
+}
+
+type orNode struct{ left, right queryNode }
+
+func (n *orNode) prepare(s *Searcher) error {
+	if err := n.left.prepare(s); err != nil {
+		return err
+	}
+	return n.right.prepare(s)
+}
+
+func (n *orNode) eval(s *Searcher, data map[string]interface{}) bool {
+	return n.left.eval(s, data) || n.right.eval(s, data)
+}
+
+type notNode struct{ operand queryNode }
+
+func (n *notNode) prepare(s *Searcher) error {
+	return n.operand.prepare(s)
+}
+
+func (n *notNode) eval(s *Searcher, data map[string]interface{}) bool {
+	return !n.operand.eval(s, data)
+}
+
+// A bare term searched in every value
+type termNode struct {
+	term    string
+	matcher valueMatcher
+}
+
+func (n *termNode) prepare(s *Searcher) error {
+	matcher, err := s.newMatcher(ModeContains, n.term)
+	n.matcher = matcher
+	return err
+}
+
+func (n *termNode) eval(s *Searcher, data map[string]interface{}) bool {
+	return s.valueMatches(data, n.matcher)
+}
+
+// A field comparison
+type clauseNode struct {
+	field, op, value string
+	matcher          valueMatcher
+}
+
+func (n *clauseNode) prepare(s *Searcher) error {
+	var err error
+	switch n.op {
+	case "=", "!=":
+		n.matcher, err = s.newMatcher(ModeExact, n.value)
+	case "~", "!~":
+		n.matcher, err = s.newMatcher(ModeContains, n.value)
+	case "=~":
+		n.matcher, err = s.newMatcher(ModeRegex, n.value)
+	}
+	return err
+}
+
+func (n *clauseNode) eval(s *Searcher, data map[string]interface{}) bool {
+	value, ok := Lookup(data, n.field)
+	switch n.op {
+	case "=", "~", "=~":
+		return ok && s.valueMatches(value, n.matcher)
+	case "!=", "!~":
+		return !ok || !s.valueMatches(value, n.matcher)
+	}
+	if !ok {
+		return false
+	}
+	order := compareValues(value, n.value)
+	switch n.op {
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	case "<":
+		return order < 0
+	}
+	return order <= 0
+}
+
+// Order a finding value against a query value: as numbers when both are
+// numbers, as timestamps when both are timestamps, otherwise as text
+func compareValues(value interface{}, operand string) int {
+	text := String(value)
+	if a, err := strconv.ParseFloat(text, 64); err == nil {
+		if b, err := strconv.ParseFloat(operand, 64); err == nil {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	}
+	if a, ok := ParseTime(value); ok {
+		if b, ok := ParseTime(operand); ok {
+			return a.Compare(b)
+		}
+	}
+	return strings.Compare(text, operand)
+}
@@ -0,0 +1,85 @@
+package searcher
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// RawV2Components are the components of a composite RawV2 credential that
+// fields of the form rawv2.<component> address
+var RawV2Components = []string{"username", "password", "key", "secret", "url", "scheme", "host", "path", "token"}
+
+// Query parameters that carry a token in URL credentials
+var rawV2TokenParams = []string{"token", "access_token", "api_key", "apikey", "key", "sig"}
+
+// ParseRawV2 splits a composite RawV2 credential into named components.
+// Detectors use several shapes: JSON objects, URLs with credentials or tokens,
+// and separator-joined pairs such as key:secret or username:password.
+func ParseRawV2(raw string) map[string]interface{} {
+	raw = strings.TrimSpace(raw)
+	components := map[string]interface{}{}
+	if raw == "" {
+		return components
+	}
+
+	if strings.HasPrefix(raw, "{") {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err == nil {
+			for name, value := range object {
+				components[strings.ToLower(name)] = value
+			}
+			return components
+		}
+	}
+
+	// A URL and token joined by a separator, e.g. https://host;token
+	for _, separator := range []string{";", "|", " ", "\t"} {
+		if first, second, ok := strings.Cut(raw, separator); ok && strings.Contains(first, "://") {
+			components["url"] = first
+			components["token"] = strings.TrimSpace(second)
+			return components
+		}
+	}
+
+	if parsed, err := url.Parse(raw); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		components["url"] = raw
+		components["scheme"] = parsed.Scheme
+		components["host"] = parsed.Host
+		if parsed.Path != "" {
+			components["path"] = parsed.Path
+		}
+		if parsed.User != nil {
+			components["username"] = parsed.User.Username()
+			if password, ok := parsed.User.Password(); ok {
+				components["password"] = password
+			}
+		}
+		query := parsed.Query()
+		for _, param := range rawV2TokenParams {
+			if token := query.Get(param); token != "" {
+				components["token"] = token
+				break
+			}
+		}
+		return components
+	}
+
+	// Pairs do not say whether they are key+secret or username+password,
+	// so both names are exposed
+	if first, second, ok := strings.Cut(raw, ":"); ok && first != "" && second != "" && !strings.ContainsAny(first, " \t") {
+		components["key"], components["username"] = first, first
+		components["secret"], components["password"] = second, second
+	}
+	return components
+}
+
+// Resolve a rawv2.<component> path against the finding's RawV2 value
+func rawV2Field(data map[string]interface{}, component string) (interface{}, bool) {
+	raw, ok := data["RawV2"].(string)
+	if !ok {
+		return nil, false
+	}
+	value, exists := ParseRawV2(raw)[strings.ToLower(component)]
+	return value, exists
+}
@@ -0,0 +1,84 @@
+package searcher
+
+import (
+	"strings"
+)
+
+// DefaultRedactFields are the fields holding the secret of a finding
+var DefaultRedactFields = []string{"Raw", "RawV2"}
+
+// Mask hides all but the first and last four characters of a secret.
+// Secrets of twelve characters or fewer are hidden entirely.
+func Mask(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 12 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-8) + string(runes[len(runes)-4:])
+}
+
+// Redact returns a copy of a finding with the string values of fields masked.
+// Fields are resolved with Resolve; the finding itself is left unchanged.
+func Redact(data map[string]interface{}, fields []string) map[string]interface{} {
+	redacted, copied := data, false
+	for _, field := range fields {
+		path, ok := Resolve(data, field)
+		if !ok || strings.HasPrefix(path, "rawv2.") {
+			continue
+		}
+		if !copied {
+			redacted, copied = cloneMap(data), true
+		}
+		setMasked(redacted, strings.Split(path, "."))
+	}
+	return redacted
+}
+
+// Mask the value at a path, copying the maps along it so the original is not modified
+func setMasked(data map[string]interface{}, parts []string) {
+	for i, part := range parts {
+		value, ok := data[part]
+		if !ok {
+			return
+		}
+		if i == len(parts)-1 {
+			data[part] = maskValue(value)
+			return
+		}
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		child = cloneMap(child)
+		data[part] = child
+		data = child
+	}
+}
+
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return Mask(v)
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskValue(item)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			masked[key] = maskValue(item)
+		}
+		return masked
+	}
+	return value
+}
+
+func cloneMap(data map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		clone[key] = value
+	}
+	return clone
+}
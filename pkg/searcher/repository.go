@@ -0,0 +1,90 @@
+package searcher
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RepositoryPath returns a repository URL as host/path, e.g.
+// "github.com/acme/api" for https://github.com/acme/api.git or
+// git@github.com:acme/api.git, without credentials, port or .git suffix
+func RepositoryPath(repository string) string {
+	repository = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(repository), "/"), ".git")
+	if strings.Contains(repository, "://") {
+		if u, err := url.Parse(repository); err == nil {
+			return u.Hostname() + strings.TrimSuffix(u.Path, "/")
+		}
+		return repository
+	}
+	// scp-like git@github.com:acme/api
+	if _, rest, ok := strings.Cut(repository, "@"); ok {
+		if host, path, ok := strings.Cut(rest, ":"); ok {
+			return host + "/" + strings.TrimPrefix(path, "/")
+		}
+	}
+	return repository
+}
+
+// Organization returns the owner of a repository path as returned by
+// RepositoryPath: "acme" for github.com/acme/api, the top-level group on GitLab
+func Organization(path string) string {
+	segments := strings.Split(path, "/")
+	if len(segments) < 3 {
+		return ""
+	}
+	return segments[1]
+}
+
+// A pattern of Options.Repositories
+type repositoryPattern struct {
+	re       *regexp.Regexp
+	withHost bool // the pattern starts with a host name, e.g. github.com/acme/*
+}
+
+// Compile a repository pattern: org/name, a URL, or a glob where * matches
+// within a path segment and ** across segments, e.g. acme/* or gitlab.com/acme/**
+func compileRepositoryPattern(pattern string) (repositoryPattern, error) {
+	path := RepositoryPath(pattern)
+	segments := strings.Split(path, "/")
+	if len(segments) < 2 || segments[0] == "" {
+		return repositoryPattern{}, fmt.Errorf("repository pattern %q needs an organization and a name, e.g. acme/api or acme/*", pattern)
+	}
+	expr := regexp.QuoteMeta(path)
+	expr = strings.ReplaceAll(expr, `\*\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\*`, "[^/]*")
+	expr = strings.ReplaceAll(expr, `\?`, "[^/]")
+	re, err := regexp.Compile("(?i)^" + expr + "$")
+	if err != nil {
+		return repositoryPattern{}, err
+	}
+	return repositoryPattern{re: re, withHost: strings.Contains(segments[0], ".")}, nil
+}
+
+// Whether a repository, as returned by RepositoryPath, matches the pattern
+func (p repositoryPattern) matches(path string) bool {
+	if !p.withHost {
+		_, path, _ = strings.Cut(path, "/")
+	}
+	return p.re.MatchString(path)
+}
+
+// Whether the repository of a finding matches one of Options.Repositories
+func (s *Searcher) inRepositories(data map[string]interface{}) bool {
+	path := RepositoryPath(repository(data))
+	if path == "" {
+		return false
+	}
+	for _, pattern := range s.repos {
+		if pattern.matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func repository(data map[string]interface{}) string {
+	value, _ := Lookup(data, "repository")
+	return String(value)
+}
@@ -0,0 +1,58 @@
+package searcher
+
+import "testing"
+
+func TestCompileRepositoryPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string // as returned by RepositoryPath
+		match   bool
+	}{
+		{"acme/api", "github.com/acme/api", true},
+		{"ACME/API", "github.com/acme/api", true},
+		{"acme/api", "gitlab.com/acme/api", true},
+		{"acme/api", "github.com/acme/api-v2", false},
+		{"acme/*", "github.com/acme/api", true},
+		{"acme/*", "gitlab.com/acme/group/api", false},
+		{"acme/**", "gitlab.com/acme/group/api", true},
+		{"acme/ap?", "github.com/acme/api", true},
+		{"github.com/acme/*", "github.com/acme/api", true},
+		{"github.com/acme/*", "gitlab.com/acme/api", false},
+		{"https://github.com/acme/api.git", "github.com/acme/api", true},
+		{"git@github.com:acme/api.git", "github.com/acme/api", true},
+		{"acme/a.i", "github.com/acme/api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			pattern, err := compileRepositoryPattern(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pattern.matches(tt.path); got != tt.match {
+				t.Errorf("matches(%q) = %v, want %v", tt.path, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCompileRepositoryPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"", "api", "/api"} {
+		if _, err := compileRepositoryPattern(pattern); err == nil {
+			t.Errorf("compileRepositoryPattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestRepositoryPath(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/acme/api.git":          "github.com/acme/api",
+		"https://user:pw@github.com:443/acme/api/": "github.com/acme/api",
+		"git@github.com:acme/api.git":              "github.com/acme/api",
+		"github.com/acme/api":                      "github.com/acme/api",
+	}
+	for repository, want := range tests {
+		if got := RepositoryPath(repository); got != want {
+			t.Errorf("RepositoryPath(%q) = %q, want %q", repository, got, want)
+		}
+	}
+}
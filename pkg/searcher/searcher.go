@@ -0,0 +1,515 @@
+// Package searcher finds findings in trufflehog JSON output.
+//
+// A Searcher is built once from Options and applied to any number of
+// trufflehog output streams:
+//
+//	s, err := searcher.New(searcher.Options{Terms: []string{"acme"}, Detectors: []string{"AWS"}})
+//	if err != nil {
+//		return err
+//	}
+//	findings, err := s.Search(ctx, file)
+//
+// Options can also be given as functions, e.g. searcher.New(searcher.WithTerms("acme")),
+// and the matches read one at a time with Findings, ranged over with
+// FindingStream.All or received from FindingStream.Chan. Errors are of the kinds
+// ErrBadQuery and ErrCorruptInput.
+//
+// Streams may hold JSON lines (trufflehog --json), a JSON array, a
+// {"results": [...]} envelope or concatenated pretty-printed objects; the
+// format is detected per stream. Fields are addressed with dot paths and
+// resolved against the metadata of whichever source produced the finding,
+// see Lookup.
+//
+// Options.Query holds a boolean query expression such as
+//
+//	DetectorName=AWS AND Verified=true AND (repository~acme OR repository~corp) AND NOT file~test
+//
+// Clauses compare a field, resolved with Lookup, against a value:
+//
+//	field=value   the value equals
+//	field!=value  the value differs, or the field is missing
+//	field~value   the value contains
+//	field!~value  the value does not contain, or the field is missing
+//	field=~regex  the value matches the regular expression
+//	field>value   also >=, < and <=; numbers, timestamps and otherwise text are compared
+//
+// A term without a field matches when any value of the finding contains it.
+// Clauses combine with AND, OR and NOT (or &&, || and !), grouped with
+// parentheses; clauses next to each other are combined with AND. Values with
+// spaces or operator characters are quoted with double or single quotes.
+package searcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Search modes
+const (
+	ModeContains = "contains" // the value contains the term
+	ModeExact    = "exact"    // the value equals the term
+	ModeNear     = "near"     // the term and Options.Near occur within Options.Within characters
+	ModeRegex    = "regex"    // the term is a regular expression matched against the value
+	ModeFuzzy    = "fuzzy"    // the value, one of its tokens or a window of it is similar to the term
+)
+
+// Modes lists the supported search modes
+var Modes = []string{ModeContains, ModeExact, ModeNear, ModeRegex, ModeFuzzy}
+
+// Options configure a Searcher. The zero value matches every finding.
+type Options struct {
+	Terms []string // search terms; a finding matches when any term matches
+	All   bool     // require every term to match instead of any
+	Mode  string   // one of Modes, "contains" when empty
+	Field string   // field to search in, resolved with Lookup; empty searches every value
+
+	Near      string  // second term for ModeNear
+	Within    int     // maximum number of characters between the terms in ModeNear
+	Threshold float64 // minimum similarity between 0 and 1 for ModeFuzzy, 0.8 when zero
+
+	CaseSensitive bool     // compare values and terms as they are instead of lowercased
+	Normalize     []string // normalizations applied before comparing, see Normalizations
+
+	Query string   // boolean query expression, see the package documentation
+	Not   []string // terms that must not occur in the searched values
+
+	MissingFields []string  // fields the finding must not have
+	Verified      *bool     // only verified (true) or unverified (false) findings
+	Detectors     []string  // detector names, case-insensitive
+	DetectorTypes []string  // DetectorType codes or their names, e.g. "17" or "URI" (see DetectorTypes)
+	Repositories  []string  // repository patterns such as acme/api or acme/*; findings of other repositories are excluded
+	Organizations []string  // organizations owning the repository, case-insensitive
+	Since, Until  time.Time // commit timestamp window; findings without a timestamp are excluded
+
+	// Names of DetectorType codes besides DetectorTypes, e.g. of custom
+	// detectors, taking precedence over them
+	DetectorTypeNames map[int64]string
+
+	MinEntropy float64 // minimum Shannon entropy of the secret in bits per character
+	MinLength  int     // minimum length of the secret
+	MaxLength  int     // maximum length of the secret, 0 for no limit
+	Charset    string  // required character set of the secret: "base64" or "hex"
+
+	MaxLineBytes int // longest JSON line accepted by Search, 0 for no limit
+}
+
+// Finding is a finding that matched a search
+type Finding struct {
+	Line  int                    // line number for JSON lines input, otherwise the position in the stream
+	Data  map[string]interface{} // the decoded finding
+	Terms []string               // search terms that matched
+}
+
+// Searcher decides which findings match its Options. It is safe for concurrent use.
+type Searcher struct {
+	opts          Options
+	terms         []valueMatcher
+	not           []valueMatcher
+	query         queryNode
+	detectors     map[string]bool
+	detectorTypes map[string]bool
+	repos         []repositoryPattern
+	orgs          map[string]bool
+	norm          normalizer
+	prefilter     [][]byte // terms as they must appear in the raw JSON, nil when that cannot be decided
+}
+
+// Matches a single, already normalized, value
+type valueMatcher func(value string) bool
+
+// New validates the options and prepares the search. Options given as
+// Options or as With* functions apply in order, later ones overriding:
+//
+//	s, err := searcher.New(searcher.WithTerms("acme"), searcher.WithVerified(true))
+//
+// Options that cannot be searched with are reported as an ErrBadQuery.
+func New(options ...Option) (*Searcher, error) {
+	var opts Options
+	for _, option := range options {
+		option.apply(&opts)
+	}
+	s, err := newSearcher(opts)
+	if err != nil {
+		return nil, badQuery(err)
+	}
+	return s, nil
+}
+
+func newSearcher(opts Options) (*Searcher, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeContains
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = 0.8
+	}
+	if opts.Threshold < 0 || opts.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1, got %g", opts.Threshold)
+	}
+	if opts.Mode == ModeNear && opts.Near == "" {
+		return nil, fmt.Errorf("near mode requires a second term")
+	}
+	if opts.Charset != "" && opts.Charset != "base64" && opts.Charset != "hex" {
+		return nil, fmt.Errorf("unsupported charset %q (expected base64 or hex)", opts.Charset)
+	}
+
+	s := &Searcher{opts: opts}
+	norm, err := newNormalizer(opts.Normalize, opts.CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	s.norm = norm
+
+	for _, term := range opts.Terms {
+		matcher, err := s.newMatcher(opts.Mode, term)
+		if err != nil {
+			return nil, err
+		}
+		s.terms = append(s.terms, matcher)
+	}
+	for _, term := range opts.Not {
+		matcher, err := s.newMatcher(ModeContains, term)
+		if err != nil {
+			return nil, err
+		}
+		s.not = append(s.not, matcher)
+	}
+	if strings.TrimSpace(opts.Query) != "" {
+		query, err := parseQuery(opts.Query)
+		if err != nil {
+			return nil, err
+		}
+		if err := query.prepare(s); err != nil {
+			return nil, err
+		}
+		s.query = query
+	}
+	if len(opts.Detectors) > 0 {
+		s.detectors = map[string]bool{}
+		for _, detector := range opts.Detectors {
+			s.detectors[strings.ToLower(detector)] = true
+		}
+	}
+	if len(opts.DetectorTypes) > 0 {
+		s.detectorTypes = map[string]bool{}
+		for _, detectorType := range opts.DetectorTypes {
+			s.detectorTypes[strings.ToLower(strings.TrimSpace(detectorType))] = true
+		}
+	}
+	if len(opts.Organizations) > 0 {
+		s.orgs = map[string]bool{}
+		for _, org := range opts.Organizations {
+			s.orgs[strings.ToLower(org)] = true
+		}
+	}
+	for _, pattern := range opts.Repositories {
+		compiled, err := compileRepositoryPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		s.repos = append(s.repos, compiled)
+	}
+	s.prefilter = s.buildPrefilter()
+	return s, nil
+}
+
+// Build the matcher of one term for a mode
+func (s *Searcher) newMatcher(mode, term string) (valueMatcher, error) {
+	switch mode {
+	case ModeRegex:
+		pattern := term
+		if !s.opts.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", term, err)
+		}
+		return re.MatchString, nil
+	case ModeExact:
+		term = s.norm.term(term)
+		return func(value string) bool { return value == term }, nil
+	case ModeContains:
+		term = s.norm.term(term)
+		return func(value string) bool { return strings.Contains(value, term) }, nil
+	case ModeNear:
+		term, near, within := s.norm.term(term), s.norm.term(s.opts.Near), s.opts.Within
+		return func(value string) bool { return termsNear(value, term, near, within) }, nil
+	case ModeFuzzy:
+		term, threshold := s.norm.term(term), s.opts.Threshold
+		return func(value string) bool { return fuzzyMatch(value, term, threshold) }, nil
+	}
+	return nil, fmt.Errorf("unsupported mode %q (expected %s)", mode, strings.Join(Modes, ", "))
+}
+
+// Match reports whether a decoded finding matches, with the terms that matched
+func (s *Searcher) Match(data map[string]interface{}) ([]string, bool) {
+	if !s.passesFilters(data) {
+		return nil, false
+	}
+
+	var scope interface{} = data
+	if s.opts.Field != "" {
+		value, ok := Lookup(data, s.opts.Field)
+		if !ok {
+			return nil, false
+		}
+		scope = value
+	}
+
+	var matched []string
+	for i, matcher := range s.terms {
+		if s.valueMatches(scope, matcher) {
+			matched = append(matched, s.opts.Terms[i])
+		} else if s.opts.All {
+			return nil, false
+		}
+	}
+	if len(s.terms) > 0 && len(matched) == 0 {
+		return nil, false
+	}
+
+	for _, matcher := range s.not {
+		if s.valueMatches(scope, matcher) {
+			return nil, false
+		}
+	}
+	if s.query != nil && !s.query.eval(s, data) {
+		return nil, false
+	}
+	return matched, true
+}
+
+// Check reports whether a record matches, decoding it only when it can.
+// Records that cannot be decoded have Err set and never match.
+func (s *Searcher) Check(record *Record) ([]string, bool) {
+	if record.Err != nil {
+		return nil, false
+	}
+	if record.Data == nil && !s.candidate(record.Raw) {
+		// Entries skipped without decoding are still validated so broken ones are reported
+		if !validJSON(record.Raw) {
+			record.Decode()
+		}
+		return nil, false
+	}
+	if err := record.Decode(); err != nil {
+		return nil, false
+	}
+	return s.Match(record.Data)
+}
+
+// Search reads all of r and returns the matching findings in input order.
+// Entries that cannot be parsed are skipped; use Stream to observe them.
+func (s *Searcher) Search(ctx context.Context, r io.Reader) ([]Finding, error) {
+	var findings []Finding
+	err := s.Stream(ctx, r, ScanOptions{}, func(finding Finding) error {
+		findings = append(findings, finding)
+		return nil
+	}, nil)
+	return findings, err
+}
+
+// Stream searches r, calling fn for every matching finding and onError (if
+// set) for every entry that could not be parsed. Decoding and matching run on
+// opts.Workers goroutines; fn and onError are always called from the caller's
+// goroutine. Options.MaxLineBytes applies unless opts sets its own limit.
+func (s *Searcher) Stream(ctx context.Context, r io.Reader, opts ScanOptions, fn func(Finding) error, onError func(line int, err error)) error {
+	if opts.MaxLineBytes == 0 {
+		opts.MaxLineBytes = s.opts.MaxLineBytes
+	}
+	type outcome struct {
+		terms []string
+		ok    bool
+	}
+	return Scan(ctx, r, opts, func(record *Record) outcome {
+		terms, ok := s.Check(record)
+		return outcome{terms, ok}
+	}, func(record *Record, result outcome) error {
+		if record.Err != nil {
+			if onError != nil {
+				onError(record.Line, record.Err)
+			}
+			return nil
+		}
+		if !result.ok {
+			return nil
+		}
+		return fn(Finding{Line: record.Line, Data: record.Data, Terms: result.terms})
+	})
+}
+
+// FindingStream reads the matches of a search one at a time, on the
+// caller's goroutine, in the manner of bufio.Scanner:
+//
+//	stream := s.Findings(ctx, file)
+//	for stream.Next() {
+//		use(stream.Finding())
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+//
+// Entries that cannot be parsed are skipped and counted by Skipped.
+type FindingStream struct {
+	ctx     context.Context
+	s       *Searcher
+	decoder *Decoder
+	finding Finding
+	skipped int
+	err     error
+}
+
+// Findings returns a stream of the matches of r, read as they are asked for,
+// so a caller can stop early without reading the rest of the input
+func (s *Searcher) Findings(ctx context.Context, r io.Reader) *FindingStream {
+	return &FindingStream{ctx: ctx, s: s, decoder: NewDecoder(r, s.opts.MaxLineBytes)}
+}
+
+// Next reads up to the next match; false at the end of the input, when the
+// context is done or on a read error, told apart by Err
+func (f *FindingStream) Next() bool {
+	for f.err == nil {
+		if f.err = f.ctx.Err(); f.err != nil {
+			break
+		}
+		record, err := f.decoder.Next()
+		if err != nil {
+			if err != io.EOF {
+				f.err = err
+			}
+			return false
+		}
+		terms, ok := f.s.Check(record)
+		if record.Err != nil {
+			f.skipped++
+			continue
+		}
+		if ok {
+			f.finding = Finding{Line: record.Line, Data: record.Data, Terms: terms}
+			return true
+		}
+	}
+	return false
+}
+
+// Finding is the match read by the last call to Next
+func (f *FindingStream) Finding() Finding {
+	return f.finding
+}
+
+// Err is the error that ended the stream, nil at the end of the input
+func (f *FindingStream) Err() error {
+	return f.err
+}
+
+// Skipped is the number of entries that could not be parsed so far
+func (f *FindingStream) Skipped() int {
+	return f.skipped
+}
+
+// All ranges over the rest of the matches; breaking out of the loop stops
+// reading the input. Err tells afterwards why the loop ended:
+//
+//	stream := s.Findings(ctx, file)
+//	for finding := range stream.All() {
+//		use(finding)
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+func (f *FindingStream) All() iter.Seq[Finding] {
+	return func(yield func(Finding) bool) {
+		for f.Next() {
+			if !yield(f.finding) {
+				return
+			}
+		}
+	}
+}
+
+// Chan sends the rest of the matches on a channel from another goroutine and
+// closes it at the end of the input, when the context is done or on a read
+// error; Err may be read once it is closed. A receiver stopping early must
+// cancel the context so the goroutine ends.
+func (f *FindingStream) Chan() <-chan Finding {
+	findings := make(chan Finding)
+	go func() {
+		defer close(findings)
+		for f.Next() {
+			select {
+			case findings <- f.finding:
+			case <-f.ctx.Done():
+				f.err = f.ctx.Err()
+				return
+			}
+		}
+	}()
+	return findings
+}
+
+// Check the filters that do not depend on the search terms
+func (s *Searcher) passesFilters(data map[string]interface{}) bool {
+	if s.opts.Verified != nil {
+		verified, _ := data["Verified"].(bool)
+		if verified != *s.opts.Verified {
+			return false
+		}
+	}
+	if s.detectors != nil {
+		detector, _ := data["DetectorName"].(string)
+		if !s.detectors[strings.ToLower(detector)] {
+			return false
+		}
+	}
+	if s.detectorTypes != nil && !s.hasDetectorType(data) {
+		return false
+	}
+	if s.repos != nil && !s.inRepositories(data) {
+		return false
+	}
+	if s.orgs != nil && !s.orgs[strings.ToLower(Organization(RepositoryPath(repository(data))))] {
+		return false
+	}
+	for _, field := range s.opts.MissingFields {
+		if _, ok := Lookup(data, field); ok {
+			return false
+		}
+	}
+	if !s.opts.Since.IsZero() || !s.opts.Until.IsZero() {
+		value, _ := Lookup(data, "timestamp")
+		timestamp, ok := ParseTime(value)
+		if !ok {
+			return false
+		}
+		if !s.opts.Since.IsZero() && timestamp.Before(s.opts.Since) {
+			return false
+		}
+		if !s.opts.Until.IsZero() && timestamp.After(s.opts.Until) {
+			return false
+		}
+	}
+	if s.opts.MinEntropy > 0 || s.opts.MinLength > 0 || s.opts.MaxLength > 0 || s.opts.Charset != "" {
+		secret := Secret(data)
+		if secret == "" {
+			return false
+		}
+		if s.opts.MinLength > 0 && len(secret) < s.opts.MinLength {
+			return false
+		}
+		if s.opts.MaxLength > 0 && len(secret) > s.opts.MaxLength {
+			return false
+		}
+		if s.opts.MinEntropy > 0 && Entropy(secret) < s.opts.MinEntropy {
+			return false
+		}
+		if s.opts.Charset != "" && !MatchesCharset(secret, s.opts.Charset) {
+			return false
+		}
+	}
+	return true
+}
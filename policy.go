@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// Outcome of the Rego policy for one finding
+type policyDecision struct {
+	Allow    bool     `json:"allow"`              // accepted finding, left out of the results
+	Deny     bool     `json:"deny"`               // policy violation
+	Severity string   `json:"severity,omitempty"` // e.g. "critical"
+	Routes   []string `json:"routes,omitempty"`   // names of configured sinks receiving the finding
+}
+
+// Rego policy evaluated against every matched finding. The policy must be in
+// package trufflehog; the rules allow, deny, severity and route are read from it
+// with the finding as input.
+type findingPolicy struct {
+	query rego.PreparedEvalQuery
+}
+
+// Compile a Rego policy file
+func loadPolicy(path string) (*findingPolicy, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	query, err := rego.New(
+		rego.Query("data.trufflehog"),
+		rego.Module(path, string(source)),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &findingPolicy{query: query}, nil
+}
+
+// Evaluate the policy for one finding. Without a policy nothing is allowed or denied.
+func (p *findingPolicy) evaluate(ctx context.Context, data JSONData) (policyDecision, error) {
+	var decision policyDecision
+	if p == nil {
+		return decision, nil
+	}
+	results, err := p.query.Eval(ctx, rego.EvalInput(map[string]interface{}(data)))
+	if err != nil {
+		return decision, err
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return decision, nil
+	}
+	rules, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return decision, nil
+	}
+	decision.Allow, _ = rules["allow"].(bool)
+	decision.Deny, _ = rules["deny"].(bool)
+	decision.Severity, _ = rules["severity"].(string)
+	// route may be a single name or a set of names
+	switch route := rules["route"].(type) {
+	case string:
+		decision.Routes = []string{route}
+	case []interface{}:
+		for _, name := range route {
+			if name, ok := name.(string); ok {
+				decision.Routes = append(decision.Routes, name)
+			}
+		}
+		sort.Strings(decision.Routes)
+	}
+	return decision, nil
+}
+
+// Summary line printed with a finding the policy did not allow
+func (d policyDecision) String() string {
+	parts := []string{"pass"}
+	if d.Deny {
+		parts[0] = "deny"
+	}
+	if d.Severity != "" {
+		parts = append(parts, "severity "+d.Severity)
+	}
+	if len(d.Routes) > 0 {
+		parts = append(parts, "route "+strings.Join(d.Routes, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Run saved queries, the presets of the configuration file, in a single pass
+// over the input: every finding is read and parsed once for all of them. With
+// several profiles each writes to its own file, named after it unless its
+// preset sets output-file.
+func runProfiles(args []string) {
+	extra := func(fs *flag.FlagSet) {
+		presets := fs.Lookup("preset").Value
+		fs.Func("profiles", "Comma-separated presets run together, e.g. aws-verified,slack-tokens (required)", func(value string) error {
+			for _, name := range splitList(value) {
+				if err := presets.Set(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		fs.Usage = func() {
+			fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher run -profiles NAME[,NAME...] -i INPUT [search flags]")
+			fmt.Fprintln(fs.Output(), "e.g. trufflehog-searcher run -profiles aws-verified,slack-tokens,db-passwords -i results/ -r")
+			fs.PrintDefaults()
+		}
+	}
+
+	// Check for profiles before searching; parse errors are reported by the search itself
+	probe := flag.NewFlagSet("run", flag.ContinueOnError)
+	probe.SetOutput(io.Discard)
+	f := registerSearchFlags(probe)
+	extra(probe)
+	if err := probe.Parse(args); err == nil && len(f.presets) == 0 {
+		fmt.Println("Error: -profiles is a required parameter.")
+		probe.SetOutput(os.Stderr)
+		probe.Usage()
+		os.Exit(1)
+	}
+
+	os.Exit(runSearch("run", args, extra, nil))
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters shared by the workers, read by -progress and -metrics-json
+type runMetrics struct {
+	filesTotal  atomic.Int64
+	filesDone   atomic.Int64
+	lines       atomic.Int64
+	matches     atomic.Int64
+	parseErrors atomic.Int64
+	bytes       atomic.Int64 // raw bytes read, before decompression
+	bytesTotal  atomic.Int64 // size of the sources with a known size
+	unsized     atomic.Int64 // sources of unknown size; the ETA needs every size
+	listing     atomic.Bool  // sources are still being listed, so the totals are not final
+	start       time.Time
+
+	mu      sync.Mutex
+	files   []*fileMetrics
+	workers []*workerMetrics
+	active  map[*fileMetrics]bool // sources being searched
+}
+
+// Counters of one worker of the pool
+type workerMetrics struct {
+	files   atomic.Int64
+	lines   atomic.Int64
+	bytes   atomic.Int64
+	matches atomic.Int64
+	busy    atomic.Int64 // nanoseconds spent searching sources
+}
+
+// Statistics of one searched source
+type fileMetrics struct {
+	Name        string `json:"file"`
+	Bytes       int64  `json:"bytes"`
+	Lines       int64  `json:"lines"`
+	Matches     int64  `json:"matches"`
+	ParseErrors int64  `json:"parse_errors"`
+	DurationMS  int64  `json:"duration_ms"`
+	Error       string `json:"error,omitempty"`
+	start       time.Time
+	size        int64
+	read        atomic.Int64   // Bytes, readable while the source is searched
+	worker      *workerMetrics // nil outside of the worker pool
+}
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{start: time.Now(), active: map[*fileMetrics]bool{}}
+}
+
+// Count the sources to search and their size
+func (r *runMetrics) addSources(sources []inputSource) {
+	r.filesTotal.Add(int64(len(sources)))
+	for _, src := range sources {
+		if src.size > 0 {
+			r.bytesTotal.Add(src.size)
+		} else {
+			r.unsized.Add(1)
+		}
+	}
+}
+
+// Create the counters of n workers
+func (r *runMetrics) startWorkers(n int) []*workerMetrics {
+	workers := make([]*workerMetrics, n)
+	for i := range workers {
+		workers[i] = &workerMetrics{}
+	}
+	r.mu.Lock()
+	r.workers = workers
+	r.mu.Unlock()
+	return workers
+}
+
+// Start timing a source. Its counters belong to the worker until finishFile.
+func (r *runMetrics) startFile(src inputSource, worker *workerMetrics) *fileMetrics {
+	f := &fileMetrics{Name: src.name, start: time.Now(), size: src.size, worker: worker}
+	r.mu.Lock()
+	r.active[f] = true
+	r.mu.Unlock()
+	return f
+}
+
+func (r *runMetrics) finishFile(f *fileMetrics) {
+	// Zip archives are read at random and failed reads stop early, so the
+	// rest of the source is counted once it is done to keep the ETA right
+	if f.Bytes < f.size {
+		r.addBytes(f, int(f.size-f.Bytes))
+	}
+	elapsed := time.Since(f.start)
+	f.DurationMS = elapsed.Milliseconds()
+	r.filesDone.Add(1)
+	if f.worker != nil {
+		f.worker.files.Add(1)
+		f.worker.busy.Add(int64(elapsed))
+	}
+	r.mu.Lock()
+	r.files = append(r.files, f)
+	delete(r.active, f)
+	r.mu.Unlock()
+}
+
+func (r *runMetrics) addLines(f *fileMetrics, n int64) {
+	f.Lines += n
+	r.lines.Add(n)
+	if f.worker != nil {
+		f.worker.lines.Add(n)
+	}
+}
+
+func (r *runMetrics) addMatch(f *fileMetrics) {
+	f.Matches++
+	r.matches.Add(1)
+	if f.worker != nil {
+		f.worker.matches.Add(1)
+	}
+}
+
+func (r *runMetrics) addBytes(f *fileMetrics, n int) {
+	f.Bytes += int64(n)
+	f.read.Add(int64(n))
+	r.bytes.Add(int64(n))
+	if f.worker != nil {
+		f.worker.bytes.Add(int64(n))
+	}
+}
+
+// Wrap the opening of a source so the raw bytes read from it are counted.
+// Zip archives are left alone: they need the *os.File to read at random.
+func (r *runMetrics) countBytes(src inputSource, f *fileMetrics) func(ctx context.Context) (io.ReadCloser, error) {
+	if strings.HasSuffix(src.name, ".zip") {
+		return src.open
+	}
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		raw, err := src.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &countingReader{ReadCloser: raw, metrics: r, file: f}, nil
+	}
+}
+
+// Counts the bytes read through it
+type countingReader struct {
+	io.ReadCloser
+	metrics *runMetrics
+	file    *fileMetrics
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.metrics.addBytes(c.file, n)
+	return n, err
+}
+
+// Lines and megabytes per second over a duration
+func throughput(lines, bytes int64, elapsed time.Duration) (float64, float64) {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+	return float64(lines) / seconds, float64(bytes) / 1e6 / seconds
+}
+
+// Share of total done, at most 100
+func percent(done, total int64) float64 {
+	return min(100, float64(done)*100/float64(total))
+}
+
+// Time left to read the remaining bytes at the current rate; false while
+// listing, when some source has no known size, nothing was read yet or under
+// a second is left
+func (r *runMetrics) eta() (time.Duration, bool) {
+	read, total := r.bytes.Load(), r.bytesTotal.Load()
+	elapsed := time.Since(r.start)
+	if r.listing.Load() || r.unsized.Load() > 0 || total == 0 || read == 0 || read >= total {
+		return 0, false
+	}
+	remaining := time.Duration(float64(elapsed) * float64(total-read) / float64(read)).Round(time.Second)
+	return remaining, remaining > 0
+}
+
+// One status line: files done/total, lines parsed, matches, parse errors,
+// throughput overall and per worker, and the ETA
+func (r *runMetrics) status() string {
+	elapsed := time.Since(r.start)
+	lineRate, byteRate := throughput(r.lines.Load(), r.bytes.Load(), elapsed)
+	line := fmt.Sprintf("files %d/%d, lines %d, matches %d, parse errors %d, %.0f lines/s, %.1f MB/s",
+		r.filesDone.Load(), r.filesTotal.Load(), r.lines.Load(), r.matches.Load(), r.parseErrors.Load(), lineRate, byteRate)
+	read, total := r.bytes.Load(), r.bytesTotal.Load()
+	if total > 0 && r.unsized.Load() == 0 && !r.listing.Load() {
+		line += fmt.Sprintf(", %.1f/%.1f MB (%.0f%%)", float64(read)/1e6, float64(total)/1e6, percent(read, total))
+	}
+	if eta, ok := r.eta(); ok {
+		line += fmt.Sprintf(", ETA %s", eta)
+	}
+	r.mu.Lock()
+	workers := r.workers
+	var largest *fileMetrics
+	for f := range r.active {
+		if largest == nil || f.size > largest.size {
+			largest = f
+		}
+	}
+	r.mu.Unlock()
+	// A file holding a large share of the input, whose progress the file count does not show
+	if largest != nil && largest.size > 0 && largest.size*10 >= total {
+		fileRead := largest.read.Load()
+		line += fmt.Sprintf(", %s %.1f/%.1f MB (%.0f%%)", largest.Name, float64(fileRead)/1e6, float64(largest.size)/1e6, percent(fileRead, largest.size))
+	}
+	if len(workers) > 1 {
+		rates := make([]string, len(workers))
+		for i, w := range workers {
+			_, rate := throughput(0, w.bytes.Load(), elapsed)
+			rates[i] = fmt.Sprintf("%.1f", rate)
+		}
+		line += fmt.Sprintf(" [workers MB/s: %s]", strings.Join(rates, " "))
+	}
+	return line
+}
+
+// Final throughput of the run, e.g. for the summary
+func (r *runMetrics) throughputSummary() string {
+	elapsed := time.Since(r.start)
+	lineRate, byteRate := throughput(r.lines.Load(), r.bytes.Load(), elapsed)
+	return fmt.Sprintf("%.1f MB in %s, %.0f lines/s, %.1f MB/s",
+		float64(r.bytes.Load())/1e6, elapsed.Round(time.Millisecond), lineRate, byteRate)
+}
+
+// Rewrite the status line on w every interval until the returned function is called
+func (r *runMetrics) reportProgress(w io.Writer, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Fprintf(w, "\r\033[K%s", r.status())
+			case <-done:
+				fmt.Fprintf(w, "\r\033[K%s\n", r.status())
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Totals of one worker of the pool, for -metrics-json and -stats-verbose
+type workerStats struct {
+	Worker         int     `json:"worker"`
+	Files          int64   `json:"files"`
+	Lines          int64   `json:"lines"`
+	Bytes          int64   `json:"bytes"`
+	Matches        int64   `json:"matches"`
+	BusyMS         int64   `json:"busy_ms"`
+	BusyPercent    float64 `json:"busy_percent"` // share of the run spent searching
+	LinesPerSecond float64 `json:"lines_per_second"`
+	MBPerSecond    float64 `json:"mb_per_second"`
+}
+
+func (r *runMetrics) workerStats() []workerStats {
+	r.mu.Lock()
+	pool := r.workers
+	r.mu.Unlock()
+	elapsed := time.Since(r.start)
+	workers := make([]workerStats, len(pool))
+	for i, w := range pool {
+		busy := time.Duration(w.busy.Load())
+		workers[i] = workerStats{
+			Worker: i + 1, Files: w.files.Load(), Lines: w.lines.Load(), Bytes: w.bytes.Load(), Matches: w.matches.Load(),
+			BusyMS: busy.Milliseconds(), BusyPercent: percent(int64(busy), int64(elapsed)),
+		}
+		workers[i].LinesPerSecond, workers[i].MBPerSecond = throughput(workers[i].Lines, workers[i].Bytes, elapsed)
+	}
+	return workers
+}
+
+// Write the totals and per-file statistics as JSON, files in name order
+func (r *runMetrics) writeJSON(path string) error {
+	r.mu.Lock()
+	files := append([]*fileMetrics(nil), r.files...)
+	r.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	elapsed := time.Since(r.start)
+	lineRate, byteRate := throughput(r.lines.Load(), r.bytes.Load(), elapsed)
+	report := map[string]interface{}{
+		"files":            r.filesDone.Load(),
+		"lines":            r.lines.Load(),
+		"bytes":            r.bytes.Load(),
+		"matches":          r.matches.Load(),
+		"parse_errors":     r.parseErrors.Load(),
+		"duration_ms":      elapsed.Milliseconds(),
+		"lines_per_second": lineRate,
+		"mb_per_second":    byteRate,
+		"per_worker":       r.workerStats(),
+		"per_file":         files,
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Delete findings past their retention period from the database
+func runDBPurge(args []string) {
+	fs := flag.NewFlagSet("db purge", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	configPath := fs.String("config", defaultConfigPath(), "Path of the configuration file holding retention settings")
+	olderThan := fs.String("older-than", "", "Purge findings imported longer ago than this age (e.g. 180d), overriding the configured retention")
+	corpusName := fs.String("corpus", "", "Only purge this corpus")
+	dryRun := fs.Bool("dry-run", false, "Report what would be purged without deleting anything")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	lock, err := lockDB(context.Background(), *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	corpora, err := listCorpora(db)
+	if err != nil {
+		fmt.Printf("Error reading database: %v\n", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	total := 0
+	for _, name := range corpora {
+		if *corpusName != "" && name != *corpusName {
+			continue
+		}
+
+		// An explicit --older-than wins over the per-corpus and default retention
+		retention := *olderThan
+		if retention == "" {
+			retention = cfg.Retention.Corpora[name]
+		}
+		if retention == "" {
+			retention = cfg.Retention.Default
+		}
+		if retention == "" {
+			continue
+		}
+		age, err := parseAge(retention)
+		if err != nil {
+			fmt.Printf("Error: corpus %s: %v\n", name, err)
+			os.Exit(1)
+		}
+
+		cutoff := now.Add(-age).Unix()
+		purged, err := purgeCorpus(db, name, cutoff, *dryRun)
+		if err != nil {
+			fmt.Printf("Error purging corpus %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		if purged > 0 {
+			fmt.Printf("Corpus %s: %d findings older than %s\n", name, purged, retention)
+		}
+		total += purged
+	}
+
+	// Cached search results and named baselines hold findings too; they follow the default retention
+	cached := 0
+	var baselines []string
+	if retention := *olderThan; *corpusName == "" {
+		if retention == "" {
+			retention = cfg.Retention.Default
+		}
+		if retention != "" {
+			age, err := parseAge(retention)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if cached, err = purgeResultCache(db, now.Add(-age).Unix(), *dryRun); err != nil {
+				fmt.Printf("Error purging cached results: %v\n", err)
+				os.Exit(1)
+			}
+			if cached > 0 {
+				fmt.Printf("Search cache: %d cached results older than %s\n", cached, retention)
+			}
+			if baselines, err = purgeBaselines(db, now.Add(-age).Unix(), *dryRun); err != nil {
+				fmt.Printf("Error purging baselines: %v\n", err)
+				os.Exit(1)
+			}
+			for _, name := range baselines {
+				fmt.Printf("Baseline %s: created longer ago than %s\n", name, retention)
+			}
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("Would purge %d findings, %d cached results and %d baselines (dry run)\n", total, cached, len(baselines))
+		return
+	}
+	if total > 0 || cached > 0 || len(baselines) > 0 {
+		// Rebuild the file so deleted secret material does not linger in free pages
+		if _, err := db.Exec(`VACUUM`); err != nil {
+			fmt.Printf("Error compacting database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Purged %d findings\n", total)
+}
+
+// List the corpus names present in the database
+func listCorpora(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT corpus FROM imported_files UNION SELECT corpus FROM findings ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var corpora []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		corpora = append(corpora, name)
+	}
+	return corpora, rows.Err()
+}
+
+// Delete a corpus' findings and file records imported before the cutoff, returning the number of findings
+func purgeCorpus(db *sql.DB, corpusName string, cutoff int64, dryRun bool) (int, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM findings WHERE corpus = ? AND imported_at < ?`, corpusName, cutoff).Scan(&count); err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return count, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM findings WHERE corpus = ? AND imported_at < ?`, corpusName, cutoff); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM imported_files WHERE corpus = ? AND imported_at < ?`, corpusName, cutoff); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// Delete the per-file results and whole outputs of -cache stored before the
+// cutoff, and the hashes of files no cached result refers to any more,
+// returning the number of results
+func purgeResultCache(db *sql.DB, cutoff int64, dryRun bool) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT (SELECT COUNT(*) FROM result_lines WHERE created_at < ?) + (SELECT COUNT(*) FROM query_cache WHERE created_at < ?)`, cutoff, cutoff).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return count, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM result_lines WHERE created_at < ?`, cutoff); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM query_cache WHERE created_at < ?`, cutoff); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM file_hashes WHERE hash NOT IN (SELECT file_hash FROM result_lines)`); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// Delete the named baselines created before the cutoff, returning their names
+func purgeBaselines(db *sql.DB, cutoff int64, dryRun bool) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM baselines WHERE created_at < ? ORDER BY name`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || dryRun || len(names) == 0 {
+		return names, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, name := range names {
+		if _, err := deleteBaseline(tx, name); err != nil {
+			return nil, err
+		}
+	}
+	return names, tx.Commit()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Points a -policy severity adds to the risk of a match
+var severityRisk = map[string]float64{"critical": 30, "high": 20, "medium": 10, "low": 5}
+
+// Risk of a match for -rank, from 0 to 100: a verified secret weighs the
+// most, then the severity a -policy gave it and how random the secret looks.
+// Likely false positives and third-party code are discounted.
+func riskScore(m *searchMatch) float64 {
+	score := 0.0
+	if verified, _ := m.data["Verified"].(bool); verified {
+		score += 50
+	}
+	score += severityRisk[strings.ToLower(m.decision.Severity)]
+	if m.decision.Deny {
+		score += 5
+	}
+	// Up to 15 for a secret as random as base64, 6 bits per character
+	score += min(15, searcher.Entropy(searcher.Secret(m.data))*2.5)
+	score *= 1 - m.falsePositive.Score
+	if m.thirdParty != "" {
+		score /= 2
+	}
+	// Rounded so equal secrets tie however the entropy was summed, and ties keep input order
+	return math.Round(score*100) / 100
+}
+
+// The k riskiest matches seen so far, for -top with -rank. The heap holds
+// the least risky of them at its root, so each match costs O(log k) and the
+// rest of the matches are never kept.
+type topMatches struct {
+	k       int
+	matches []rankedMatch
+	next    int
+}
+
+type rankedMatch struct {
+	*searchMatch
+	seq int // arrival order, to break ties
+}
+
+// Whether a ranks before b: a higher score, or the same score found earlier
+func (a rankedMatch) riskier(b rankedMatch) bool {
+	if a.risk != b.risk {
+		return a.risk > b.risk
+	}
+	return a.seq < b.seq
+}
+
+func newTopMatches(k int) *topMatches {
+	return &topMatches{k: k}
+}
+
+func (t *topMatches) add(m *searchMatch) {
+	m.risk = riskScore(m)
+	ranked := rankedMatch{m, t.next}
+	t.next++
+	if len(t.matches) < t.k {
+		heap.Push(t, ranked)
+	} else if ranked.riskier(t.matches[0]) {
+		t.matches[0] = ranked
+		heap.Fix(t, 0)
+	}
+}
+
+// The kept matches, riskiest first
+func (t *topMatches) sorted() []*searchMatch {
+	ranked := append([]rankedMatch(nil), t.matches...)
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].riskier(ranked[j]) })
+	matches := make([]*searchMatch, len(ranked))
+	for i, m := range ranked {
+		matches[i] = m.searchMatch
+	}
+	return matches
+}
+
+func (t *topMatches) Len() int           { return len(t.matches) }
+func (t *topMatches) Less(i, j int) bool { return t.matches[j].riskier(t.matches[i]) }
+func (t *topMatches) Swap(i, j int)      { t.matches[i], t.matches[j] = t.matches[j], t.matches[i] }
+func (t *topMatches) Push(x interface{}) { t.matches = append(t.matches, x.(rankedMatch)) }
+func (t *topMatches) Pop() interface{} {
+	last := t.matches[len(t.matches)-1]
+	t.matches = t.matches[:len(t.matches)-1]
+	return last
+}
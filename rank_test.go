@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRiskScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		match searchMatch
+		want  float64
+	}{
+		{"empty secret", searchMatch{data: JSONData{}}, 0},
+		{"verified", searchMatch{data: JSONData{"Verified": true}}, 50},
+		{"policy severity", searchMatch{data: JSONData{}, decision: policyDecision{Severity: "Critical"}}, 30},
+		{"policy denial", searchMatch{data: JSONData{}, decision: policyDecision{Deny: true, Severity: "low"}}, 10},
+		{"repeated character", searchMatch{data: JSONData{"Raw": "aaaaaaaa"}}, 0},
+		{"random secret", searchMatch{data: JSONData{"Raw": "abcdefgh"}}, 7.5},
+		{"entropy capped", searchMatch{data: JSONData{"Raw": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"}}, 15},
+		{"likely false positive", searchMatch{data: JSONData{"Verified": true}, falsePositive: fpScore{Score: 0.5}}, 25},
+		{"third-party code", searchMatch{data: JSONData{"Verified": true}, thirdParty: "vendor/**"}, 25},
+		{"everything", searchMatch{data: JSONData{"Verified": true, "Raw": "abcdefgh"}, decision: policyDecision{Severity: "high"}, falsePositive: fpScore{Score: 0.2}}, 62},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := riskScore(&tt.match); got != tt.want {
+				t.Errorf("riskScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopMatches(t *testing.T) {
+	// Lines of matches with their secret; equal secrets tie and keep input order
+	secrets := []string{"aaaa", "abcdefgh", "ab", "abcd", "abcdefgh", "aaaa", "abcdefghijklmnop"}
+	tests := []struct {
+		k    int
+		want []int
+	}{
+		{1, []int{7}},
+		{3, []int{7, 2, 5}},
+		{5, []int{7, 2, 5, 4, 3}},
+		{10, []int{7, 2, 5, 4, 3, 1, 6}},
+	}
+	for _, tt := range tests {
+		top := newTopMatches(tt.k)
+		for i, secret := range secrets {
+			top.add(&searchMatch{line: i + 1, data: JSONData{"Raw": secret}})
+		}
+		var got []int
+		for _, m := range top.sorted() {
+			got = append(got, m.line)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("top %d = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Number of goroutines each zstd decoder may use, independent from the file workers
+var decompressThreads = 1
+
+// Goroutines decompressing streams ahead of their parser, shared by every
+// file; a stream opened while all of them are busy is decompressed by the
+// goroutine parsing it
+var decompressWorkers = runtime.NumCPU()
+
+var (
+	decompressSlots     chan struct{}
+	decompressSlotsOnce sync.Once
+)
+
+// Decompressed chunks held for the parser by each read-ahead goroutine
+const (
+	readAheadChunks    = 4
+	readAheadChunkSize = 256 << 10
+)
+
+// Compression formats recognized by extension and by their magic bytes
+var compressionFormats = []struct {
+	ext   string
+	magic []byte
+}{
+	{".gz", []byte{0x1f, 0x8b}},
+	{".zst", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{".xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// Strip a compression extension from a file name
+func trimCompressionExt(name string) string {
+	for _, format := range compressionFormats {
+		if strings.HasSuffix(name, format.ext) {
+			return strings.TrimSuffix(name, format.ext)
+		}
+	}
+	return name
+}
+
+// List the trufflehog output files inside a directory. A path to a single file is returned as is.
+func listInputFiles(dir string) ([]string, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return []string{dir}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(trimCompressionExt(entry.Name()))
+		if !entry.IsDir() && (ext == ".json" || ext == ".jsonl") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Stream with its decompressor, closing both together
+type inputFile struct {
+	io.Reader
+	source       io.Closer
+	decompressor io.Closer
+	ahead        *readAhead
+}
+
+func (f *inputFile) Close() error {
+	if f.ahead == nil {
+		if f.decompressor != nil {
+			f.decompressor.Close()
+		}
+		return f.source.Close()
+	}
+	// Closing the source before waiting for the read-ahead goroutine ends its
+	// read when it is blocked on a FIFO, a followed file or a stalled remote
+	// stream. The goroutine must be done with the decompressor before it is closed.
+	f.ahead.stop()
+	err := f.source.Close()
+	f.ahead.wait()
+	if f.decompressor != nil {
+		f.decompressor.Close()
+	}
+	return err
+}
+
+// Open an input file, transparently decompressing gzip, zstd and xz content
+func openInput(filePath string) (io.ReadCloser, error) {
+	fileHandle, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(fileHandle, filePath)
+}
+
+// Decompress a stream named name. The format is selected by extension, falling back to the magic bytes.
+// Closing the result closes r.
+func decompress(r io.ReadCloser, name string) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(6)
+
+	format := ""
+	for _, candidate := range compressionFormats {
+		if strings.HasSuffix(name, candidate.ext) {
+			format = candidate.ext
+		}
+	}
+	if format == "" {
+		for _, candidate := range compressionFormats {
+			if bytes.HasPrefix(head, candidate.magic) {
+				format = candidate.ext
+			}
+		}
+	}
+
+	input := &inputFile{Reader: buffered, source: r}
+	switch format {
+	case ".gz":
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		input.Reader, input.decompressor = gz, gz
+	case ".zst":
+		decoder, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(decompressThreads))
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		input.Reader, input.decompressor = decoder, decoder.IOReadCloser()
+	case ".xz":
+		xzReader, err := xz.NewReader(buffered)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		input.Reader = xzReader
+	}
+	if format != "" && acquireDecompressSlot() {
+		input.ahead = newReadAhead(input.Reader)
+		input.Reader = input.ahead
+	}
+	return input, nil
+}
+
+// Take one of the -decompress-workers slots, if one is free
+func acquireDecompressSlot() bool {
+	decompressSlotsOnce.Do(func() {
+		decompressSlots = make(chan struct{}, max(decompressWorkers, 0))
+	})
+	select {
+	case decompressSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Decompresses a stream on its own goroutine, a few chunks ahead of the
+// parser reading it, so a file is decompressed and parsed at the same time
+type readAhead struct {
+	chunks  chan []byte
+	free    chan []byte // consumed chunks, reused by the goroutine
+	done    chan struct{}
+	exited  chan struct{}
+	err     error  // read error of the stream, set before chunks is closed
+	chunk   []byte // being consumed
+	current []byte // the rest of chunk to return
+}
+
+func newReadAhead(r io.Reader) *readAhead {
+	ra := &readAhead{
+		chunks: make(chan []byte, readAheadChunks),
+		free:   make(chan []byte, readAheadChunks+1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go ra.fill(r)
+	return ra
+}
+
+func (ra *readAhead) fill(r io.Reader) {
+	defer func() { <-decompressSlots }()
+	defer close(ra.exited)
+	defer close(ra.chunks)
+	for {
+		select {
+		case <-ra.done:
+			return
+		default:
+		}
+		var buf []byte
+		select {
+		case buf = <-ra.free:
+		default:
+			buf = make([]byte, readAheadChunkSize)
+		}
+		// A single read, so lines appended to a followed file are not held back
+		n, err := r.Read(buf)
+		if n > 0 {
+			select {
+			case ra.chunks <- buf[:n]:
+			case <-ra.done:
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				ra.err = err
+			}
+			return
+		}
+	}
+}
+
+func (ra *readAhead) Read(p []byte) (int, error) {
+	for len(ra.current) == 0 {
+		if ra.chunk != nil {
+			select {
+			case ra.free <- ra.chunk[:cap(ra.chunk)]:
+			default:
+			}
+			ra.chunk = nil
+		}
+		chunk, ok := <-ra.chunks
+		if !ok {
+			if ra.err != nil {
+				return 0, ra.err
+			}
+			return 0, io.EOF
+		}
+		ra.chunk, ra.current = chunk, chunk
+	}
+	n := copy(p, ra.current)
+	ra.current = ra.current[n:]
+	return n, nil
+}
+
+// Tell the goroutine to stop once the parser is done reading. It exits as
+// soon as its read in progress returns.
+func (ra *readAhead) stop() {
+	close(ra.done)
+}
+
+func (ra *readAhead) wait() {
+	<-ra.exited
+}
+
+// Parse trufflehog output from r, calling handle for every finding. JSON lines,
+// arrays and {"results": [...]} envelopes are recognized, see searcher.Decoder.
+// Entries that are not valid JSON are reported to onParseError (if set) and skipped.
+func scanFindings(r io.Reader, handle func(lineNum int, data JSONData), onParseError func(lineNum int, err error)) error {
+	return scanRecords(r, func(record *searcher.Record) {
+		handle(record.Line, record.Data)
+	}, onParseError)
+}
+
+// Like scanFindings, handing over the decoded records with their raw JSON
+func scanRecords(r io.Reader, handle func(record *searcher.Record), onParseError func(lineNum int, err error)) error {
+	decoder := searcher.NewDecoder(r, 0)
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := record.Decode(); err != nil {
+			if onParseError != nil {
+				onParseError(record.Line, err)
+			}
+			continue
+		}
+		handle(record)
+	}
+}
+
+// Unwrap a line of the tool's own result output (see resultRecord)
+func unwrapResult(data JSONData) (resultRecord, bool) {
+	finding, ok := data["finding"].(map[string]interface{})
+	if !ok {
+		return resultRecord{}, false
+	}
+	sourceFile, _ := data["source_file"].(string)
+	sourceLine, _ := data["source_line"].(float64)
+	fp, _ := data["fingerprint"].(string)
+	return resultRecord{SourceFile: sourceFile, SourceLine: int(sourceLine), Finding: finding, Fingerprint: fp}, true
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/iterator"
+)
+
+// Endpoint and addressing of S3-compatible stores, set by -s3-endpoint and
+// -s3-path-style; the AWS defaults when empty
+var (
+	s3Endpoint  string
+	s3PathStyle bool
+)
+
+// Whether -i points at a remote location
+func isRemote(input string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "azblob://", "sftp://", "http://", "https://"} {
+		if strings.HasPrefix(input, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// List the objects below a remote location, using the standard credential chain of each provider
+func listRemote(ctx context.Context, input string, filter inputFilter) ([]inputSource, error) {
+	location, err := url.Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	switch location.Scheme {
+	case "s3":
+		return listS3(ctx, location.Host, strings.TrimPrefix(location.Path, "/"), filter)
+	case "gs":
+		return listGCS(ctx, location.Host, strings.TrimPrefix(location.Path, "/"), filter)
+	case "azblob":
+		return listAzureBlob(ctx, location.Host, strings.TrimPrefix(location.Path, "/"), filter)
+	case "sftp":
+		return listSFTP(ctx, location, filter)
+	}
+	return []inputSource{httpSource(input, path.Base(location.Path))}, nil
+}
+
+// A single file served over HTTP(S)
+func httpSource(rawURL, name string) inputSource {
+	if name == "" || name == "/" || name == "." {
+		name = rawURL
+	}
+	return inputSource{name: name, open: func(ctx context.Context) (io.ReadCloser, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode >= 300 {
+			response.Body.Close()
+			return nil, fmt.Errorf("GET %s: %s", rawURL, response.Status)
+		}
+		return response.Body, nil
+	}}
+}
+
+// Objects of an S3 bucket below a prefix, or the object named by the prefix itself
+func listS3(ctx context.Context, bucket, prefix string, filter inputFilter) ([]inputSource, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg, func(options *s3.Options) {
+		if s3Endpoint != "" {
+			options.BaseEndpoint = aws.String(s3Endpoint)
+		}
+		options.UsePathStyle = s3PathStyle
+	})
+	open := func(key string) func(context.Context) (io.ReadCloser, error) {
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			object, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+			if err != nil {
+				return nil, err
+			}
+			return object.Body, nil
+		}
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		if head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(prefix)}); err == nil {
+			return []inputSource{{name: path.Base(prefix), size: aws.ToInt64(head.ContentLength), open: open(prefix)}}, nil
+		}
+		prefix += "/"
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	if !filter.recursive {
+		input.Delimiter = aws.String("/")
+	}
+	var sources []inputSource
+	pages := s3.NewListObjectsV2Paginator(client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if source, ok := remoteSource(key, prefix, filter); ok {
+				source.open = open(key)
+				source.size = aws.ToInt64(object.Size)
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// Objects of a Google Cloud Storage bucket below a prefix, or the object named by the prefix itself
+func listGCS(ctx context.Context, bucket, prefix string, filter inputFilter) ([]inputSource, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	handle := client.Bucket(bucket)
+	open := func(key string) func(context.Context) (io.ReadCloser, error) {
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			return handle.Object(key).NewReader(ctx)
+		}
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		if attrs, err := handle.Object(prefix).Attrs(ctx); err == nil {
+			return []inputSource{{name: path.Base(prefix), size: attrs.Size, open: open(prefix)}}, nil
+		}
+		prefix += "/"
+	}
+
+	query := &storage.Query{Prefix: prefix}
+	if !filter.recursive {
+		query.Delimiter = "/"
+	}
+	var sources []inputSource
+	objects := handle.Objects(ctx, query)
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if attrs.Name == "" {
+			continue // a common prefix standing for a "directory"
+		}
+		if source, ok := remoteSource(attrs.Name, prefix, filter); ok {
+			source.open = open(attrs.Name)
+			source.size = attrs.Size
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// Blobs of an Azure Storage container below a prefix, or the blob named by
+// the prefix itself. The account is taken from AZURE_STORAGE_ACCOUNT and
+// credentials from the Azure SDK chain: environment, workload or managed
+// identity, then the Azure CLI login.
+func listAzureBlob(ctx context.Context, containerName, prefix string, filter inputFilter) ([]inputSource, error) {
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return nil, errors.New("azblob:// needs the storage account in AZURE_STORAGE_ACCOUNT")
+	}
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", account), credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	open := func(name string) func(context.Context) (io.ReadCloser, error) {
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			blob, err := client.DownloadStream(ctx, containerName, name, nil)
+			if err != nil {
+				return nil, err
+			}
+			return blob.Body, nil
+		}
+	}
+
+	// Blob storage has no directories: a prefix naming a blob is that blob,
+	// otherwise it stands for the blobs below prefix/
+	dir := prefix
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	var sources []inputSource
+	pages := client.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pages.More() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range page.Segment.BlobItems {
+			name := *blob.Name
+			var size int64
+			if blob.Properties != nil && blob.Properties.ContentLength != nil {
+				size = *blob.Properties.ContentLength
+			}
+			if name == prefix && dir != prefix {
+				return []inputSource{{name: path.Base(name), size: size, open: open(name)}}, nil
+			}
+			// Without -r only the blobs directly below the prefix are searched
+			if !strings.HasPrefix(name, dir) || (!filter.recursive && strings.Contains(strings.TrimPrefix(name, dir), "/")) {
+				continue
+			}
+			if source, ok := remoteSource(name, dir, filter); ok {
+				source.open = open(name)
+				source.size = size
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// Name an object relative to the listed prefix, keeping only trufflehog output the filter accepts
+func remoteSource(key, prefix string, filter inputFilter) (inputSource, bool) {
+	rel := strings.TrimPrefix(key, prefix)
+	if rel == "" || !(isFindingsFile(rel) || isArchive(rel)) || !filter.accepts(rel) {
+		return inputSource{}, false
+	}
+	return inputSource{name: rel}, true
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Help text for the interactive session
+const replHelp = `Enter a search term to search the loaded corpus, or a command:
+  :mode <mode>                 set the search mode: exact, contains, near, regex or fuzzy
+  :field <name>               search only this field (no name searches everything)
+  :near <term>                second term for the near mode
+  :within <n>                 maximum distance between the near terms
+  :normalize on|off           toggle whitespace/quote normalization
+  :show                       print the current settings
+  :help                       print this help
+  :quit                       leave the session`
+
+// Load the corpus once and answer successive queries interactively
+func runREPL(args []string) {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	inDir := fs.String("i", "", "Input directory containing JSON trufflehog output files (required)")
+	showSecrets := fs.Bool("show-secrets", false, "Print secret values in full instead of masking them")
+	fs.Parse(args)
+
+	if *inDir == "" {
+		fmt.Println("Error: -i is a required parameter.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	loaded, err := loadCorpus(*inDir)
+	if err != nil {
+		fmt.Printf("Error reading directory: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Loaded %d findings from %d files in %s. Type :help for commands.\n", loaded.findings, len(loaded.files), time.Since(start).Round(time.Millisecond))
+
+	redact := searcher.DefaultRedactFields
+	if *showSecrets {
+		redact = nil
+	}
+	mode, field, near, within, normalize := searcher.ModeContains, "", "", 50, false
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !input.Scan() {
+			fmt.Println()
+			return
+		}
+		line := strings.TrimSpace(input.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			command, argument, _ := strings.Cut(line, " ")
+			argument = strings.TrimSpace(argument)
+			switch command {
+			case ":quit", ":q", ":exit":
+				return
+			case ":help":
+				fmt.Println(replHelp)
+			case ":mode":
+				if !containsString(searcher.Modes, argument) {
+					fmt.Printf("Error: mode must be one of %s.\n", strings.Join(searcher.Modes, ", "))
+					continue
+				}
+				mode = argument
+			case ":field":
+				field = argument
+			case ":near":
+				near = argument
+			case ":within":
+				n, err := strconv.Atoi(argument)
+				if err != nil || n < 0 {
+					fmt.Println("Error: within must be a non-negative number.")
+					continue
+				}
+				within = n
+			case ":normalize":
+				normalize = argument == "on"
+			case ":show":
+				fmt.Printf("mode=%s field=%q near=%q within=%d normalize=%t\n", mode, field, near, within, normalize)
+			default:
+				fmt.Printf("Unknown command %s. Type :help for commands.\n", command)
+			}
+			continue
+		}
+
+		if mode == searcher.ModeNear && near == "" {
+			fmt.Println("Error: set a second term with :near before searching in near mode.")
+			continue
+		}
+		opts := searcher.Options{Terms: []string{line}, Mode: mode, Field: field, Near: near, Within: within}
+		if normalize {
+			opts.Normalize = []string{"space"}
+		}
+		s, err := searcher.New(opts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		start := time.Now()
+		matches := loaded.search(os.Stdout, s, field, redact, false)
+		fmt.Printf("\n%d match(es) in %s\n", matches, time.Since(start).Round(time.Millisecond))
+	}
+}
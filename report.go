@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// One row of the HTML report
+type reportRow struct {
+	Detector   string
+	Repository string
+	File       string
+	Line       int64
+	Verified   bool
+	Secret     string
+	Link       string
+	Source     string
+	Anomaly    string
+	LikelyFP   string // why the finding is likely a false positive
+	ThirdParty string // the vendored path holding the finding
+	JSON       string
+}
+
+// A bar of the per-detector and per-repository charts
+type reportBar struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+type reportPage struct {
+	Generated    string
+	Total        int
+	Anomalies    []string
+	Rows         []reportRow
+	Detectors    []reportBar
+	Repositories []reportBar
+}
+
+// Write the matches as a standalone HTML page: a sortable and filterable table,
+// charts per detector and repository, and the JSON of every finding.
+// Secrets are masked unless redact is nil (-show-secrets). Anomalies found by
+// -anomalies are listed above everything else.
+func writeHTMLReport(path string, matches []*searchMatch, anomalies []anomaly, redact []string, now time.Time) error {
+	page := reportPage{Generated: now.Format(time.RFC1123), Total: len(matches)}
+	for _, a := range anomalies {
+		page.Anomalies = append(page.Anomalies, a.String())
+	}
+	detectors, repositories := map[string]int{}, map[string]int{}
+	for _, m := range matches {
+		data := m.data
+		if redact != nil {
+			data = searcher.Redact(data, redact)
+		}
+		flat := flattenFinding(data, m.sourceFile, m.sourceLine)
+		pretty, _ := json.MarshalIndent(data, "", "  ")
+		row := reportRow{
+			Detector: flat.DetectorName, Repository: flat.Repository, File: flat.File, Line: flat.Line,
+			Verified: flat.Verified, Secret: searcher.Secret(data), Link: flat.Link,
+			Source: m.sourceFile, Anomaly: m.anomaly, JSON: string(pretty),
+		}
+		if fp := m.falsePositive; fp.Score >= likelyFalsePositive {
+			row.LikelyFP = fmt.Sprintf("Likely false positive (%.2f): %s", fp.Score, strings.Join(fp.Reasons, ", "))
+		}
+		if m.thirdParty != "" {
+			row.ThirdParty = "Third-party code: " + m.thirdParty
+		}
+		page.Rows = append(page.Rows, row)
+		detectors[orNone(flat.DetectorName)]++
+		repositories[orNone(flat.Repository)]++
+	}
+	page.Detectors = reportBars(detectors)
+	page.Repositories = reportBars(repositories)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(file, page); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Chart bars scaled to the largest count
+func reportBars(counts map[string]int) []reportBar {
+	var bars []reportBar
+	for _, entry := range sortedCounts(counts) {
+		if len(bars) == 0 {
+			bars = append(bars, reportBar{entry.value, entry.count, 100})
+			continue
+		}
+		bars = append(bars, reportBar{entry.value, entry.count, entry.count * 100 / bars[0].Count})
+	}
+	return bars
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>trufflehog-searcher report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+.meta { color: #666; margin-bottom: 2em; }
+.charts { display: flex; gap: 3em; flex-wrap: wrap; }
+.chart { flex: 1; min-width: 320px; }
+.bar { display: flex; align-items: center; margin: 2px 0; font-size: 0.9em; }
+.bar .label { width: 40%; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar .fill { background: #3572a5; height: 1em; margin-right: 0.5em; }
+input { margin: 1.5em 0 0.5em; padding: 0.4em; width: 100%; max-width: 30em; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+th { cursor: pointer; background: #f4f4f4; user-select: none; }
+td.secret { font-family: monospace; }
+.verified { color: #b00; font-weight: bold; }
+.anomalies { background: #fff4e0; border-left: 4px solid #e08a00; padding: 0.5em 1em; margin-bottom: 2em; }
+tr.anomaly { background: #fff4e0; }
+tr.likely-fp { color: #999; }
+tr.third-party { color: #777; font-style: italic; }
+pre { background: #f8f8f8; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>trufflehog-searcher report</h1>
+<div class="meta">{{.Total}} finding(s), generated {{.Generated}}</div>
+{{if .Anomalies}}<div class="anomalies"><h2>Anomalies</h2>
+<ul>{{range .Anomalies}}<li>{{.}}</li>{{end}}</ul>
+</div>
+{{end}}<div class="charts">
+<div class="chart"><h2>Findings per detector</h2>
+{{range .Detectors}}<div class="bar"><span class="label" title="{{.Label}}">{{.Label}}</span><span class="fill" style="width: {{.Percent}}%"></span>{{.Count}}</div>
+{{end}}</div>
+<div class="chart"><h2>Findings per repository</h2>
+{{range .Repositories}}<div class="bar"><span class="label" title="{{.Label}}">{{.Label}}</span><span class="fill" style="width: {{.Percent}}%"></span>{{.Count}}</div>
+{{end}}</div>
+</div>
+<input id="filter" type="search" placeholder="Filter findings">
+<table id="findings">
+<thead><tr><th>Detector</th><th>Repository</th><th>File</th><th>Line</th><th>Verified</th><th>Secret</th><th>Source</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr{{if .Anomaly}} class="anomaly" title="{{.Anomaly}}"{{else if .LikelyFP}} class="likely-fp" title="{{.LikelyFP}}"{{else if .ThirdParty}} class="third-party" title="{{.ThirdParty}}"{{end}}>
+<td>{{.Detector}}</td>
+<td>{{.Repository}}</td>
+<td>{{if .Link}}<a href="{{.Link}}">{{.File}}</a>{{else}}{{.File}}{{end}}</td>
+<td>{{.Line}}</td>
+<td{{if .Verified}} class="verified"{{end}}>{{.Verified}}</td>
+<td class="secret">{{.Secret}}</td>
+<td>{{.Source}}<details><summary>JSON</summary><pre>{{.JSON}}</pre></details></td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+const table = document.getElementById("findings");
+const rows = () => Array.from(table.tBodies[0].rows);
+document.getElementById("filter").addEventListener("input", event => {
+  const filter = event.target.value.toLowerCase();
+  rows().forEach(row => { row.hidden = !row.textContent.toLowerCase().includes(filter); });
+});
+table.tHead.querySelectorAll("th").forEach((th, column) => {
+  let ascending = true;
+  th.addEventListener("click", () => {
+    const value = row => row.cells[column].firstChild ? row.cells[column].firstChild.textContent.trim() : "";
+    const sorted = rows().sort((a, b) => {
+      const x = value(a), y = value(b);
+      const order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y);
+      return ascending ? order : -order;
+    });
+    ascending = !ascending;
+    sorted.forEach(row => table.tBodies[0].appendChild(row));
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Run trufflehog and search its findings as they are produced, saving its raw output
+func runScan(args []string) {
+	var saveDir, binary string
+	extra := func(fs *flag.FlagSet) {
+		fs.StringVar(&saveDir, "save", ".", "Directory receiving the raw trufflehog output as scan-<time>.json")
+		fs.StringVar(&binary, "trufflehog", "trufflehog", "Path of the trufflehog binary")
+		fs.Usage = func() {
+			fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher scan [search flags] -- TRUFFLEHOG ARGUMENTS")
+			fmt.Fprintln(fs.Output(), "e.g. trufflehog-searcher scan -verified -s acme -- git https://github.com/org/repo")
+			fs.PrintDefaults()
+		}
+	}
+	listSources := func(ctx context.Context, f *searchFlags, filter inputFilter, fn func(inputSource) error) error {
+		if len(f.args) == 0 {
+			return fmt.Errorf("expected the trufflehog arguments after the flags, e.g. -- git https://github.com/org/repo")
+		}
+		if err := os.MkdirAll(saveDir, 0o755); err != nil {
+			return err
+		}
+		savePath := filepath.Join(saveDir, fmt.Sprintf("scan-%s.json", time.Now().Format("20060102-150405")))
+		return fn(inputSource{name: "trufflehog", open: func(ctx context.Context) (io.ReadCloser, error) {
+			return startTrufflehog(ctx, binary, f.args, savePath)
+		}})
+	}
+	os.Exit(runSearch("scan", args, extra, listSources))
+}
+
+// Output of a running trufflehog, copied to the save file while it is read.
+// Closing waits for trufflehog to exit.
+type trufflehogOutput struct {
+	io.Reader
+	cmd       *exec.Cmd
+	save      *os.File
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Start trufflehog with JSON output
+func startTrufflehog(ctx context.Context, binary string, args []string, savePath string) (io.ReadCloser, error) {
+	if !containsString(args, "--json") && !containsString(args, "-j") {
+		args = append(args, "--json")
+	}
+	save, err := os.Create(savePath)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		save.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		save.Close()
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Saving trufflehog output to %s\n", savePath)
+	return &trufflehogOutput{Reader: io.TeeReader(stdout, save), cmd: cmd, save: save}, nil
+}
+
+func (t *trufflehogOutput) Close() error {
+	t.closeOnce.Do(func() {
+		t.closeErr = t.cmd.Wait()
+		if err := t.save.Close(); t.closeErr == nil {
+			t.closeErr = err
+		}
+		if t.closeErr != nil {
+			t.closeErr = fmt.Errorf("trufflehog: %w", t.closeErr)
+		}
+	})
+	return t.closeErr
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// A finding that matched, with everything learned about it on the way
+type searchMatch struct {
+	source        string // input the finding was read from
+	line          int
+	sourceFile    string // attribution kept in results; differs from source for -input-format self
+	sourceLine    int
+	location      string // "line N", or "line N of file" for -input-format self
+	data          JSONData
+	terms         []string // search terms that matched
+	fingerprint   string   // occurrence fingerprint, see occurrenceFields
+	decision      policyDecision
+	policy        bool   // decision comes from a -policy
+	status        string // stored triage status
+	note          string // stored triage note
+	ignoredBy     string // ignore rule, for findings shown by -show-ignored
+	link          string // permalink synthesized for findings without one
+	blame         *blameInfo
+	anomaly       string           // why -anomalies put the finding first
+	risk          float64          // score of -rank
+	falsePositive fpScore          // signs of a made-up secret
+	expiry        expiryInfo       // whether the secret has likely expired
+	thirdParty    string           // path glob of the vendored code holding the finding
+	fieldPath     string           // full path -f resolved to, when it is not the one given
+	explain       []searcher.Check // predicates of the search evaluated, for -explain
+	warnings      []string         // problems met while handling the match
+}
+
+// One search over the input: its matcher and everything done with the matches.
+// Several presets run as several jobs over a single pass of the input.
+type searchJob struct {
+	name     string // preset name, empty without presets
+	flags    *searchFlags
+	searcher *searcher.Searcher
+	anyTime  *searcher.Searcher // without the -since/-until window, for -cache
+	policy   *findingPolicy
+	sinks    []resultSink
+	router   *sinkRouter // matches sent to the sinks of the configuration file
+	ignore   []ignoreRule
+	examples exampleSecrets
+	vendored thirdPartyPaths
+	baseline *baselineSet
+	triage   *triageStore
+	out      *printer
+	ignored  atomic.Int64 // findings suppressed by ignore rules or as example secrets
+	hiddenFP atomic.Int64 // likely false positives left out by -hide-likely-fp
+	hiddenTP atomic.Int64 // findings left out by -third-party
+}
+
+// Set up a search from its flags
+func newSearchJob(ctx context.Context, name string, f *searchFlags, cfg *config, metrics *runMetrics, streams outputStreams) (*searchJob, error) {
+	opts, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+	opts.MaxLineBytes = f.maxLineBytes
+	s, err := searcher.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	job := &searchJob{name: name, flags: f, searcher: s, router: newSinkRouter(cfg)}
+	if f.cache && (!opts.Since.IsZero() || !opts.Until.IsZero()) {
+		opts.Since, opts.Until = time.Time{}, time.Time{}
+		if job.anyTime, err = searcher.New(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.policyPath != "" {
+		if job.policy, err = loadPolicy(f.policyPath); err != nil {
+			return nil, fmt.Errorf("loading policy %s: %w", f.policyPath, err)
+		}
+	}
+	if job.ignore, err = loadIgnoreRules(f.ignoreFile, cfg.Ignore); err != nil {
+		return nil, err
+	}
+	job.examples = newExampleSecrets(cfg.ExampleSecrets)
+	job.vendored = newThirdPartyPaths(cfg.ThirdPartyPaths)
+	if f.baseline != "" {
+		if job.baseline, err = loadBaseline(ctx, f.baseline, s, inputFilter{recursive: f.recursive}, f.showResolved); err != nil {
+			return nil, fmt.Errorf("loading baseline %s: %w", f.baseline, err)
+		}
+	}
+	if f.against != "" {
+		if job.baseline, err = loadNamedBaseline(f.dbPath, f.against, s, f.showResolved); err != nil {
+			return nil, err
+		}
+	}
+	if job.triage, err = openTriageStore(f.dbPath, f.status != "" || f.tui); err != nil {
+		return nil, err
+	}
+
+	// Open the destinations receiving every match besides the output
+	if f.outDir != "" {
+		var redact []string
+		if !f.showSecrets {
+			redact = splitList(f.redactFields)
+		}
+		results, err := newLayoutWriter(f.outDir, f.layout, redact)
+		if err != nil {
+			return nil, err
+		}
+		job.sinks = append(job.sinks, results)
+	}
+	if f.exportKind != "" {
+		exporter, err := newExportSink(f.exportKind, exportOptions{table: f.exportTable, dsn: f.exportDSN, maxLen: f.exportMaxLen})
+		if err != nil {
+			return nil, err
+		}
+		job.sinks = append(job.sinks, exporter)
+	}
+
+	for _, sink := range f.sinks {
+		sinkCfg, ok := cfg.Sinks[sink]
+		if !ok {
+			return nil, fmt.Errorf("-sink: unknown sink %q", sink)
+		}
+		if _, err := newAlertSink(sinkCfg); err != nil {
+			return nil, fmt.Errorf("-sink %s: %w", sink, err)
+		}
+	}
+
+	if job.out, err = newPrinter(job, metrics, len(opts.Terms) > 1, streams); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Decide what happens to a finding the searcher matched. Returns false for
+// findings left out: ignored, known from the baseline, of another triage
+// status, likely false positives with -hide-likely-fp, filtered out by
+// -third-party or allowed by policy.
+func (j *searchJob) accept(ctx context.Context, m *searchMatch) bool {
+	if m.fingerprint == "" {
+		m.fingerprint = fingerprint(m.data, occurrenceFields)
+	}
+	if rule := matchIgnoreRules(j.ignore, m.data); rule != nil {
+		j.ignored.Add(1)
+		if !j.flags.showIgnored {
+			return false
+		}
+		m.ignoredBy = rule.String()
+	} else if example := j.examples.match(m.data); example != "" {
+		// Published example credentials are ignored like known false positives
+		j.ignored.Add(1)
+		if !j.flags.showIgnored {
+			return false
+		}
+		m.ignoredBy = "known example secret: " + example
+	}
+	if j.baseline != nil && !j.baseline.isNew(m.fingerprint) {
+		return false
+	}
+	if j.triage != nil {
+		m.status, m.note = j.triage.lookup(m.fingerprint)
+	}
+	if j.flags.status != "" {
+		if triageStatusOrNew(m.status) != j.flags.status {
+			return false
+		}
+		m.status = triageStatusOrNew(m.status)
+	}
+	m.falsePositive = scoreFalsePositive(m.data)
+	if j.flags.hideLikelyFP && m.falsePositive.Score >= likelyFalsePositive {
+		j.hiddenFP.Add(1)
+		return false
+	}
+	m.expiry = estimateExpiry(m.data, time.Now())
+	if j.flags.expiry != "" && m.expiry.Status != j.flags.expiry {
+		return false
+	}
+	m.thirdParty = j.vendored.match(m.data)
+	if mode := j.flags.thirdParty; (mode == "hide" && m.thirdParty != "") || (mode == "only" && m.thirdParty == "") {
+		j.hiddenTP.Add(1)
+		return false
+	}
+
+	decision, err := j.policy.evaluate(ctx, m.data)
+	if err != nil {
+		m.warnings = append(m.warnings, fmt.Sprintf("Error evaluating policy at %s: %v", m.location, err))
+	}
+	// Allowed findings are accepted by policy and left out of the results
+	if decision.Allow {
+		return false
+	}
+	m.decision, m.policy = decision, j.policy != nil
+
+	m.link = permalink(m.data)
+	m.fieldPath = resolvedField(m.data, j.flags.field)
+	if j.flags.explain {
+		m.explain = j.searcher.Explain(m.data)
+	}
+	if j.flags.enrichGit != "" {
+		if m.blame, err = gitBlame(ctx, j.flags.enrichGit, m.data); err != nil {
+			m.warnings = append(m.warnings, fmt.Sprintf("Error enriching %s from %s: %v", m.location, j.flags.enrichGit, err))
+		}
+		if m.blame != nil && !j.flags.showSecrets {
+			m.blame.redact(searcher.Secret(m.data))
+		}
+	}
+
+	// Findings shown by -show-ignored do not count as matches
+	if m.ignoredBy != "" {
+		return true
+	}
+	outcome.match(m.data, decision)
+	if j.baseline != nil {
+		outcome.newFindings.Add(1)
+	}
+	for _, route := range decision.Routes {
+		j.router.add("policy route "+route, []string{route}, m.data)
+	}
+	if len(j.flags.sinks) > 0 {
+		j.router.add(j.rule(), j.flags.sinks, m.data)
+	}
+	for _, sink := range j.sinks {
+		if err := sink.write(m.data, m.sourceFile, m.sourceLine); err != nil {
+			m.warnings = append(m.warnings, fmt.Sprintf("Error writing result: %v", err))
+		}
+	}
+	return true
+}
+
+// Full path a -f field was read from, when it was resolved below the source
+// metadata or found deeper in the finding; "" when it is the path given
+func resolvedField(data JSONData, field string) string {
+	if path, ok := searcher.Resolve(data, field); ok && path != field {
+		return path
+	}
+	return ""
+}
+
+// Name of the alerts the job sends to its -sink: the profile, or "search"
+func (j *searchJob) rule() string {
+	if j.name == "" {
+		return "search"
+	}
+	return "profile " + j.name
+}
+
+// Flush the routed findings and close the sinks
+func (j *searchJob) close(ctx context.Context) error {
+	j.router.dispatch(ctx)
+	var firstErr error
+	for _, sink := range j.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if j.triage != nil {
+		j.triage.Close()
+	}
+	return firstErr
+}
+
+// Returned for lines of -input-format self that do not hold a result
+var errMissingFinding = errors.New("missing finding")
+
+// Reads the input once and hands every finding to each job
+type pipeline struct {
+	jobs           []*searchJob
+	inputFormat    string
+	filter         inputFilter
+	threads        int
+	scan           searcher.ScanOptions
+	perFileTimeout time.Duration
+	metrics        *runMetrics
+	searched       func(src inputSource) // called for every source searched completely, if set
+	cache          *resultCache          // results of local files by content, with -cache
+}
+
+// Calls fn for every source of a search as it is found
+type sourceWalker func(fn func(inputSource) error) error
+
+// Sources listed ahead of the printers. Listing waits when the printers fall
+// this far behind, so memory does not grow with the number of sources.
+const sourceQueueSize = 256
+
+// Search the sources as walk finds them and return the error of the listing.
+// Output stays in source order whatever the number of threads: each job's
+// printer drains the sources one after the other while workers fill them.
+func (p *pipeline) run(ctx context.Context, walk sourceWalker) error {
+	queues := make([]chan *sourceOutput, len(p.jobs))
+	for j, job := range p.jobs {
+		queues[j] = make(chan *sourceOutput, sourceQueueSize)
+		job.out.start(queues[j])
+	}
+
+	type task struct {
+		src  inputSource
+		outs []*sourceOutput
+	}
+	tasks := make(chan task, p.threads)
+	var wg sync.WaitGroup
+	for _, worker := range p.metrics.startWorkers(p.threads) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				p.searchSource(ctx, t.src, t.outs, worker)
+			}
+		}()
+	}
+
+	// A source is queued to the printers before the workers see it, so the
+	// printers always wait on a source that a worker has or will get
+	p.metrics.listing.Store(true)
+	err := walk(func(src inputSource) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.metrics.addSources([]inputSource{src})
+		outs := make([]*sourceOutput, len(p.jobs))
+		for j := range p.jobs {
+			outs[j] = newSourceOutput()
+			queues[j] <- outs[j]
+		}
+		tasks <- task{src: src, outs: outs}
+		return nil
+	})
+	p.metrics.listing.Store(false)
+	close(tasks)
+	for _, queue := range queues {
+		close(queue)
+	}
+
+	wg.Wait()
+	for _, job := range p.jobs {
+		job.out.wait()
+	}
+	return err
+}
+
+// Search one source, sending its results to the outputs of every job
+func (p *pipeline) searchSource(ctx context.Context, src inputSource, outs []*sourceOutput, worker *workerMetrics) {
+	defer func() {
+		for _, out := range outs {
+			close(out.events)
+		}
+	}()
+	if ctx.Err() != nil {
+		return
+	}
+	fileCtx := ctx
+	if p.perFileTimeout > 0 {
+		var cancel context.CancelFunc
+		fileCtx, cancel = context.WithTimeout(ctx, p.perFileTimeout)
+		defer cancel()
+	}
+
+	stats := p.metrics.startFile(src, worker)
+	src.open = p.metrics.countBytes(src, stats)
+	var recorder *cacheRecorder
+	var hash string
+	var cached map[string]cachedStream
+	if p.cache != nil && src.path != "" {
+		var streams []cachedStream
+		var ok bool
+		if hash, streams, ok = p.cache.lookup(src); ok {
+			cached = map[string]cachedStream{}
+			for _, stream := range streams {
+				cached[stream.Name] = stream
+			}
+		} else if hash != "" {
+			recorder = &cacheRecorder{}
+		}
+	}
+	err := readSource(fileCtx, src, p.filter, func(name string, r io.Reader) error {
+		broadcast(outs, outputEvent{header: name})
+		if cached != nil {
+			return p.replayStream(fileCtx, name, r, cached[name], outs, stats)
+		}
+		recorder.startStream(name)
+		return p.scanStream(fileCtx, name, r, outs, stats, recorder)
+	})
+	// An interrupted or timed out search is reported once for all sources
+	if err != nil && ctx.Err() == nil {
+		if errors.Is(fileCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", p.perFileTimeout)
+		}
+		outcome.errors.Add(1)
+		stats.Error = err.Error()
+		broadcast(outs, outputEvent{message: fmt.Sprintf("Error reading file %s: %v", src.name, err)})
+	}
+	if err == nil && fileCtx.Err() == nil {
+		if recorder != nil {
+			if err := p.cache.store(hash, recorder.streams); err != nil {
+				broadcast(outs, outputEvent{message: fmt.Sprintf("Error caching the results of %s: %v", src.name, err)})
+			}
+		}
+		if p.searched != nil {
+			p.searched(src)
+		}
+	}
+	p.metrics.finishFile(stats)
+}
+
+// Decode and match the findings of one stream
+func (p *pipeline) scanStream(ctx context.Context, name string, r io.Reader, outs []*sourceOutput, stats *fileMetrics, recorder *cacheRecorder) error {
+	r, err := adaptStream(p.inputFormat, r)
+	if err != nil {
+		return err
+	}
+	return searcher.Scan(ctx, r, p.scan, func(record *searcher.Record) processed {
+		return p.process(ctx, name, record)
+	}, func(record *searcher.Record, result processed) error {
+		recorder.add(record, result.hit)
+		p.emit(name, record, result.matches, outs, stats)
+		return nil
+	})
+}
+
+// Count a processed record and send its parse error or matches to the outputs
+func (p *pipeline) emit(name string, record *searcher.Record, matches []*searchMatch, outs []*sourceOutput, stats *fileMetrics) {
+	p.metrics.addLines(stats, 1)
+	if record.Err != nil {
+		stats.ParseErrors++
+		p.metrics.parseErrors.Add(1)
+		outcome.parseErrors.Add(1)
+		kind := "JSON"
+		if errors.Is(record.Err, errMissingFinding) {
+			kind = "result"
+		}
+		broadcast(outs, outputEvent{message: fmt.Sprintf("Error parsing %s at line %d in file %s: %v", kind, record.Line, name, record.Err)})
+		return
+	}
+	if matches == nil {
+		return
+	}
+	p.metrics.addMatch(stats)
+	for i, m := range matches {
+		if m != nil {
+			outs[i].events <- outputEvent{match: m}
+		}
+	}
+}
+
+// Outcome of matching one record against every job
+type processed struct {
+	matches []*searchMatch // per job; nil when no job kept the record
+	hit     bool           // some job's searcher matched, even if the job left the finding out
+	skipped bool           // not parsed, as -cache knows it matches nothing
+}
+
+// Match one record against every job. Runs on the line workers.
+func (p *pipeline) process(ctx context.Context, name string, record *searcher.Record) processed {
+	data := JSONData(record.Data)
+	sourceFile, sourceLine, fp := name, record.Line, ""
+	location := fmt.Sprintf("line %d", record.Line)
+	if p.inputFormat == "self" {
+		if err := record.Decode(); err != nil {
+			return processed{}
+		}
+		// Findings read back from result files keep their original attribution
+		result, ok := unwrapResult(record.Data)
+		if !ok {
+			record.Err = errMissingFinding
+			return processed{}
+		}
+		data, sourceFile, sourceLine, fp = result.Finding, result.SourceFile, result.SourceLine, result.Fingerprint
+		location = fmt.Sprintf("line %d of %s", sourceLine, sourceFile)
+	}
+	adapt := findingAdapters[p.inputFormat]
+	if adapt != nil {
+		if err := record.Decode(); err != nil {
+			return processed{}
+		}
+		finding, err := adapt(record.Data)
+		if err != nil {
+			record.Err = err
+			return processed{}
+		}
+		data = finding
+	}
+
+	var result processed
+	// Returns false when the record turns out not to be valid JSON
+	match := func(i int, job *searchJob) bool {
+		var terms []string
+		var ok bool
+		if p.inputFormat == "self" || adapt != nil {
+			terms, ok = job.searcher.Match(data)
+		} else {
+			terms, ok = job.searcher.Check(record)
+			data = record.Data
+		}
+		if record.Err != nil {
+			return false
+		}
+		if !ok {
+			// Findings outside the -since/-until window are cached all the same
+			if job.anyTime != nil {
+				var anyTime bool
+				if p.inputFormat == "self" || adapt != nil {
+					_, anyTime = job.anyTime.Match(data)
+				} else {
+					_, anyTime = job.anyTime.Check(record)
+				}
+				result.hit = result.hit || anyTime
+			}
+			return true
+		}
+		result.hit = true
+		m := &searchMatch{source: name, line: record.Line, sourceFile: sourceFile, sourceLine: sourceLine,
+			location: location, data: data, terms: terms, fingerprint: fp}
+		if !job.accept(ctx, m) {
+			return true
+		}
+		if result.matches == nil {
+			result.matches = make([]*searchMatch, len(p.jobs))
+		}
+		result.matches[i] = m
+		return true
+	}
+	for i, job := range p.jobs {
+		if !job.flags.fallback && !match(i, job) {
+			return processed{}
+		}
+	}
+	// -fallback jobs get the findings every other job left out
+	if result.matches == nil {
+		for i, job := range p.jobs {
+			if job.flags.fallback && !match(i, job) {
+				return processed{}
+			}
+		}
+	}
+	return result
+}
+
+// Send an event to the outputs of every job
+func broadcast(outs []*sourceOutput, event outputEvent) {
+	for _, out := range outs {
+		out.events <- event
+	}
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Findings hub receiving trufflehog output over HTTP
+type server struct {
+	db      *sql.DB
+	dbPath  string
+	cfg     *config
+	maxBody int64
+	mu      sync.Mutex // serializes ingestion so duplicate detection sees committed rows
+	alerts  chan []firedAlert
+}
+
+// Batches of fired alerts waiting for delivery before ingestion waits for them
+const alertQueueSize = 100
+
+// Summary returned by POST /ingest
+type ingestResponse struct {
+	Accepted   int             `json:"accepted"`
+	Duplicates int             `json:"duplicates"`
+	Rejected   []ingestProblem `json:"rejected"`
+	Alerts     []firedAlert    `json:"alerts"`
+}
+
+// A body line that failed validation
+type ingestProblem struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Run the HTTP server
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	configPath := fs.String("config", defaultConfigPath(), "Path of the configuration file holding alert rules")
+	maxBody := fs.Int64("max-body", 64<<20, "Maximum accepted request body size in bytes")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := compileAlerts(cfg); err != nil {
+		fmt.Printf("Error in config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database %s: %v\n", *dbPath, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	srv := &server{db: db, dbPath: *dbPath, cfg: cfg, maxBody: *maxBody, alerts: make(chan []firedAlert, alertQueueSize)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ingest", srv.handleIngest)
+	mux.HandleFunc("GET /search", srv.handleSearch)
+	mux.HandleFunc("GET /search/stream", srv.handleSearchStream)
+
+	// One worker delivers the alerts, in the order they fired. Once the
+	// server is shut down it delivers what is still queued and exits.
+	drain, delivered := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(delivered)
+		for {
+			select {
+			case alerts := <-srv.alerts:
+				dispatchAlerts(context.Background(), cfg, alerts)
+			case <-drain:
+				for {
+					select {
+					case alerts := <-srv.alerts:
+						dispatchAlerts(context.Background(), cfg, alerts)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	httpServer := &http.Server{Addr: *addr, Handler: mux}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutDown := make(chan struct{})
+	go func() {
+		defer close(shutDown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Listening on %s with %d alert rule(s)", *addr, len(cfg.Alerts))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	<-shutDown
+	close(drain)
+	<-delivered
+	log.Printf("Stopped")
+}
+
+// How long an ingestion waits for another run writing to the database, like
+// db import, before answering 503
+const ingestLockWait = 30 * time.Second
+
+// Accept a trufflehog JSONL body: validate, fingerprint and store each finding,
+// then evaluate the alert rules against the findings that were not seen before.
+// The optional corpus and source query parameters label the stored findings.
+func (s *server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading body: %v", err), http.StatusRequestEntityTooLarge)
+		return
+	}
+	corpusName := r.URL.Query().Get("corpus")
+	if corpusName == "" {
+		corpusName = "ingest"
+	}
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = "ingest"
+	}
+
+	s.mu.Lock()
+	lock, err := lockDB(r.Context(), s.dbPath, ingestLockWait)
+	if err != nil {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	response, err := s.ingest(body, corpusName, source)
+	lock.Close()
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, alert := range response.Alerts {
+		log.Printf("Alert %s: %s finding %s in %s", alert.Rule, alert.Detector, alert.Fingerprint[:12], alert.Repository)
+	}
+	if len(response.Alerts) > 0 {
+		// A full queue holds the request back rather than dropping alerts
+		select {
+		case s.alerts <- response.Alerts:
+		case <-r.Context().Done():
+			log.Printf("Dropped %d alert(s): the client went away while the alert queue was full", len(response.Alerts))
+		}
+	}
+	status := http.StatusOK
+	if len(response.Rejected) > 0 && response.Accepted == 0 && response.Duplicates == 0 {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Store the findings of one body in a single transaction
+func (s *server) ingest(body []byte, corpusName, source string) (*ingestResponse, error) {
+	sum := sha256.Sum256(body)
+	bodyHash := hex.EncodeToString(sum[:])
+	response := &ingestResponse{Rejected: []ingestProblem{}, Alerts: []firedAlert{}}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var data JSONData
+		if err := json.Unmarshal(line, &data); err != nil {
+			response.Rejected = append(response.Rejected, ingestProblem{Line: lineNum, Error: err.Error()})
+			continue
+		}
+		if detector, _ := data["DetectorName"].(string); detector == "" {
+			response.Rejected = append(response.Rejected, ingestProblem{Line: lineNum, Error: "not a trufflehog finding: missing DetectorName"})
+			continue
+		}
+
+		fp := fingerprint(data, occurrenceFields)
+		var seen int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM findings WHERE fingerprint = ?`, fp).Scan(&seen); err != nil {
+			return nil, err
+		}
+		if seen > 0 {
+			response.Duplicates++
+			continue
+		}
+		if err := insertFinding(tx, data, corpusName, bodyHash, source, lineNum, now); err != nil {
+			return nil, err
+		}
+		response.Accepted++
+		response.Alerts = append(response.Alerts, evaluateAlerts(s.cfg.Alerts, data, fp)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if response.Accepted > 0 {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO imported_files (hash, path, corpus, imported_at, findings) VALUES (?, ?, ?, ?, ?)`,
+			bodyHash, source, corpusName, now, response.Accepted); err != nil {
+			return nil, err
+		}
+	}
+	return response, tx.Commit()
+}
+
+// Page sizes of GET /search
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	// Findings read by one request at most, so a search matching little
+	// cannot hold a request for a scan of the whole database; the cursor
+	// resumes after them
+	maxScannedPerPage = 100000
+)
+
+// One page of GET /search. A page may hold fewer matches than asked, even
+// none; there are more as long as next_cursor is set.
+type searchPage struct {
+	Matches    []jsonResult `json:"matches"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Scanned    int          `json:"scanned"` // findings read for this page
+}
+
+// Where the next page starts, bound to the search it was returned for
+type searchCursor struct {
+	After  int64  `json:"after"`  // id of the last finding read
+	Search string `json:"search"` // hash of the search parameters
+}
+
+// Search the stored findings, a page at a time, in the order they were
+// stored. The parameters are those of a search: s (repeatable), all, mode,
+// field, q, not, verified, detector, repo, plus corpus, page_size and the
+// cursor of the previous page.
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	matcher, err := serverSearcher(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageSize := defaultPageSize
+	if value := params.Get("page_size"); value != "" {
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 || pageSize > maxPageSize {
+			http.Error(w, fmt.Sprintf("page_size must be between 1 and %d", maxPageSize), http.StatusBadRequest)
+			return
+		}
+	}
+	search := searchHash(params)
+	var after int64
+	if value := params.Get("cursor"); value != "" {
+		var cursor searchCursor
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			err = json.Unmarshal(decoded, &cursor)
+		}
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if cursor.Search != search {
+			http.Error(w, "the cursor belongs to another search", http.StatusBadRequest)
+			return
+		}
+		after = cursor.After
+	}
+
+	page, last, err := s.searchPage(r.Context(), matcher, params.Get("corpus"), after, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if last > 0 {
+		encoded, _ := json.Marshal(searchCursor{After: last, Search: search})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(encoded)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// Findings read by one query of GET /search and GET /search/stream, which
+// read them a batch at a time so they never hold the database against
+// ingestion for long
+const searchBatchSize = 1000
+
+// Findings read between two progress events of GET /search/stream
+const streamProgressEvery = 10000
+
+// Stream the matches of a search over every stored finding as Server-Sent
+// Events, as they are found: a "match" event per match with the finding's
+// id as event id, a "progress" event every 10000 findings read and a "done"
+// event at the end. A client reconnecting with Last-Event-ID, as browsers'
+// EventSource does, resumes after the last match it received.
+func (s *server) handleSearchStream(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	matcher, err := serverSearcher(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var after int64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if after, err = strconv.ParseInt(id, 10, 64); err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event, id string, data interface{}) {
+		encoded, _ := json.Marshal(data)
+		if id != "" {
+			fmt.Fprintf(w, "id: %s\n", id)
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+		flusher.Flush()
+	}
+	scanned, matched := 0, 0
+	for {
+		read := 0
+		err := s.searchFindings(r.Context(), matcher, params.Get("corpus"), after, searchBatchSize, func(id int64, result *jsonResult) bool {
+			read++
+			scanned++
+			after = id
+			if result != nil {
+				matched++
+				send("match", strconv.FormatInt(id, 10), result)
+			}
+			if scanned%streamProgressEvery == 0 {
+				send("progress", "", map[string]int{"scanned": scanned, "matched": matched})
+			}
+			return true
+		})
+		if r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			send("error", "", map[string]string{"error": err.Error()})
+			return
+		}
+		if read < searchBatchSize {
+			break
+		}
+	}
+	send("done", "", map[string]int{"scanned": scanned, "matched": matched})
+}
+
+// Read the findings stored after the given id until a page is full, and
+// return the id to resume from, 0 once every finding was read
+func (s *server) searchPage(ctx context.Context, matcher *searcher.Searcher, corpusName string, after int64, pageSize int) (*searchPage, int64, error) {
+	page := &searchPage{Matches: []jsonResult{}}
+	last := after
+	for page.Scanned < maxScannedPerPage && len(page.Matches) < pageSize {
+		limit := min(searchBatchSize, maxScannedPerPage-page.Scanned)
+		read := 0
+		err := s.searchFindings(ctx, matcher, corpusName, last, limit, func(id int64, result *jsonResult) bool {
+			read++
+			page.Scanned++
+			last = id
+			if result != nil {
+				page.Matches = append(page.Matches, *result)
+			}
+			return len(page.Matches) < pageSize
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if read < limit {
+			break
+		}
+	}
+	// A full page or scan window may be followed by more findings
+	if len(page.Matches) == pageSize || page.Scanned == maxScannedPerPage {
+		return page, last, nil
+	}
+	return page, 0, nil
+}
+
+// Read the findings stored after the given id in the order they were stored,
+// at most limit of them, calling fn with each of them and,
+// when it matches, its result with secrets masked. fn returns false to stop.
+// The rows are read before fn is called, so a slow client of fn never keeps
+// a read transaction open against ingestion.
+func (s *server) searchFindings(ctx context.Context, matcher *searcher.Searcher, corpusName string, after int64, limit int, fn func(id int64, result *jsonResult) bool) error {
+	type storedFinding struct {
+		id      int64
+		match   searchMatch
+		encoded string
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, source_file, source_line, fingerprint, data FROM findings
+		WHERE id > ? AND (? = '' OR corpus = ?) ORDER BY id LIMIT ?`, after, corpusName, corpusName, limit)
+	if err != nil {
+		return err
+	}
+	var batch []storedFinding
+	for rows.Next() {
+		var f storedFinding
+		if err := rows.Scan(&f.id, &f.match.sourceFile, &f.match.sourceLine, &f.match.fingerprint, &f.encoded); err != nil {
+			rows.Close()
+			return err
+		}
+		batch = append(batch, f)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range batch {
+		m := &batch[i].match
+		var result *jsonResult
+		if err := json.Unmarshal([]byte(batch[i].encoded), &m.data); err == nil {
+			if terms, ok := matcher.Match(m.data); ok {
+				m.terms = terms
+				m.link = permalink(m.data)
+				matched := newJSONResult(m, searcher.Redact(m.data, searcher.DefaultRedactFields), false)
+				result = &matched
+			}
+		}
+		if !fn(batch[i].id, result) {
+			break
+		}
+	}
+	return nil
+}
+
+// The searcher of the search parameters of a request
+func serverSearcher(params url.Values) (*searcher.Searcher, error) {
+	opts := searcher.Options{
+		Terms:        params["s"],
+		Mode:         params.Get("mode"),
+		Field:        params.Get("field"),
+		Query:        params.Get("q"),
+		Not:          params["not"],
+		Detectors:    params["detector"],
+		Repositories: params["repo"],
+	}
+	var err error
+	if value := params.Get("all"); value != "" {
+		if opts.All, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("all must be true or false")
+		}
+	}
+	if value := params.Get("verified"); value != "" {
+		verified, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("verified must be true or false")
+		}
+		opts.Verified = &verified
+	}
+	return searcher.New(opts)
+}
+
+// Identity of a search: its parameters without the paging ones
+func searchHash(params url.Values) string {
+	search := url.Values{}
+	for name, values := range params {
+		if name != "cursor" && name != "page_size" {
+			search[name] = values
+		}
+	}
+	sum := sha256.Sum256([]byte(search.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Sections of -by-severity, most severe first
+var severityLevels = []string{"critical", "high", "medium", "low"}
+
+// Values of -color
+var colorModes = []string{"auto", "always", "never"}
+
+// ANSI colors of the -by-severity sections
+var severityColors = map[string]string{
+	"critical": "\033[1;31m", // bold red
+	"high":     "\033[31m",   // red
+	"medium":   "\033[33m",   // yellow
+	"low":      "\033[36m",   // cyan
+}
+
+const colorReset = "\033[0m"
+
+// Severity of a match: the one a -policy gave it, otherwise taken from its
+// risk score. Verified secrets that look random are critical, verified ones
+// that do not are high, random-looking unverified ones medium.
+func matchSeverity(m *searchMatch) string {
+	if severity := strings.ToLower(m.decision.Severity); severityRisk[severity] > 0 {
+		return severity
+	}
+	switch {
+	case m.risk >= 60:
+		return "critical"
+	case m.risk >= 45:
+		return "high"
+	case m.risk >= 10:
+		return "medium"
+	}
+	return "low"
+}
+
+// Whether -color asks for ANSI colors on w: always, or with auto when w is a
+// terminal and NO_COLOR is not set
+func useColor(mode string, w io.Writer) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Print the matches in a section per severity, each riskiest first, after a
+// line counting every section
+func (p *printer) writeSeverity(matches []*searchMatch) {
+	sections := map[string][]*searchMatch{}
+	for _, m := range matches {
+		if m.risk == 0 {
+			m.risk = riskScore(m)
+		}
+		severity := matchSeverity(m)
+		sections[severity] = append(sections[severity], m)
+	}
+	color := useColor(p.flags.color, p.w)
+	paint := func(severity, text string) string {
+		if !color {
+			return text
+		}
+		return severityColors[severity] + text + colorReset
+	}
+
+	counts := make([]string, len(severityLevels))
+	for i, severity := range severityLevels {
+		counts[i] = paint(severity, fmt.Sprintf("%s: %d", strings.ToUpper(severity[:1])+severity[1:], len(sections[severity])))
+	}
+	fmt.Fprintln(p.w, strings.Join(counts, "  "))
+	for _, severity := range severityLevels {
+		section := sections[severity]
+		if len(section) == 0 {
+			continue
+		}
+		sort.SliceStable(section, func(i, j int) bool { return section[i].risk > section[j].risk })
+		fmt.Fprintf(p.w, "\n%s\n", paint(severity, fmt.Sprintf("=== %s (%d) ===", strings.ToUpper(severity), len(section))))
+		for _, m := range section {
+			p.emit(m)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Files below a directory of an SSH host, or the file named by the path
+// itself, read over SFTP so the host needs nothing but sshd. Both
+// sftp://host:/var/scans/ and sftp://user@host:2222/var/scans/ are accepted.
+func listSFTP(ctx context.Context, location *url.URL, filter inputFilter) ([]inputSource, error) {
+	client, err := dialSFTP(location)
+	if err != nil {
+		return nil, err
+	}
+	root := location.Path
+	if root == "" {
+		root = "."
+	}
+	open := func(name string) func(context.Context) (io.ReadCloser, error) {
+		return func(context.Context) (io.ReadCloser, error) {
+			return client.Open(name)
+		}
+	}
+
+	info, err := client.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []inputSource{{name: path.Base(root), size: info.Size(), mtime: info.ModTime(), open: open(root)}}, nil
+	}
+	var sources []inputSource
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := client.ReadDir(path.Join(root, rel))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryRel := path.Join(rel, entry.Name())
+			if entry.IsDir() {
+				if filter.recursive && !excludedDir(filter, entryRel) {
+					if err := walk(entryRel); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if source, ok := remoteSource(entryRel, "", filter); ok {
+				source.open = open(path.Join(root, entryRel))
+				source.size, source.mtime = entry.Size(), entry.ModTime()
+				sources = append(sources, source)
+			}
+		}
+		return nil
+	}
+	return sources, walk("")
+}
+
+// Connect to the host of an sftp:// location as the user of the URL, or the
+// local user, authenticating with the SSH agent and the default keys of
+// ~/.ssh. The host key must be in ~/.ssh/known_hosts.
+func dialSFTP(location *url.URL) (*sftp.Client, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("reading known hosts: %w", err)
+	}
+
+	var methods []ssh.AuthMethod
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	var signers []ssh.Signer
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		key, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		// Keys protected by a passphrase are left to the agent
+		if signer, err := ssh.ParsePrivateKey(key); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no SSH agent or unencrypted key in ~/.ssh to authenticate with")
+	}
+
+	user := location.User.Username()
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	port := location.Port()
+	if port == "" {
+		port = "22"
+	}
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(location.Hostname(), port), &ssh.ClientConfig{
+		User:            user,
+		Auth:            methods,
+		HostKeyCallback: hostKeys,
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Notification sink configured in the sinks section of the configuration file
+type sinkConfig struct {
+	Type       string `yaml:"type"`
+	URL        string `yaml:"url"`
+	RoutingKey string `yaml:"routing_key"`
+	APIKey     string `yaml:"api_key"`
+	Severity   string `yaml:"severity"`
+	Priority   string `yaml:"priority"`
+	Site       string `yaml:"site"`     // datadog site, e.g. datadoghq.eu
+	Kind       string `yaml:"kind"`     // datadog destination: events or logs
+	Index      string `yaml:"index"`    // elasticsearch index (default trufflehog-alerts)
+	User       string `yaml:"user"`     // elasticsearch basic authentication
+	Password   string `yaml:"password"` // elasticsearch basic authentication
+
+	MaxFindings int  `yaml:"max_findings"` // findings listed in chat summaries (default 10)
+	PerFinding  bool `yaml:"per_finding"`  // also post one message per finding (discord)
+}
+
+// Destination for fired alerts
+type alertSink interface {
+	send(ctx context.Context, rule string, alerts []firedAlert) error
+}
+
+// Create the sink described by a configuration entry
+func newAlertSink(cfg sinkConfig) (alertSink, error) {
+	switch cfg.Type {
+	case "pagerduty":
+		if cfg.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty sink requires routing_key")
+		}
+		return &pagerDutySink{cfg: cfg}, nil
+	case "teams":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("teams sink requires url")
+		}
+		return &teamsSink{cfg: cfg}, nil
+	case "discord":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("discord sink requires url")
+		}
+		return &discordSink{cfg: cfg}, nil
+	case "opsgenie":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("opsgenie sink requires api_key")
+		}
+		return &opsgenieSink{cfg: cfg}, nil
+	case "alertmanager":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("alertmanager sink requires url")
+		}
+		return &alertmanagerSink{cfg: cfg}, nil
+	case "datadog":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("datadog sink requires api_key")
+		}
+		if cfg.Kind != "" && cfg.Kind != "events" && cfg.Kind != "logs" {
+			return nil, fmt.Errorf("datadog sink kind must be events or logs")
+		}
+		return &datadogSink{cfg: cfg}, nil
+	case "elasticsearch":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("elasticsearch sink requires url")
+		}
+		return &elasticsearchSink{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("unsupported sink type %q", cfg.Type)
+}
+
+// Deliver fired alerts to the sinks listed by their rules, logging failures
+func dispatchAlerts(ctx context.Context, cfg *config, alerts []firedAlert) {
+	byRule := map[string][]firedAlert{}
+	for _, alert := range alerts {
+		byRule[alert.Rule] = append(byRule[alert.Rule], alert)
+	}
+
+	for _, rule := range cfg.Alerts {
+		fired := byRule[rule.Name]
+		if len(fired) == 0 {
+			continue
+		}
+		for _, name := range rule.Sinks {
+			sinkCfg, ok := cfg.Sinks[name]
+			if !ok {
+				log.Printf("Alert %s: unknown sink %q", rule.Name, name)
+				continue
+			}
+			sink, err := newAlertSink(sinkCfg)
+			if err != nil {
+				log.Printf("Alert %s: sink %s: %v", rule.Name, name, err)
+				continue
+			}
+			sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := sink.send(sendCtx, rule.Name, fired); err != nil {
+				log.Printf("Alert %s: sink %s: %v", rule.Name, name, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Findings queued for sinks while a search runs, by the rule that routed them
+// and the sink receiving them: the routes of a -policy and the -sink of each
+// profile. Dispatch may be called as often as needed; each finding is sent once.
+type sinkRouter struct {
+	cfg    *config
+	mu     sync.Mutex
+	routed map[sinkRoute][]firedAlert
+}
+
+// How often -watch sends the findings routed so far
+const sinkDispatchInterval = 10 * time.Second
+
+type sinkRoute struct {
+	rule string
+	sink string
+}
+
+func newSinkRouter(cfg *config) *sinkRouter {
+	return &sinkRouter{cfg: cfg, routed: map[sinkRoute][]firedAlert{}}
+}
+
+// Queue a finding for each of the sinks
+func (r *sinkRouter) add(rule string, sinks []string, data JSONData) {
+	flat := flattenFinding(data, "", 0)
+	alert := firedAlert{
+		Rule:        rule,
+		Fingerprint: fingerprint(data, occurrenceFields),
+		Detector:    flat.DetectorName,
+		Repository:  flat.Repository,
+		Verified:    flat.Verified,
+		Finding:     data,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sink := range sinks {
+		route := sinkRoute{rule, sink}
+		r.routed[route] = append(r.routed[route], alert)
+	}
+}
+
+// Send the queued findings to their sinks, logging failures
+func (r *sinkRouter) dispatch(ctx context.Context) {
+	r.mu.Lock()
+	routed := r.routed
+	r.routed = map[sinkRoute][]firedAlert{}
+	r.mu.Unlock()
+
+	routes := make([]sinkRoute, 0, len(routed))
+	for route := range routed {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].rule != routes[j].rule {
+			return routes[i].rule < routes[j].rule
+		}
+		return routes[i].sink < routes[j].sink
+	})
+	for _, route := range routes {
+		sinkCfg, ok := r.cfg.Sinks[route.sink]
+		if !ok {
+			log.Printf("Alert %s: unknown sink %q", route.rule, route.sink)
+			continue
+		}
+		sink, err := newAlertSink(sinkCfg)
+		if err != nil {
+			log.Printf("Alert %s: sink %s: %v", route.rule, route.sink, err)
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if err := sink.send(sendCtx, route.rule, routed[route]); err != nil {
+			log.Printf("Alert %s: sink %s: %v", route.rule, route.sink, err)
+		}
+		cancel()
+	}
+}
+
+// Finding details safe to send to third parties: the secret itself is never included
+func alertDetails(alert firedAlert) map[string]interface{} {
+	flat := flattenFinding(alert.Finding, "", 0)
+	return map[string]interface{}{
+		"detector":    flat.DetectorName,
+		"verified":    flat.Verified,
+		"redacted":    flat.Redacted,
+		"repository":  flat.Repository,
+		"commit":      flat.Commit,
+		"file":        flat.File,
+		"line":        flat.Line,
+		"link":        flat.Link,
+		"fingerprint": alert.Fingerprint,
+	}
+}
+
+// Count alerts per detector, most frequent first, e.g. "AWS: 3, Slack: 1"
+func detectorSummary(alerts []firedAlert) string {
+	counts := map[string]int{}
+	var detectors []string
+	for _, alert := range alerts {
+		if counts[alert.Detector] == 0 {
+			detectors = append(detectors, alert.Detector)
+		}
+		counts[alert.Detector]++
+	}
+	sort.SliceStable(detectors, func(i, j int) bool { return counts[detectors[i]] > counts[detectors[j]] })
+	parts := make([]string, len(detectors))
+	for i, detector := range detectors {
+		parts[i] = fmt.Sprintf("%s: %d", detector, counts[detector])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// The first alerts to list in a chat summary, verified findings first
+func topAlerts(alerts []firedAlert, limit int) []firedAlert {
+	if limit <= 0 {
+		limit = 10
+	}
+	top := append([]firedAlert(nil), alerts...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Verified && !top[j].Verified })
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// One-line description of an alert's location
+func alertLocation(alert firedAlert) string {
+	details := alertDetails(alert)
+	location := fmt.Sprintf("%v", details["repository"])
+	if file := fmt.Sprintf("%v", details["file"]); file != "" {
+		location += " " + file
+		if line, _ := details["line"].(int64); line > 0 {
+			location += fmt.Sprintf(":%d", line)
+		}
+	}
+	return location
+}
+
+// POST a JSON payload and fail on non-2xx responses
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// PagerDuty Events API v2 sink. Dedup keys are the finding fingerprints, so
+// repeated runs update the existing incident instead of paging again.
+type pagerDutySink struct {
+	cfg sinkConfig
+}
+
+func (p *pagerDutySink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	url := p.cfg.URL
+	if url == "" {
+		url = "https://events.pagerduty.com/v2/enqueue"
+	}
+	severity := p.cfg.Severity
+	if severity == "" {
+		severity = "critical"
+	}
+
+	for _, alert := range alerts {
+		event := map[string]interface{}{
+			"routing_key":  p.cfg.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    alert.Fingerprint,
+			"payload": map[string]interface{}{
+				"summary":        fmt.Sprintf("[%s] %s secret found in %s", rule, alert.Detector, alert.Repository),
+				"source":         "trufflehog-searcher",
+				"severity":       severity,
+				"component":      alert.Detector,
+				"group":          alert.Repository,
+				"custom_details": alertDetails(alert),
+			},
+		}
+		if err := postJSON(ctx, url, nil, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Microsoft Teams incoming webhook sink posting an adaptive card
+type teamsSink struct {
+	cfg sinkConfig
+}
+
+func (t *teamsSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "size": "Large", "weight": "Bolder", "text": fmt.Sprintf("Secret alert: %s", rule)},
+		map[string]interface{}{"type": "FactSet", "facts": []interface{}{
+			map[string]interface{}{"title": "Findings", "value": fmt.Sprintf("%d", len(alerts))},
+			map[string]interface{}{"title": "Detectors", "value": detectorSummary(alerts)},
+		}},
+	}
+	top := topAlerts(alerts, t.cfg.MaxFindings)
+	for _, alert := range top {
+		verified := "unverified"
+		if alert.Verified {
+			verified = "**verified**"
+		}
+		body = append(body, map[string]interface{}{
+			"type":      "TextBlock",
+			"wrap":      true,
+			"separator": true,
+			"text":      fmt.Sprintf("%s (%s) `%v` in %s", alert.Detector, verified, alertDetails(alert)["redacted"], alertLocation(alert)),
+		})
+	}
+	if len(alerts) > len(top) {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "isSubtle": true, "text": fmt.Sprintf("... and %d more", len(alerts)-len(top))})
+	}
+
+	message := map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{map[string]interface{}{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+	return postJSON(ctx, t.cfg.URL, nil, message)
+}
+
+// Discord webhook sink posting a summary embed and, optionally, one message per finding
+type discordSink struct {
+	cfg sinkConfig
+}
+
+func (d *discordSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	top := topAlerts(alerts, d.cfg.MaxFindings)
+	var lines []string
+	for _, alert := range top {
+		lines = append(lines, fmt.Sprintf("- %s `%v` in %s", alert.Detector, alertDetails(alert)["redacted"], alertLocation(alert)))
+	}
+	if len(alerts) > len(top) {
+		lines = append(lines, fmt.Sprintf("... and %d more", len(alerts)-len(top)))
+	}
+
+	summary := map[string]interface{}{
+		"username": "trufflehog-searcher",
+		"embeds": []interface{}{map[string]interface{}{
+			"title":       fmt.Sprintf("Secret alert: %s", rule),
+			"description": strings.Join(lines, "\n"),
+			"color":       0xE01E5A,
+			"fields": []interface{}{
+				map[string]interface{}{"name": "Findings", "value": fmt.Sprintf("%d", len(alerts)), "inline": true},
+				map[string]interface{}{"name": "Detectors", "value": detectorSummary(alerts), "inline": true},
+			},
+		}},
+	}
+	if err := postJSON(ctx, d.cfg.URL, nil, summary); err != nil {
+		return err
+	}
+	if !d.cfg.PerFinding {
+		return nil
+	}
+
+	for _, alert := range top {
+		details := alertDetails(alert)
+		var fields []interface{}
+		for _, key := range []string{"detector", "verified", "redacted", "repository", "file", "line", "commit"} {
+			if value := fmt.Sprintf("%v", details[key]); value != "" {
+				fields = append(fields, map[string]interface{}{"name": key, "value": value, "inline": key != "repository" && key != "commit"})
+			}
+		}
+		embed := map[string]interface{}{
+			"title":  fmt.Sprintf("%s secret (%s)", alert.Detector, rule),
+			"fields": fields,
+			"footer": map[string]interface{}{"text": "fingerprint " + alert.Fingerprint[:12]},
+		}
+		if link, _ := details["link"].(string); link != "" {
+			embed["url"] = link
+		}
+		message := map[string]interface{}{"username": "trufflehog-searcher", "embeds": []interface{}{embed}}
+		if err := postJSON(ctx, d.cfg.URL, nil, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Opsgenie Alert API sink. The fingerprint is used as alias so repeated runs
+// are deduplicated into the open alert.
+type opsgenieSink struct {
+	cfg sinkConfig
+}
+
+func (o *opsgenieSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	url := o.cfg.URL
+	if url == "" {
+		url = "https://api.opsgenie.com/v2/alerts"
+	}
+	priority := o.cfg.Priority
+	if priority == "" {
+		priority = "P1"
+	}
+
+	for _, alert := range alerts {
+		details := map[string]string{}
+		for key, value := range alertDetails(alert) {
+			details[key] = fmt.Sprintf("%v", value)
+		}
+		message := fmt.Sprintf("[%s] %s secret found in %s", rule, alert.Detector, alert.Repository)
+		if len(message) > 130 {
+			message = message[:130]
+		}
+		payload := map[string]interface{}{
+			"message":     message,
+			"alias":       alert.Fingerprint,
+			"description": fmt.Sprintf("%s secret in %s", alert.Detector, alertLocation(alert)),
+			"tags":        []string{"trufflehog", rule, alert.Detector},
+			"details":     details,
+			"priority":    priority,
+			"source":      "trufflehog-searcher",
+		}
+		if err := postJSON(ctx, url, map[string]string{"Authorization": "GenieKey " + o.cfg.APIKey}, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prometheus Alertmanager v2 API sink. Alertmanager groups and deduplicates
+// by labels, which include the finding fingerprint.
+type alertmanagerSink struct {
+	cfg sinkConfig
+}
+
+func (a *alertmanagerSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	severity := a.cfg.Severity
+	if severity == "" {
+		severity = "critical"
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	payload := make([]interface{}, 0, len(alerts))
+	for _, alert := range alerts {
+		payload = append(payload, map[string]interface{}{
+			"startsAt": now,
+			"labels": map[string]string{
+				"alertname":   rule,
+				"severity":    severity,
+				"detector":    alert.Detector,
+				"repository":  alert.Repository,
+				"verified":    fmt.Sprintf("%t", alert.Verified),
+				"fingerprint": alert.Fingerprint,
+			},
+			"annotations": map[string]string{
+				"summary":     fmt.Sprintf("%s secret found in %s", alert.Detector, alert.Repository),
+				"description": fmt.Sprintf("%s secret %v in %s", alert.Detector, alertDetails(alert)["redacted"], alertLocation(alert)),
+			},
+		})
+	}
+	return postJSON(ctx, strings.TrimSuffix(a.cfg.URL, "/")+"/api/v2/alerts", nil, payload)
+}
+
+// Datadog sink sending one event (or log entry) per finding, tagged so
+// findings can drive Datadog monitors next to infrastructure data
+type datadogSink struct {
+	cfg sinkConfig
+}
+
+func (d *datadogSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	site := d.cfg.Site
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	headers := map[string]string{"DD-API-KEY": d.cfg.APIKey}
+
+	if d.cfg.Kind == "logs" {
+		url := d.cfg.URL
+		if url == "" {
+			url = "https://http-intake.logs." + site + "/api/v2/logs"
+		}
+		entries := make([]interface{}, 0, len(alerts))
+		for _, alert := range alerts {
+			entry := alertDetails(alert)
+			entry["ddsource"] = "trufflehog-searcher"
+			entry["service"] = "trufflehog-searcher"
+			entry["ddtags"] = strings.Join(datadogTags(rule, alert), ",")
+			entry["message"] = fmt.Sprintf("[%s] %s secret found in %s", rule, alert.Detector, alertLocation(alert))
+			entries = append(entries, entry)
+		}
+		return postJSON(ctx, url, headers, entries)
+	}
+
+	url := d.cfg.URL
+	if url == "" {
+		url = "https://api." + site + "/api/v1/events"
+	}
+	for _, alert := range alerts {
+		alertType := "warning"
+		if alert.Verified {
+			alertType = "error"
+		}
+		event := map[string]interface{}{
+			"title":            fmt.Sprintf("[%s] %s secret found in %s", rule, alert.Detector, alert.Repository),
+			"text":             fmt.Sprintf("%s secret %v in %s", alert.Detector, alertDetails(alert)["redacted"], alertLocation(alert)),
+			"tags":             datadogTags(rule, alert),
+			"alert_type":       alertType,
+			"aggregation_key":  alert.Fingerprint,
+			"source_type_name": "trufflehog-searcher",
+		}
+		if err := postJSON(ctx, url, headers, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tags attached to Datadog events and logs
+func datadogTags(rule string, alert firedAlert) []string {
+	tags := []string{
+		"rule:" + rule,
+		"detector:" + strings.ToLower(alert.Detector),
+		fmt.Sprintf("verified:%t", alert.Verified),
+	}
+	if alert.Repository != "" {
+		tags = append(tags, "repo:"+layoutFileName(alert.Repository))
+	}
+	return tags
+}
+
+// Elasticsearch or OpenSearch sink indexing one document per finding, keyed
+// by its fingerprint so a finding routed again updates its document
+type elasticsearchSink struct {
+	cfg sinkConfig
+}
+
+func (e *elasticsearchSink) send(ctx context.Context, rule string, alerts []firedAlert) error {
+	index := e.cfg.Index
+	if index == "" {
+		index = "trufflehog-alerts"
+	}
+	indexer := &bulkIndexer{url: e.cfg.URL, index: index, batch: 500, retries: 3, user: e.cfg.User, password: e.cfg.Password, apiKey: e.cfg.APIKey}
+	for _, alert := range alerts {
+		doc := alertDetails(alert)
+		doc["rule"] = rule
+		doc["finding"] = searcher.Redact(alert.Finding, searcher.DefaultRedactFields)
+		if err := indexer.add(ctx, alert.Fingerprint, doc); err != nil {
+			return err
+		}
+	}
+	if err := indexer.flush(ctx); err != nil {
+		return err
+	}
+	if indexer.failed > 0 {
+		return fmt.Errorf("%d of %d document(s) rejected by %s", indexer.failed, len(alerts), index)
+	}
+	return nil
+}
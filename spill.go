@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Matches of -group-by or -dedupe held under -max-memory. They are kept
+// encoded in memory until they pass the limit, then sorted by key and written
+// to a temporary file; once the search is done the files are merge-sorted
+// into one, where the matches of each key follow each other.
+type spillStore struct {
+	limit   int64
+	pending []spillEntry
+	size    int64    // bytes held by pending
+	runs    []string // sorted temporary files
+	counts  map[string]int
+	seq     int
+
+	sorted  bool
+	merged  *os.File
+	offsets map[string]int64 // where the matches of a key start in merged
+	starts  map[string]int   // or in pending, when nothing was spilled
+}
+
+// One match with what it is ordered by: its key, then its rank, then arrival
+type spillEntry struct {
+	Key   string       `json:"key"`
+	Rank  int          `json:"rank"`
+	Seq   int          `json:"seq"`
+	Match spilledMatch `json:"match"`
+	line  []byte       // the entry as JSON
+}
+
+func newSpillStore(limit int64) *spillStore {
+	return &spillStore{limit: limit, counts: map[string]int{}}
+}
+
+func (s *spillStore) add(key string, rank int, m *searchMatch) error {
+	entry := spillEntry{Key: key, Rank: rank, Seq: s.seq, Match: newSpilledMatch(m)}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	entry.line, entry.Match = append(line, '\n'), spilledMatch{}
+	s.seq++
+	s.counts[key]++
+	s.pending = append(s.pending, entry)
+	s.size += int64(len(entry.line))
+	if s.size > s.limit {
+		return s.spill()
+	}
+	return nil
+}
+
+func (s *spillStore) sortPending() {
+	sort.Slice(s.pending, func(i, j int) bool { return s.pending[i].less(s.pending[j]) })
+}
+
+func (e spillEntry) less(other spillEntry) bool {
+	if e.Key != other.Key {
+		return e.Key < other.Key
+	}
+	if e.Rank != other.Rank {
+		return e.Rank < other.Rank
+	}
+	return e.Seq < other.Seq
+}
+
+// Write the pending matches, sorted, to a new temporary file
+func (s *spillStore) spill() error {
+	s.sortPending()
+	file, err := os.CreateTemp("", "ths-spill-")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, file.Name())
+	w := bufio.NewWriter(file)
+	for _, entry := range s.pending {
+		if _, err := w.Write(entry.line); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	s.pending, s.size = nil, 0
+	return file.Close()
+}
+
+// Sort what is left in memory or, once something was spilled, merge every
+// file into one and index where each key starts
+func (s *spillStore) finish() error {
+	if s.sorted {
+		return nil
+	}
+	s.sorted = true
+	if len(s.runs) == 0 {
+		s.sortPending()
+		s.starts = map[string]int{}
+		for i := len(s.pending) - 1; i >= 0; i-- {
+			s.starts[s.pending[i].Key] = i
+		}
+		return nil
+	}
+	if len(s.pending) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+
+	merged, err := os.CreateTemp("", "ths-spill-")
+	if err != nil {
+		return err
+	}
+	s.merged, s.offsets = merged, map[string]int64{}
+	runs := &spillRuns{}
+	for _, path := range s.runs {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		run := &spillRun{r: bufio.NewReader(file)}
+		if ok, err := run.next(); err != nil {
+			return err
+		} else if ok {
+			runs.items = append(runs.items, run)
+		}
+	}
+	heap.Init(runs)
+	w := bufio.NewWriter(merged)
+	var offset int64
+	for runs.Len() > 0 {
+		run := runs.items[0]
+		if _, seen := s.offsets[run.entry.Key]; !seen {
+			s.offsets[run.entry.Key] = offset
+		}
+		n, err := w.Write(run.entry.line)
+		if err != nil {
+			return err
+		}
+		offset += int64(n)
+		if ok, err := run.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(runs, 0)
+		} else {
+			heap.Pop(runs)
+		}
+	}
+	return w.Flush()
+}
+
+// Call fn with the matches of a key, by rank then in the order they were added
+func (s *spillStore) each(key string, fn func(*searchMatch)) error {
+	if err := s.finish(); err != nil {
+		return err
+	}
+	count := s.counts[key]
+	if s.merged == nil {
+		start, ok := s.starts[key]
+		if !ok {
+			return nil
+		}
+		for _, entry := range s.pending[start : start+count] {
+			m, err := decodeSpillEntry(entry.line)
+			if err != nil {
+				return err
+			}
+			fn(m)
+		}
+		return nil
+	}
+	offset, ok := s.offsets[key]
+	if !ok {
+		return nil
+	}
+	r := bufio.NewReader(io.NewSectionReader(s.merged, offset, 1<<62))
+	for i := 0; i < count; i++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		m, err := decodeSpillEntry(line)
+		if err != nil {
+			return err
+		}
+		fn(m)
+	}
+	return nil
+}
+
+// Remove the temporary files
+func (s *spillStore) close() {
+	for _, path := range s.runs {
+		os.Remove(path)
+	}
+	if s.merged != nil {
+		s.merged.Close()
+		os.Remove(s.merged.Name())
+	}
+}
+
+func decodeSpillEntry(line []byte) (*searchMatch, error) {
+	var entry spillEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, err
+	}
+	return entry.Match.match(), nil
+}
+
+// A sorted temporary file being merged, positioned on its next entry
+type spillRun struct {
+	r     *bufio.Reader
+	entry spillEntry
+}
+
+func (run *spillRun) next() (bool, error) {
+	line, err := run.r.ReadBytes('\n')
+	if err == io.EOF && len(line) == 0 {
+		return false, nil
+	}
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	// Only the ordering fields are needed to merge; the match is copied as it is
+	var header struct {
+		Key  string `json:"key"`
+		Rank int    `json:"rank"`
+		Seq  int    `json:"seq"`
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return false, err
+	}
+	run.entry = spillEntry{Key: header.Key, Rank: header.Rank, Seq: header.Seq, line: bytes.Clone(line)}
+	return true, nil
+}
+
+// The runs ordered by their next entry, for container/heap
+type spillRuns struct {
+	items []*spillRun
+}
+
+func (h *spillRuns) Len() int           { return len(h.items) }
+func (h *spillRuns) Less(i, j int) bool { return h.items[i].entry.less(h.items[j].entry) }
+func (h *spillRuns) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *spillRuns) Push(x interface{}) { h.items = append(h.items, x.(*spillRun)) }
+func (h *spillRuns) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// Everything a match carries to the outputs, with exported fields to encode
+type spilledMatch struct {
+	Source        string           `json:"source"`
+	Line          int              `json:"line"`
+	SourceFile    string           `json:"source_file"`
+	SourceLine    int              `json:"source_line"`
+	Location      string           `json:"location"`
+	Data          JSONData         `json:"data"`
+	Terms         []string         `json:"terms,omitempty"`
+	Fingerprint   string           `json:"fingerprint,omitempty"`
+	Decision      policyDecision   `json:"decision"`
+	Policy        bool             `json:"policy,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	Note          string           `json:"note,omitempty"`
+	IgnoredBy     string           `json:"ignored_by,omitempty"`
+	Link          string           `json:"link,omitempty"`
+	Blame         *blameInfo       `json:"blame,omitempty"`
+	Anomaly       string           `json:"anomaly,omitempty"`
+	Risk          float64          `json:"risk,omitempty"`
+	FalsePositive fpScore          `json:"false_positive"`
+	Expiry        expiryInfo       `json:"expiry"`
+	ThirdParty    string           `json:"third_party,omitempty"`
+	FieldPath     string           `json:"field_path,omitempty"`
+	Explain       []searcher.Check `json:"explain,omitempty"`
+	Warnings      []string         `json:"warnings,omitempty"`
+}
+
+func newSpilledMatch(m *searchMatch) spilledMatch {
+	return spilledMatch{
+		Source: m.source, Line: m.line, SourceFile: m.sourceFile, SourceLine: m.sourceLine, Location: m.location,
+		Data: m.data, Terms: m.terms, Fingerprint: m.fingerprint, Decision: m.decision, Policy: m.policy,
+		Status: m.status, Note: m.note, IgnoredBy: m.ignoredBy, Link: m.link, Blame: m.blame, Anomaly: m.anomaly, Risk: m.risk,
+		FalsePositive: m.falsePositive, Expiry: m.expiry, ThirdParty: m.thirdParty, FieldPath: m.fieldPath, Explain: m.explain, Warnings: m.warnings,
+	}
+}
+
+func (s spilledMatch) match() *searchMatch {
+	return &searchMatch{
+		source: s.Source, line: s.Line, sourceFile: s.SourceFile, sourceLine: s.SourceLine, location: s.Location,
+		data: s.Data, terms: s.Terms, fingerprint: s.Fingerprint, decision: s.Decision, policy: s.Policy,
+		status: s.Status, note: s.Note, ignoredBy: s.IgnoredBy, link: s.Link, blame: s.Blame, anomaly: s.Anomaly, risk: s.Risk,
+		falsePositive: s.FalsePositive, expiry: s.Expiry, thirdParty: s.ThirdParty, fieldPath: s.FieldPath, explain: s.Explain, warnings: s.Warnings,
+	}
+}
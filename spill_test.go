@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSpillStore(t *testing.T) {
+	type added struct {
+		key  string
+		rank int
+		line int
+	}
+	input := []added{
+		{"b", 1, 1}, {"a", 0, 2}, {"b", 0, 3}, {"c", 0, 4}, {"a", 0, 5},
+		{"b", 1, 6}, {"a", 2, 7}, {"c", 0, 8}, {"b", 0, 9}, {"a", 1, 10},
+	}
+	// Matches of each key by rank, then in the order they were added
+	want := map[string][]int{"a": {2, 5, 10, 7}, "b": {3, 9, 1, 6}, "c": {4, 8}, "d": nil}
+
+	tests := []struct {
+		name   string
+		limit  int64
+		spills bool
+	}{
+		{"in memory", 1 << 20, false},
+		{"spilling every match", 1, true},
+		{"spilling every few matches", 2000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newSpillStore(tt.limit)
+			defer store.close()
+			for _, in := range input {
+				m := &searchMatch{source: "scan.json", line: in.line, data: JSONData{"DetectorName": "AWS", "Raw": fmt.Sprintf("secret-%d", in.line)}, terms: []string{"acme"}}
+				if err := store.add(in.key, in.rank, m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if spilled := len(store.runs) > 0; spilled != tt.spills {
+				t.Fatalf("spilled = %v, want %v", spilled, tt.spills)
+			}
+			for key, lines := range want {
+				var got []int
+				err := store.each(key, func(m *searchMatch) {
+					got = append(got, m.line)
+					if raw := fmt.Sprintf("secret-%d", m.line); m.data["Raw"] != raw || m.source != "scan.json" || !reflect.DeepEqual(m.terms, []string{"acme"}) {
+						t.Errorf("match of line %d read back as %+v", m.line, m)
+					}
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, lines) {
+					t.Errorf("each(%q) = %v, want %v", key, got, lines)
+				}
+			}
+		})
+	}
+}
+
+func TestSpillStoreRemovesFiles(t *testing.T) {
+	store := newSpillStore(1)
+	for i := range 3 {
+		if err := store.add("key", 0, &searchMatch{line: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.finish(); err != nil {
+		t.Fatal(err)
+	}
+	files := append([]string{store.merged.Name()}, store.runs...)
+	store.close()
+	for _, path := range files {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", path, err)
+		}
+	}
+}
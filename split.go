@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Split one large JSON lines file into line-aligned chunks
+func runSplit(args []string) {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	inFile := fs.String("i", "", "Input JSON trufflehog output file (required)")
+	outDir := fs.String("o", "", "Output directory for the chunks (required)")
+	maxLines := fs.Int("lines", 0, "Maximum number of lines per chunk")
+	maxBytes := fs.Int64("bytes", 0, "Maximum number of bytes per chunk (a single longer line gets its own chunk)")
+	compress := fs.Bool("gzip", false, "Gzip-compress the chunks")
+	fs.Parse(args)
+
+	if *inFile == "" || *outDir == "" {
+		fmt.Println("Error: -i and -o are required parameters.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if (*maxLines > 0) == (*maxBytes > 0) {
+		fmt.Println("Error: exactly one of --lines or --bytes must be set to a positive value.")
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Printf("Error creating directory %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	chunks, err := splitFile(*inFile, *outDir, *maxLines, *maxBytes, *compress)
+	if err != nil {
+		fmt.Printf("Error splitting file %s: %v\n", *inFile, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d chunk(s) to %s\n", chunks, *outDir)
+}
+
+// Chunk file currently being written by splitFile
+type splitChunk struct {
+	file  *os.File
+	gz    *gzip.Writer
+	out   *bufio.Writer
+	lines int
+	bytes int64
+}
+
+func (c *splitChunk) close() error {
+	if err := c.out.Flush(); err != nil {
+		return err
+	}
+	if c.gz != nil {
+		if err := c.gz.Close(); err != nil {
+			return err
+		}
+	}
+	return c.file.Close()
+}
+
+// Copy lines into numbered chunks, starting a new chunk whenever a limit would be exceeded
+func splitFile(inFile, outDir string, maxLines int, maxBytes int64, compress bool) (int, error) {
+	fileHandle, err := openInput(inFile)
+	if err != nil {
+		return 0, err
+	}
+	defer fileHandle.Close()
+
+	base := strings.TrimSuffix(trimCompressionExt(filepath.Base(inFile)), ".json")
+	ext := ".json"
+	if compress {
+		ext += ".gz"
+	}
+
+	chunks := 0
+	var chunk *splitChunk
+	openChunk := func() error {
+		chunks++
+		file, err := os.Create(filepath.Join(outDir, fmt.Sprintf("%s.part%04d%s", base, chunks, ext)))
+		if err != nil {
+			return err
+		}
+		chunk = &splitChunk{file: file}
+		if compress {
+			chunk.gz = gzip.NewWriter(file)
+			chunk.out = bufio.NewWriter(chunk.gz)
+		} else {
+			chunk.out = bufio.NewWriter(file)
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(fileHandle)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			full := chunk != nil && ((maxLines > 0 && chunk.lines >= maxLines) ||
+				(maxBytes > 0 && chunk.lines > 0 && chunk.bytes+int64(len(line)) > maxBytes))
+			if full {
+				if err := chunk.close(); err != nil {
+					return chunks, err
+				}
+				chunk = nil
+			}
+			if chunk == nil {
+				if err := openChunk(); err != nil {
+					return chunks, err
+				}
+			}
+			if _, err := chunk.out.Write(line); err != nil {
+				return chunks, err
+			}
+			chunk.lines++
+			chunk.bytes += int64(len(line))
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return chunks, readErr
+		}
+	}
+
+	if chunk != nil {
+		return chunks, chunk.close()
+	}
+	return chunks, nil
+}
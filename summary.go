@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+)
+
+// Match counts per detector, repository, verified status and file extension for -stats
+type searchStats struct {
+	matched      int
+	detectors    map[string]int
+	repositories map[string]int
+	verified     map[string]int
+	extensions   map[string]int
+	perWorker    bool // -stats-verbose: the counters of each worker of the pool
+}
+
+func newSearchStats() *searchStats {
+	return &searchStats{detectors: map[string]int{}, repositories: map[string]int{}, verified: map[string]int{}, extensions: map[string]int{}}
+}
+
+func (s *searchStats) add(m *searchMatch) {
+	if m.ignoredBy != "" {
+		return
+	}
+	flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+	s.matched++
+	s.detectors[orNone(flat.DetectorName)]++
+	s.repositories[orNone(flat.Repository)]++
+	s.verified[strconv.FormatBool(flat.Verified)]++
+	s.extensions[orNone(path.Ext(flat.File))]++
+}
+
+// Placeholder for findings without a value
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// Write the summary as tables, or as one JSON object with -o json
+func (s *searchStats) write(w io.Writer, format string, metrics *runMetrics) {
+	scanned, parseErrors := metrics.lines.Load()-metrics.parseErrors.Load(), metrics.parseErrors.Load()
+	if format == "json" {
+		lineRate, byteRate := throughput(metrics.lines.Load(), metrics.bytes.Load(), time.Since(metrics.start))
+		summary := map[string]interface{}{
+			"files": metrics.filesDone.Load(), "scanned": scanned, "matched": s.matched, "parse_errors": parseErrors,
+			"bytes": metrics.bytes.Load(), "lines_per_second": lineRate, "mb_per_second": byteRate,
+			"detectors": s.detectors, "repositories": s.repositories, "verified": s.verified, "extensions": s.extensions,
+		}
+		if s.perWorker {
+			summary["per_worker"] = metrics.workerStats()
+		}
+		writeJSONLine(w, summary)
+		return
+	}
+
+	fmt.Fprintf(w, "Matched %d of %d findings in %d files (%d parse errors)\n", s.matched, scanned, metrics.filesDone.Load(), parseErrors)
+	fmt.Fprintf(w, "Read %s\n", metrics.throughputSummary())
+	for _, table := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"DetectorName", s.detectors},
+		{"Repository", s.repositories},
+		{"Verified", s.verified},
+		{"File extension", s.extensions},
+	} {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tCount\n", table.title)
+		for _, entry := range sortedCounts(table.counts) {
+			fmt.Fprintf(tw, "%s\t%d\n", entry.value, entry.count)
+		}
+		tw.Flush()
+	}
+	if s.perWorker {
+		writeWorkerStats(w, metrics.workerStats())
+	}
+}
+
+// One row per worker; a worker busy far longer than the others, with few
+// files, was held up by a huge file while the rest of the pool sat idle
+func writeWorkerStats(w io.Writer, workers []workerStats) {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Worker\tFiles\tLines\tMatches\tMB\tBusy\tBusy %%\t\n")
+	for _, worker := range workers {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%.1f\t%s\t%.0f%%\t\n", worker.Worker, worker.Files, worker.Lines, worker.Matches,
+			float64(worker.Bytes)/1e6, (time.Duration(worker.BusyMS) * time.Millisecond).Round(time.Millisecond), worker.BusyPercent)
+	}
+	tw.Flush()
+}
+
+// A value with its number of occurrences
+type valueCount struct {
+	value string
+	count int
+}
+
+// Counts ordered by decreasing count, then by value
+func sortedCounts(counts map[string]int) []valueCount {
+	entries := make([]valueCount, 0, len(counts))
+	for value, count := range counts {
+		entries = append(entries, valueCount{value, count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].value < entries[j].value
+	})
+	return entries
+}
+
+// Print the collected matches grouped by -group-by, largest groups first
+func (p *printer) writeGroups() {
+	counts := map[string]int{}
+	groups := map[string][]*searchMatch{}
+	each := func(key string, fn func(*searchMatch)) error {
+		for _, m := range groups[key] {
+			fn(m)
+		}
+		return nil
+	}
+	if p.groups != nil {
+		counts, each = p.groups.counts, p.groups.each
+	} else {
+		for _, m := range p.collected {
+			value, _ := searcher.Lookup(m.data, p.flags.groupBy)
+			key := orNone(searcher.String(value))
+			groups[key] = append(groups[key], m)
+			counts[key]++
+		}
+	}
+	ordered := sortedCounts(counts)
+	if p.flags.top > 0 && len(ordered) > p.flags.top {
+		ordered = ordered[:p.flags.top]
+	}
+
+	for _, group := range ordered {
+		var err error
+		if p.flags.format == "json" {
+			err = p.writeJSONGroup(group, each)
+		} else {
+			fmt.Fprintf(p.w, "\n=== %s: %s (%d match(es)) ===\n", p.flags.groupBy, group.value, group.count)
+			err = each(group.value, p.emit)
+		}
+		if err != nil {
+			fmt.Fprintf(p.diag, "Error reading spilled matches: %v\n", err)
+			return
+		}
+	}
+}
+
+// One JSON line per group, written a finding at a time so a group read back
+// from disk is never held in memory whole
+func (p *printer) writeJSONGroup(group valueCount, each func(string, func(*searchMatch)) error) error {
+	field, _ := json.Marshal(p.flags.groupBy)
+	value, _ := json.Marshal(group.value)
+	fmt.Fprintf(p.w, `{"count":%d,"field":%s,"findings":[`, group.count, field)
+	first := true
+	var writeErr error
+	err := each(group.value, func(m *searchMatch) {
+		finding, err := json.Marshal(newJSONResult(m, p.display(m.data), p.multiTerm))
+		if err != nil {
+			writeErr = err
+			return
+		}
+		if !first {
+			p.w.Write([]byte{','})
+		}
+		first = false
+		p.w.Write(finding)
+	})
+	fmt.Fprintf(p.w, "],\"value\":%s}\n", value)
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// Width of the largest bar of -histogram
+const histogramWidth = 40
+
+// Print the most frequent values of the -histogram field with a bar each
+func (p *printer) writeHistogram() {
+	ordered := sortedCounts(p.histogram)
+	if p.flags.top > 0 && len(ordered) > p.flags.top {
+		ordered = ordered[:p.flags.top]
+	}
+	if p.flags.format == "json" {
+		for _, entry := range ordered {
+			writeJSONLine(p.w, map[string]interface{}{"field": p.flags.histogram, "value": entry.value, "count": entry.count})
+		}
+		return
+	}
+
+	fmt.Fprintf(p.w, "%s: %d distinct value(s) in %d match(es)\n", p.flags.histogram, len(p.histogram), p.matched)
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for _, entry := range ordered {
+		// Every value gets at least one mark, however rare
+		bar := max(1, entry.count*histogramWidth/ordered[0].count)
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%s\n", entry.value, entry.count, 100*float64(entry.count)/float64(p.matched), strings.Repeat("#", bar))
+	}
+	tw.Flush()
+}
+
+// Unique secrets with every place they were found, for -dedupe
+type dedupeSet struct {
+	fields  []string
+	order   []string
+	entries map[string]*dedupeEntry
+	spill   *spillStore // under -max-memory, instead of entries
+}
+
+type dedupeEntry struct {
+	locations []*searchMatch
+}
+
+func newDedupeSet(fields []string) *dedupeSet {
+	return &dedupeSet{fields: fields, entries: map[string]*dedupeEntry{}}
+}
+
+func (d *dedupeSet) add(m *searchMatch) error {
+	key := fingerprint(m.data, d.fields)
+	if d.spill != nil {
+		if d.spill.counts[key] == 0 {
+			d.order = append(d.order, key)
+		}
+		return d.spill.add(key, 0, m)
+	}
+	entry, ok := d.entries[key]
+	if !ok {
+		entry = &dedupeEntry{}
+		d.entries[key] = entry
+		d.order = append(d.order, key)
+	}
+	entry.locations = append(entry.locations, m)
+	return nil
+}
+
+// Call fn with every occurrence of a secret, in the order they were found
+func (d *dedupeSet) each(key string, fn func(*searchMatch)) error {
+	if d.spill != nil {
+		return d.spill.each(key, fn)
+	}
+	for _, m := range d.entries[key].locations {
+		fn(m)
+	}
+	return nil
+}
+
+// Where one occurrence of a secret was found
+type dedupeLocation struct {
+	SourceFile string `json:"source_file"`
+	SourceLine int    `json:"source_line"`
+	Repository string `json:"repository,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	File       string `json:"file,omitempty"`
+	Line       int64  `json:"line,omitempty"`
+}
+
+func newDedupeLocation(m *searchMatch) dedupeLocation {
+	flat := flattenFinding(m.data, m.sourceFile, m.sourceLine)
+	return dedupeLocation{SourceFile: m.sourceFile, SourceLine: m.sourceLine, Repository: flat.Repository, Commit: flat.Commit, File: flat.File, Line: flat.Line}
+}
+
+func (l dedupeLocation) String() string {
+	parts := []string{fmt.Sprintf("%s:%d", l.SourceFile, l.SourceLine)}
+	for _, part := range []string{l.Repository, l.Commit} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if l.File != "" {
+		parts = append(parts, fmt.Sprintf("%s:%d", l.File, l.Line))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Print each unique secret once, in the order they were first found
+func (p *printer) writeDedupe() {
+	for _, key := range p.dedupe.order {
+		var first *searchMatch
+		var locations []dedupeLocation
+		err := p.dedupe.each(key, func(m *searchMatch) {
+			if first == nil {
+				first = m
+			}
+			locations = append(locations, newDedupeLocation(m))
+		})
+		if err != nil {
+			fmt.Fprintf(p.diag, "Error reading spilled matches: %v\n", err)
+			return
+		}
+		data := p.display(first.data)
+
+		switch p.flags.format {
+		case "text":
+			detector, _ := data["DetectorName"].(string)
+			fmt.Fprintf(p.w, "\n--- %s secret seen %d time(s), fingerprint %s ---\n", orNone(detector), len(locations), key[:16])
+			fmt.Fprintln(p.w, "Locations:")
+			for _, location := range locations {
+				fmt.Fprintf(p.w, "  %s\n", location)
+			}
+			writePrettyJSON(p.w, data)
+		case "json":
+			writeJSONLine(p.w, map[string]interface{}{"fingerprint": key, "occurrences": len(locations), "locations": locations, "finding": data})
+		default:
+			if err := p.writer.WriteFinding(data, first.sourceFile, first.sourceLine); err != nil {
+				fmt.Fprintf(p.diag, "Error writing result: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+
+// -third-party values: show third-party findings, leave them out or keep only them
+var thirdPartyModes = []string{"show", "hide", "only"}
+
+// Paths of vendored dependencies and generated bundles, whose secrets belong
+// to someone else's code and dominate the noise of monorepo scans
+var builtinThirdPartyPaths = []string{
+	"**/vendor/**", "**/node_modules/**", "**/third_party/**", "**/third-party/**", "**/bower_components/**",
+	"*.min.js", "*.min.css",
+}
+
+// Path globs, like -include, marking findings as third-party code
+type thirdPartyPaths []string
+
+// The built-in globs alone, for searches without a configuration
+var builtinThirdParty = newThirdPartyPaths(nil)
+
+func newThirdPartyPaths(extra []string) thirdPartyPaths {
+	return append(append(thirdPartyPaths{}, builtinThirdPartyPaths...), extra...)
+}
+
+// Glob covering the file of a finding, or "" for first-party code
+func (t thirdPartyPaths) match(data JSONData) string {
+	value, _ := searcher.Lookup(data, "file")
+	file := searcher.String(value)
+	if file == "" {
+		return ""
+	}
+	for _, glob := range t {
+		if matchGlob(glob, file) {
+			return glob
+		}
+	}
+	return ""
+}
+
+// Rank of a match where the matches are ordered at the end: first-party
+// findings, then third-party code, then likely false positives
+func (m *searchMatch) downgrade() int {
+	switch {
+	case m.falsePositive.Score >= likelyFalsePositive:
+		return 2
+	case m.thirdParty != "":
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// Triage statuses a finding can be marked with; findings never marked are new
+var triageStatuses = []string{"new", "investigating", "false-positive", "rotated"}
+
+// Triage statuses and notes stored in the findings database, keyed by occurrence fingerprint
+type triageStore struct {
+	db   *sql.DB
+	path string
+}
+
+// Open the triage state of a database. Without create, a missing database
+// means nothing was triaged yet and nil is returned.
+func openTriageStore(path string, create bool) (*triageStore, error) {
+	if !create {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	db, err := openDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", path, err)
+	}
+	return &triageStore{db: db, path: path}, nil
+}
+
+// Stored status and note of a finding, empty when it was never marked
+func (t *triageStore) lookup(fp string) (string, string) {
+	var status, note string
+	t.db.QueryRow("SELECT status, note FROM triage WHERE fingerprint = ?", fp).Scan(&status, &note)
+	return status, note
+}
+
+// Record the status and note of a finding
+func (t *triageStore) mark(fp, status, note string) error {
+	_, err := t.db.Exec(`INSERT INTO triage(fingerprint, status, note, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(fingerprint) DO UPDATE SET status = excluded.status, note = excluded.note, updated_at = excluded.updated_at`,
+		fp, status, note, time.Now().Unix())
+	return err
+}
+
+// Record the status and note of a finding from a run that keeps the database
+// open, taking the database lock for the write so it takes turns with the
+// other writers
+func (t *triageStore) markLocked(fp, status, note string, wait time.Duration) error {
+	lock, err := lockDB(context.Background(), t.path, wait)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	return t.mark(fp, status, note)
+}
+
+func (t *triageStore) Close() error {
+	return t.db.Close()
+}
+
+// Status of a finding for -status filtering: unmarked findings are new
+func triageStatusOrNew(status string) string {
+	if status == "" {
+		return "new"
+	}
+	return status
+}
+
+// Record the triage status of findings given by fingerprint
+func runMark(args []string) {
+	fs := flag.NewFlagSet("mark", flag.ExitOnError)
+	status := fs.String("status", "", "Triage status: 'new', 'investigating', 'false-positive' or 'rotated' (required)")
+	note := fs.String("note", "", "Free-text note stored with the status")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	wait := fs.Duration("wait", 0, "Wait this long for another run using the database to finish instead of failing at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trufflehog-searcher mark -status STATUS [-note TEXT] FINGERPRINT...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !containsString(triageStatuses, *status) {
+		fmt.Println("Error: -status must be 'new', 'investigating', 'false-positive' or 'rotated'.")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		fmt.Println("Error: at least one fingerprint is required.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	lock, err := lockDB(context.Background(), *dbPath, *wait)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer lock.Close()
+	store, err := openTriageStore(*dbPath, true)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	for _, fp := range fs.Args() {
+		if err := store.mark(fp, *status, *note); err != nil {
+			fmt.Printf("Error marking %s: %v\n", fp, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Marked %d finding(s) as %s\n", fs.NArg(), *status)
+}
+
+// List the findings triaged so far
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	status := fs.String("status", "", "Only list findings with this triage status")
+	dbPath := fs.String("db", defaultDBPath(), "Path of the findings database")
+	fs.Parse(args)
+
+	store, err := openTriageStore(*dbPath, false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if store == nil {
+		fmt.Println("No triaged findings")
+		return
+	}
+	defer store.Close()
+
+	rows, err := store.db.Query(`SELECT fingerprint, status, note, updated_at FROM triage
+		WHERE ? = '' OR status = ? ORDER BY updated_at DESC`, *status, *status)
+	if err != nil {
+		fmt.Printf("Error reading database: %v\n", err)
+		os.Exit(1)
+	}
+	defer rows.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Fingerprint\tStatus\tUpdated\tNote")
+	for rows.Next() {
+		var fp, status, note string
+		var updated int64
+		if err := rows.Scan(&fp, &status, &note, &updated); err != nil {
+			fmt.Printf("Error reading database: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", fp, status, time.Unix(updated, 0).Format(time.DateTime), note)
+	}
+	w.Flush()
+}
@@ -1,225 +1,483 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
 )
 
 type JSONData map[string]interface{}
 
 func main() {
-	// Command-line flags
-	inDir := flag.String("i", "", "Input directory containing JSON trufflehog output files (required)")
-	searchTerm := flag.String("s", "", "String to search for (required) (case-insensitive)")
-	searchMode := flag.String("m", "contains", "Search mode: 'exact' or 'contains'")
-	searchField := flag.String("f", "", "Specific field to search in (optional)")
-	listFields := flag.Bool("l", false, "List all searchable fields (case-sensitive)")
-	numThreads := flag.Int("t", 1, "Number of goroutines for parallel processing")
-	flag.Parse()
-
-	// Handle the -l flag to list all fields
-	if *listFields {
-		printSearchableFields()
-		os.Exit(0)
-	}
-
-	// Validate flags
-	if *inDir == "" {
-		fmt.Println("Error: -i is a required parameter.")
-		flag.Usage()
-		os.Exit(1)
+	// Subcommands take their own flags, so dispatch them before parsing the search flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "convert":
+			runConvert(os.Args[2:])
+			return
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		case "split":
+			runSplit(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "bundle":
+			runBundle(os.Args[2:])
+			return
+		case "repl":
+			runREPL(os.Args[2:])
+			return
+		case "db":
+			runDB(os.Args[2:])
+			return
+		case "baseline":
+			runBaseline(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "blast-radius":
+			runBlastRadius(os.Args[2:])
+			return
+		case "index":
+			runIndex(os.Args[2:])
+			return
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "scan":
+			runScan(os.Args[2:])
+			return
+		case "mark":
+			runMark(os.Args[2:])
+			return
+		case "status":
+			runStatus(os.Args[2:])
+			return
+		case "explore":
+			runExplore(os.Args[2:])
+			return
+		case "run":
+			runProfiles(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		}
 	}
 
-	if *searchTerm == "" {
-		fmt.Println("Error: -s is a required parameter.")
-		flag.Usage()
-		os.Exit(1)
-	}
+	os.Exit(runSearch(os.Args[0], os.Args[1:], nil, nil))
+}
 
-	if *searchMode != "exact" && *searchMode != "contains" {
-		fmt.Println("Error: -m must be 'exact' or 'contains'.")
-		os.Exit(1)
-	}
+// Calls fn for every source of a search as it is found; nil searches -i
+type sourceLister func(ctx context.Context, f *searchFlags, filter inputFilter, fn func(inputSource) error) error
 
-	// Convert search term to lowercase for case-insensitive matching
-	searchTermLower := strings.ToLower(*searchTerm)
+// Run a search command and return its exit code. extra registers the flags of
+// the command besides the search flags.
+func runSearch(name string, args []string, extra func(fs *flag.FlagSet), listSources sourceLister) int {
+	fs, cfg, presets, jobFlags := parseSearchFlags(name, args, extra)
+	f := jobFlags[0]
+	filter := inputFilter{recursive: f.recursive, include: f.include, exclude: f.exclude}
+	localInput := listSources == nil
 
-	// Prefixes for Json search. Easier add or remove in case of structure changes
-	fieldPrefixes := []string{"", "SourceMetadata.Data.Github."}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
 
-	// Read all JSON files from the directory
-	files, err := os.ReadDir(*inDir)
-	if err != nil {
-		fmt.Printf("Error reading directory: %v\n", err)
-		os.Exit(1)
+	// Handle the -l flag to list all fields
+	if f.listFields {
+		if err := printSearchableFields(ctx, f, filter); err != nil {
+			fmt.Printf("Error reading input: %v\n", err)
+			return 2
+		}
+		return 0
 	}
 
-	// Create worker pool
-	fileChan := make(chan os.DirEntry, len(files))
-	var wg sync.WaitGroup
+	if listSources == nil {
+		if f.inDir == "" {
+			fmt.Println("Error: -i is a required parameter.")
+			fs.Usage()
+			return 1
+		}
+		// A missing input is reported before any output is created
+		if f.inDir != "-" && !isRemote(f.inDir) {
+			if _, err := os.Stat(f.inDir); err != nil {
+				fmt.Printf("Error reading input: %v\n", err)
+				return 2
+			}
+		}
+		listSources = func(ctx context.Context, f *searchFlags, filter inputFilter, fn func(inputSource) error) error {
+			return walkInputs(ctx, f.inDir, filter, fn)
+		}
 
-	// Launch worker goroutines
-	for i := 0; i < *numThreads; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for file := range fileChan {
-				if filepath.Ext(file.Name()) == ".json" {
-					processFile(filepath.Join(*inDir, file.Name()), searchTermLower, *searchMode, *searchField, fieldPrefixes)
-				}
+		// Serve the corpus until interrupted; no search term is needed
+		if f.keepAlive {
+			if f.inputFormat != "trufflehog" {
+				fmt.Println("Error: -keep-alive only supports -input-format trufflehog.")
+				return 1
 			}
-		}()
+			runKeepAlive(f.inDir)
+			return 0
+		}
 	}
 
-	// Feed files into the channel
-	for _, file := range files {
-		fileChan <- file
+	for _, jf := range jobFlags {
+		if !jf.hasCriteria() && jf.status == "" && jf.baseline == "" && jf.against == "" && !jf.fallback {
+			fmt.Println("Error: -s is a required parameter (or -query, -terms-file or a filter such as -detector).")
+			fs.Usage()
+			return 1
+		}
 	}
-	close(fileChan)
 
-	// Wait for all workers to complete
-	wg.Wait()
-}
-
-// Process a single JSON file
-func processFile(filePath, searchTerm, searchMode, searchField string, fieldPrefixes []string) {
-	fileHandle, err := os.Open(filePath)
-	if err != nil {
-		fmt.Printf("Error opening file %s: %v\n", filePath, err)
-		return
-	}
-	defer fileHandle.Close()
-
-	fmt.Printf("\n--- Searching in file: %s ---\n", filepath.Base(filePath))
-	scanner := bufio.NewScanner(fileHandle)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		var jsonData JSONData
-		err := json.Unmarshal([]byte(line), &jsonData)
+	// Hash the inputs before searching them, and check them against a required manifest
+	var manifest *runManifest
+	if f.manifest != "" || f.requireManifest != "" {
+		var err error
+		manifest, err = newRunManifest(ctx, presets, jobFlags, func(fn func(inputSource) error) error {
+			return listSources(ctx, f, filter, fn)
+		})
 		if err != nil {
-			fmt.Printf("Error parsing JSON at line %d in file %s: %v\n", lineNum, filepath.Base(filePath), err)
-			continue
-		}
-
-		// Attempt search with each prefix
-		found := false
-		for _, prefix := range fieldPrefixes {
-			fullField := prefix + searchField
-			if match := findAndPrintRelatedData(jsonData, searchTerm, searchMode, fullField); match {
-				fmt.Printf("\n--- Related Data at line %d ---\n", lineNum)
-				printPrettyJSON(jsonData)
-				found = true
-				break
+			fmt.Printf("Error recording the manifest: %v\n", err)
+			return 2
+		}
+		if f.requireManifest != "" {
+			if err := requireManifest(f.requireManifest, manifest); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 1
 			}
 		}
+	}
 
-		if !found && searchField == "" {
-			// Search the entire JSON if no specific field is specified
-			if match := findAndPrintRelatedData(jsonData, searchTerm, searchMode, ""); match {
-				fmt.Printf("\n--- Related Data at line %d ---\n", lineNum)
-				printPrettyJSON(jsonData)
+	// Dispatch to a warm daemon when one is serving this directory.
+	// Everything beyond plain text output is handled locally, so it always searches the files directly.
+	if len(jobFlags) == 1 && daemonEligible(f, cfg) {
+		opts, err := f.options()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		request := daemonRequest{Options: opts}
+		if !f.showSecrets {
+			request.Redact = splitList(f.redactFields)
+		}
+		if matches, ok := queryDaemon(f.inDir, request); ok {
+			switch {
+			case outcome.errors.Load() > 0:
+				return 2
+			case matches > 0:
+				return 0
 			}
+			return 1
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading file %s: %v\n", filepath.Base(filePath), err)
+	// Runs recording state in the database take turns
+	if f.changedSince != "" || f.cache {
+		lock, err := lockDB(ctx, f.dbPath, f.wait)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		defer lock.Close()
 	}
-}
 
-// Print all searchable fields
-func printSearchableFields() {
-	fields := []string{
-		"DecoderName", "DetectorDescription", "DetectorName", "DetectorType", "project", "rotation_guide",
-		"Raw", "RawV2", "Redacted", "SourceID", "commit", "email", "file", "line", "link",
-		"repository", "timestamp", "SourceName", "SourceType", "StructuredData", "VerificationFromCache", "Verified",
+	// A search repeated over unchanged inputs is answered with its stored output
+	streams := outputStreams{stdout: os.Stdout, stderr: os.Stderr}
+	var queries *queryCache
+	if f.cache && localInput && queryCacheEligible(f, jobFlags) {
+		var err error
+		queries, err = openQueryCache(f.dbPath, f, cfg, func(fn func(inputSource) error) error {
+			return listSources(ctx, f, filter, fn)
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		defer queries.Close()
+		if code, ok := queries.replay(time.Now()); ok {
+			if !f.quiet {
+				fmt.Fprintln(os.Stderr, "Returned the stored output of this search over unchanged inputs")
+			}
+			return code
+		}
+		streams = queries.record(streams)
 	}
 
-	fmt.Println("Searchable Fields (case-sensitive):")
-	fmt.Println(strings.Repeat("-", 40))
-	for _, field := range fields {
-		fmt.Printf("- %s\n", field)
+	metrics := newRunMetrics()
+	jobs := make([]*searchJob, 0, len(jobFlags))
+	for i, jf := range jobFlags {
+		job, err := newSearchJob(ctx, presets[i], jf, cfg, metrics, streams)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		jobs = append(jobs, job)
 	}
-	fmt.Println(strings.Repeat("-", 40))
-}
 
-// Search for the term and determine if related data should be printed
-func findAndPrintRelatedData(data JSONData, term, mode, field string) bool {
-	if field != "" {
-		if value, exists := getNestedField(data, field); exists {
-			return checkMatch(value, term, mode)
+	var changes *changeTracker
+	if f.changedSince != "" {
+		var err error
+		if changes, err = openChangeTracker(f.dbPath, f.changedSince, jobFlags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
 		}
-		return false
 	}
 
-	// Search the entire JSON if no specific field is specified
-	for _, value := range data {
-		if checkMatch(value, term, mode) {
-			return true
+	var cache *resultCache
+	if f.cache {
+		var err error
+		if cache, err = openResultCache(f.dbPath, jobFlags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
 		}
+		defer cache.Close()
 	}
-	return false
-}
 
-// Check if a value matches the search term based on the mode
-func checkMatch(value interface{}, term, mode string) bool {
-	switch v := value.(type) {
-	case string:
-		lowerValue := strings.ToLower(v) // Convert to lowercase for case-insensitive matching
-		if (mode == "exact" && lowerValue == term) || (mode == "contains" && strings.Contains(lowerValue, term)) {
-			return true
-		}
-	case []interface{}:
-		for _, item := range v {
-			if checkMatch(item, term, mode) {
-				return true
+	stopProgress := func() {}
+	if f.progress {
+		stopProgress = metrics.reportProgress(os.Stderr, 500*time.Millisecond)
+	}
+	p := &pipeline{
+		jobs:           jobs,
+		inputFormat:    f.inputFormat,
+		filter:         filter,
+		threads:        f.threads,
+		scan:           searcher.ScanOptions{Workers: f.lineWorkers, Unordered: f.unordered, MaxLineBytes: f.maxLineBytes},
+		perFileTimeout: f.perFileTimeout,
+		metrics:        metrics,
+		cache:          cache,
+	}
+	walk := func(fn func(inputSource) error) error {
+		return listSources(ctx, f, filter, fn)
+	}
+	if changes != nil {
+		p.searched = changes.done
+		walk = func(fn func(inputSource) error) error {
+			return listSources(ctx, f, filter, func(src inputSource) error {
+				if !changes.changed(src) {
+					return nil
+				}
+				return fn(src)
+			})
+		}
+	}
+	if f.follow > 0 {
+		list := walk
+		walk = func(fn func(inputSource) error) error {
+			return list(func(src inputSource) error {
+				return fn(followSource(src, f.follow))
+			})
+		}
+	}
+	var err error
+	if f.watch {
+		// Watching runs until interrupted, so routed matches are sent as they come
+		go func() {
+			ticker := time.NewTicker(sinkDispatchInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					for _, job := range jobs {
+						job.router.dispatch(ctx)
+					}
+				}
 			}
+		}()
+		err = p.watch(ctx, f.inDir, walk)
+	} else {
+		err = p.run(ctx, walk)
+	}
+	stopProgress()
+	// A listing stopped by an interruption is reported with it below
+	if err != nil && ctx.Err() == nil {
+		if f.watch {
+			fmt.Printf("Error watching %s: %v\n", f.inDir, err)
+		} else {
+			fmt.Printf("Error reading input: %v\n", err)
 		}
-	case map[string]interface{}:
-		for _, item := range v {
-			if checkMatch(item, term, mode) {
-				return true
+		outcome.errors.Add(1)
+	}
+	if cache != nil && !f.quiet {
+		fmt.Fprintf(os.Stderr, "Replayed the cached results of %d file(s)\n", cache.hits)
+	}
+	if changes != nil {
+		if !f.quiet {
+			fmt.Fprintf(os.Stderr, "Skipped %d unchanged file(s)\n", changes.skipped)
+		}
+		if err := changes.Close(); err != nil {
+			fmt.Printf("Error recording searched files: %v\n", err)
+			outcome.errors.Add(1)
+		}
+	}
+
+	// Results found before an interruption are still written
+	finishCtx := context.WithoutCancel(ctx)
+	for _, job := range jobs {
+		if job.flags.tui {
+			if err := runTUI(job); err != nil {
+				fmt.Printf("Error running the terminal UI: %v\n", err)
+				outcome.errors.Add(1)
+			}
+		}
+		if err := job.out.finish(finishCtx); err != nil {
+			fmt.Printf("Error writing results: %v\n", err)
+			outcome.errors.Add(1)
+		}
+		if err := job.close(finishCtx); err != nil {
+			fmt.Printf("Error writing results: %v\n", err)
+			outcome.errors.Add(1)
+		}
+	}
+	if f.metricsJSON != "" {
+		if err := metrics.writeJSON(f.metricsJSON); err != nil {
+			fmt.Printf("Error writing metrics: %v\n", err)
+			outcome.errors.Add(1)
+		}
+	}
+	if f.manifest != "" {
+		err := manifest.addOutputs(jobFlags)
+		if err == nil {
+			err = manifest.write(f.manifest)
+		}
+		if err != nil {
+			fmt.Printf("Error writing the manifest: %v\n", err)
+			outcome.errors.Add(1)
+		}
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "Timed out after %s: results are partial\n", f.timeout)
+		outcome.errors.Add(1)
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "Interrupted: results are partial")
+		return 130
+	}
+	code := cfg.ExitCodes.code(&outcome, defaultExitCode(&outcome, f.quiet, f.baseline != "" || f.against != ""))
+	if queries != nil && outcome.errors.Load() == 0 {
+		var validUntil time.Time
+		for _, job := range jobs {
+			if expires := job.out.expires; !expires.IsZero() && (validUntil.IsZero() || expires.Before(validUntil)) {
+				validUntil = expires
 			}
 		}
+		if err := queries.store(code, validUntil); err != nil {
+			fmt.Printf("Error storing the output in the cache: %v\n", err)
+		}
 	}
-	return false
+	return code
 }
 
-// Get a nested field value by path (e.g., "a.b.c")
-func getNestedField(data JSONData, path string) (interface{}, bool) {
-	parts := strings.Split(path, ".")
-	current := data
-	for i, part := range parts {
-		value, exists := current[part]
-		if !exists {
-			return nil, false
+// Parse the flags of a search command and load the configuration. Flags left
+// unset are taken from the preset, THS_* variables and the configuration
+// defaults. Several presets give one set of flags each, all sharing the input.
+func parseSearchFlags(name string, args []string, extra func(fs *flag.FlagSet)) (*flag.FlagSet, *config, []string, []*searchFlags) {
+	newFlags := func() (*flag.FlagSet, *searchFlags) {
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		f := registerSearchFlags(fs)
+		if extra != nil {
+			extra(fs)
+		}
+		fs.Parse(args)
+		f.args = fs.Args()
+		return fs, f
+	}
+	fs, f := newFlags()
+
+	configPath := f.configPath
+	if env := os.Getenv("THS_CONFIG"); env != "" && !setFlags(fs)["config"] {
+		configPath = env
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.ExitCodes.validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	presets := []string{""}
+	if len(f.presets) > 0 {
+		presets = f.presets
+	}
+	var jobFlags []*searchFlags
+	for i, preset := range presets {
+		if i > 0 {
+			fs, f = newFlags()
 		}
-		if i == len(parts)-1 {
-			return value, true
+		if err := resolveFlags(fs, cfg, preset); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-		subMap, ok := value.(map[string]interface{})
-		if !ok {
-			return nil, false
+		if f.noCache {
+			f.cache = false
 		}
-		current = subMap
+		if f.statsVerbose {
+			f.stats = true
+		}
+		f.tunePools(fs)
+		// Each preset of a multi-preset run writes to its own file
+		if len(presets) > 1 && f.outputFile == "" {
+			f.outputFile = preset + "." + formatExtension(f.format)
+		}
+		if err := f.validate(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			fs.Usage()
+			os.Exit(1)
+		}
+		f.resolved = flagValues(fs)
+		jobFlags = append(jobFlags, f)
 	}
-	return nil, false
+	return fs, cfg, presets, jobFlags
 }
 
-// Print the JSON object in a pretty format
-func printPrettyJSON(data JSONData) {
-	prettyData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		fmt.Printf("Error pretty-printing JSON: %v\n", err)
-		return
+// File extension of an output format
+func formatExtension(format string) string {
+	switch format {
+	case "text":
+		return "txt"
+	case "json":
+		return "jsonl"
+	}
+	return format
+}
+
+// Whether a search can be answered by a -keep-alive daemon: plain text output
+// of a local directory without any of the features handled locally
+func daemonEligible(f *searchFlags, cfg *config) bool {
+	if f.format != "text" || f.fields != "" || f.outputFile != "" || f.quiet || f.count || f.stats || f.dedupe ||
+		f.groupBy != "" || f.histogram != "" || f.anomalies || f.rank || f.bySeverity || f.tui || f.watch || f.follow > 0 || f.changedSince != "" || f.cache || f.report != "" || f.graph != "" || f.notifyWebhook != "" || len(f.sinks) > 0 || f.expiry != "" || f.progress || f.metricsJSON != "" {
+		return false
+	}
+	if f.outDir != "" || f.exportKind != "" || f.policyPath != "" || cfg.ExitCodes.configured() || f.inputFormat != "trufflehog" ||
+		f.baseline != "" || f.against != "" || f.status != "" || f.enrichGit != "" || f.explain || f.hideLikelyFP || f.thirdParty != "show" || f.manifest != "" || f.requireManifest != "" {
+		return false
+	}
+	if f.recursive || len(f.include) > 0 || len(f.exclude) > 0 || f.inDir == "-" || isRemote(f.inDir) {
+		return false
 	}
-	fmt.Println(string(prettyData))
+	rules, err := loadIgnoreRules(f.ignoreFile, cfg.Ignore)
+	return err == nil && len(rules) == 0 && len(cfg.ExampleSecrets) == 0 && len(cfg.ThirdPartyPaths) == 0
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Keys marking the selected finding with a triage status in the terminal UI
+var tuiStatusKeys = map[string]string{"f": "false-positive", "i": "investigating", "r": "rotated", "n": "new"}
+
+var (
+	tuiHeaderStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	tuiSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	tuiVerifiedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	tuiDetailStyle   = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderTop(true).BorderForeground(lipgloss.Color("8"))
+	tuiHelpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// State of the -tui browser
+type tuiModel struct {
+	job       *searchJob
+	matches   []*searchMatch
+	visible   []int // indexes of the matches passing the filter
+	cursor    int   // position in visible
+	offset    int   // first visible row shown
+	filter    string
+	filtering bool
+	marked    map[int]bool // matches triaged in this session, exported by e
+	message   string
+	width     int
+	height    int
+}
+
+// Browse the matches of a job in a terminal UI, recording triage statuses in its database
+func runTUI(job *searchJob) error {
+	if len(job.out.collected) == 0 {
+		fmt.Println("No matches to browse")
+		return nil
+	}
+	m := &tuiModel{job: job, matches: job.out.collected, marked: map[int]bool{}, width: 120, height: 40}
+	m.applyFilter()
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		if m.filtering {
+			return m, m.updateFilter(msg)
+		}
+		m.message = ""
+		switch key := msg.String(); key {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup":
+			m.move(-m.tableRows())
+		case "pgdown":
+			m.move(m.tableRows())
+		case "home", "g":
+			m.move(-len(m.visible))
+		case "end", "G":
+			m.move(len(m.visible))
+		case "/":
+			m.filtering = true
+		case "esc":
+			m.filter = ""
+			m.applyFilter()
+		case "e":
+			m.export()
+		default:
+			if status, ok := tuiStatusKeys[key]; ok {
+				m.mark(status)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Edit the filter; enter keeps it, esc drops it
+func (m *tuiModel) updateFilter(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.filter = ""
+	case tea.KeyBackspace:
+		if m.filter != "" {
+			runes := []rune(m.filter)
+			m.filter = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+	}
+	m.applyFilter()
+	return nil
+}
+
+// Keep the matches whose row fuzzily matches the filter: its characters appear in order
+func (m *tuiModel) applyFilter() {
+	m.visible = m.visible[:0]
+	filter := strings.ToLower(m.filter)
+	for i, match := range m.matches {
+		if fuzzyContains(strings.ToLower(strings.Join(m.columns(match), " ")), filter) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.cursor, m.offset = 0, 0
+}
+
+// Whether the characters of pattern appear in text in the same order
+func fuzzyContains(text, pattern string) bool {
+	for _, r := range pattern {
+		index := strings.IndexRune(text, r)
+		if index < 0 {
+			return false
+		}
+		text = text[index+len(string(r)):]
+	}
+	return true
+}
+
+func (m *tuiModel) move(delta int) {
+	m.cursor = max(0, min(len(m.visible)-1, m.cursor+delta))
+	rows := m.tableRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+}
+
+// The selected match, or nil when the filter hides everything
+func (m *tuiModel) selected() (int, *searchMatch) {
+	if len(m.visible) == 0 {
+		return -1, nil
+	}
+	index := m.visible[m.cursor]
+	return index, m.matches[index]
+}
+
+// How long marking a finding waits for another run writing to the database
+const tuiLockWait = 2 * time.Second
+
+// Record a triage status for the selected match
+func (m *tuiModel) mark(status string) {
+	index, match := m.selected()
+	if match == nil {
+		return
+	}
+	if err := m.job.triage.markLocked(match.fingerprint, status, match.note, tuiLockWait); err != nil {
+		m.message = fmt.Sprintf("Error marking finding: %v", err)
+		return
+	}
+	match.status = status
+	m.marked[index] = true
+	m.message = fmt.Sprintf("Marked as %s", status)
+}
+
+// Write the matches triaged in this session as JSON lines
+func (m *tuiModel) export() {
+	if len(m.marked) == 0 {
+		m.message = "Nothing marked yet"
+		return
+	}
+	path := fmt.Sprintf("triaged-%s.jsonl", time.Now().Format("20060102-150405"))
+	file, err := os.Create(path)
+	if err != nil {
+		m.message = fmt.Sprintf("Error exporting: %v", err)
+		return
+	}
+	for i, match := range m.matches {
+		if m.marked[i] {
+			writeJSONLine(file, newJSONResult(match, m.job.out.display(match.data), false))
+		}
+	}
+	if err := file.Close(); err != nil {
+		m.message = fmt.Sprintf("Error exporting: %v", err)
+		return
+	}
+	m.message = fmt.Sprintf("Exported %d finding(s) to %s", len(m.marked), path)
+}
+
+// Status, detector, repository, file and verified columns of a match
+func (m *tuiModel) columns(match *searchMatch) []string {
+	flat := flattenFinding(match.data, match.sourceFile, match.sourceLine)
+	file := flat.File
+	if file != "" && flat.Line > 0 {
+		file += ":" + strconv.FormatInt(flat.Line, 10)
+	}
+	return []string{triageStatusOrNew(match.status), flat.DetectorName, flat.Repository, file, strconv.FormatBool(flat.Verified)}
+}
+
+// Rows of the table, leaving the rest of the screen to the detail pane
+func (m *tuiModel) tableRows() int {
+	return max(3, (m.height-4)/2)
+}
+
+func (m *tuiModel) View() string {
+	var b strings.Builder
+	widths := []int{15, 20, 40, 0, 8}
+	widths[3] = max(10, m.width-widths[0]-widths[1]-widths[2]-widths[4]-5)
+	row := func(cells []string) string {
+		padded := make([]string, len(cells))
+		for i, cell := range cells {
+			padded[i] = fitWidth(cell, widths[i])
+		}
+		return strings.Join(padded, " ")
+	}
+
+	b.WriteString(tuiHeaderStyle.Render(row([]string{"STATUS", "DETECTOR", "REPOSITORY", "FILE", "VERIFIED"})))
+	b.WriteString("\n")
+	rows := m.tableRows()
+	for n := m.offset; n < min(len(m.visible), m.offset+rows); n++ {
+		match := m.matches[m.visible[n]]
+		line := row(m.columns(match))
+		verified, _ := match.data["Verified"].(bool)
+		switch {
+		case n == m.cursor:
+			line = tuiSelectedStyle.Render(line)
+		case verified:
+			line = tuiVerifiedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	for n := len(m.visible) - m.offset; n < rows; n++ {
+		b.WriteString("\n")
+	}
+
+	detailHeight := max(1, m.height-rows-4)
+	var detail string
+	if _, match := m.selected(); match != nil {
+		pretty, _ := json.MarshalIndent(m.job.out.display(match.data), "", "  ")
+		lines := strings.Split(fmt.Sprintf("fingerprint %s  %s:%d\n%s", match.fingerprint, match.sourceFile, match.sourceLine, pretty), "\n")
+		if len(lines) > detailHeight {
+			lines = lines[:detailHeight]
+		}
+		detail = strings.Join(lines, "\n")
+	}
+	b.WriteString(tuiDetailStyle.Width(m.width).Height(detailHeight).Render(detail))
+	b.WriteString("\n")
+
+	status := fmt.Sprintf("%d/%d", min(m.cursor+1, len(m.visible)), len(m.visible))
+	if m.filtering || m.filter != "" {
+		status += "  filter: " + m.filter
+		if m.filtering {
+			status += "_"
+		}
+	}
+	if m.message != "" {
+		status += "  " + m.message
+	}
+	b.WriteString(status + "  " + tuiHelpStyle.Render("↑/↓ move  / filter  f false-positive  i investigating  r rotated  n new  e export  q quit"))
+	return b.String()
+}
+
+// Cut or pad text to exactly width characters
+func fitWidth(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:max(0, width-1)]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
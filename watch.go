@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"github.com/fsnotify/fsnotify"
+)
+
+// How often watched files are checked besides filesystem notifications,
+// which some filesystems (network mounts, some containers) never deliver
+const watchPollInterval = 2 * time.Second
+
+// Read position in a followed file
+type tailState struct {
+	name    string
+	offset  int64
+	line    int
+	partial []byte // start of a line still being written
+	stats   *fileMetrics
+}
+
+// Follow -i like tail -F: search the JSON lines already there, then the lines
+// appended to them and the files created later, until ctx is done. Compressed
+// files and archives do not grow and are skipped.
+func (p *pipeline) watch(ctx context.Context, input string, walk sourceWalker) error {
+	if input == "-" {
+		return p.run(ctx, walk)
+	}
+	if input == "" || isRemote(input) {
+		return fmt.Errorf("-watch needs a local file or directory")
+	}
+	info, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if info.IsDir() {
+		err = p.watchDirs(watcher, input)
+	} else {
+		err = watcher.Add(filepath.Dir(input))
+	}
+	if err != nil {
+		return err
+	}
+
+	// One long-lived output per job receives the results of every file
+	outs := make([]*sourceOutput, len(p.jobs))
+	for i, job := range p.jobs {
+		outs[i] = newSourceOutput()
+		queue := make(chan *sourceOutput, 1)
+		queue <- outs[i]
+		close(queue)
+		job.out.start(queue)
+	}
+	defer func() {
+		for _, out := range outs {
+			close(out.events)
+		}
+		for _, job := range p.jobs {
+			job.out.wait()
+		}
+	}()
+
+	w := &watchState{pipeline: p, input: input, outs: outs, files: map[string]*tailState{}, skipped: map[string]bool{}}
+	defer w.finish()
+	sources, err := listInputs(ctx, input, p.filter)
+	if err != nil {
+		return err
+	}
+	w.poll(ctx, sources)
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// New directories are followed too with -r
+			if event.Has(fsnotify.Create) && info.IsDir() && p.filter.recursive {
+				if created, err := os.Stat(event.Name); err == nil && created.IsDir() {
+					p.watchDirs(watcher, event.Name)
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			broadcast(outs, outputEvent{message: fmt.Sprintf("Error watching %s: %v", input, err)})
+			continue
+		case <-ticker.C:
+		}
+
+		sources, err := listInputs(ctx, input, p.filter)
+		if err != nil {
+			broadcast(outs, outputEvent{message: fmt.Sprintf("Error reading input: %v", err)})
+			continue
+		}
+		w.poll(ctx, sources)
+	}
+}
+
+// Watch a directory and, with -r, the directories below it
+func (p *pipeline) watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(dir string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if dir != root && !p.filter.recursive {
+			return filepath.SkipDir
+		}
+		return watcher.Add(dir)
+	})
+}
+
+// The files followed by -watch
+type watchState struct {
+	*pipeline
+	input   string
+	outs    []*sourceOutput
+	files   map[string]*tailState
+	skipped map[string]bool
+	current string // file of the last header sent
+}
+
+// Read what was appended to every source since the last poll
+func (w *watchState) poll(ctx context.Context, sources []inputSource) {
+	found := false
+	for _, src := range sources {
+		if ctx.Err() != nil {
+			return
+		}
+		if src.path == "" || isArchive(src.name) || trimCompressionExt(src.name) != src.name {
+			if !w.skipped[src.name] {
+				w.skipped[src.name] = true
+				broadcast(w.outs, outputEvent{message: fmt.Sprintf("Skipping %s: compressed files, archives and streams cannot be watched", src.name)})
+			}
+			continue
+		}
+		state, ok := w.files[src.path]
+		if !ok {
+			state = &tailState{name: src.name, stats: w.metrics.startFile(inputSource{name: src.name}, nil)}
+			w.files[src.path] = state
+			// Followed files keep growing, so there is no ETA
+			w.metrics.addSources([]inputSource{{name: src.name}})
+		}
+		matched, err := w.tail(ctx, src.path, state)
+		if err != nil {
+			broadcast(w.outs, outputEvent{message: fmt.Sprintf("Error reading file %s: %v", src.name, err)})
+		}
+		found = found || matched
+	}
+	// Matches found in this round are notified together
+	if found {
+		broadcast(w.outs, outputEvent{flush: true})
+	}
+}
+
+// Search the complete lines appended to a file. A file that shrank was
+// truncated or replaced and is read again from the start.
+func (w *watchState) tail(ctx context.Context, path string, state *tailState) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < state.offset {
+		state.offset, state.line, state.partial = 0, 0, nil
+		broadcast(w.outs, outputEvent{message: fmt.Sprintf("File %s was truncated, reading it again", state.name)})
+	}
+	if info.Size() == state.offset {
+		return false, nil
+	}
+	if _, err := file.Seek(state.offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	appended, err := io.ReadAll(io.LimitReader(file, info.Size()-state.offset))
+	if err != nil {
+		return false, err
+	}
+	state.offset += int64(len(appended))
+	w.metrics.addBytes(state.stats, len(appended))
+
+	content := append(state.partial, appended...)
+	matched := false
+	for {
+		newline := bytes.IndexByte(content, '\n')
+		if newline < 0 {
+			break
+		}
+		line := bytes.TrimRight(content[:newline], "\r")
+		content = content[newline+1:]
+		state.line++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record := &searcher.Record{Line: state.line, Raw: append([]byte(nil), line...)}
+		if w.scan.MaxLineBytes > 0 && len(line) > w.scan.MaxLineBytes {
+			record.Raw, record.Err = nil, fmt.Errorf("line exceeds %d bytes", w.scan.MaxLineBytes)
+		}
+		var matches []*searchMatch
+		if record.Err == nil {
+			matches = w.process(ctx, state.name, record).matches
+		}
+		if matches != nil && w.current != state.name {
+			w.current = state.name
+			broadcast(w.outs, outputEvent{header: state.name})
+		}
+		w.emit(state.name, record, matches, w.outs, state.stats)
+		matched = matched || matches != nil
+	}
+	state.partial = append([]byte(nil), content...)
+	return matched, nil
+}
+
+// Record the statistics of the followed files
+func (w *watchState) finish() {
+	for _, state := range w.files {
+		w.metrics.finishFile(state.stats)
+	}
+}
+
+// How often -follow checks a file that reached its end for appended content
+const followPollInterval = 250 * time.Millisecond
+
+// Read a local file like tail -f: at its end, wait for trufflehog to append
+// more and only report the end once nothing was appended for idle. Streams
+// end by themselves and are returned unchanged.
+func followSource(src inputSource, idle time.Duration) inputSource {
+	if src.path == "" {
+		return src
+	}
+	open := src.open
+	// A growing file has no known size
+	src.size = 0
+	src.open = func(ctx context.Context) (io.ReadCloser, error) {
+		r, err := open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &followReader{ReadCloser: r, ctx: ctx, idle: idle}, nil
+	}
+	return src
+}
+
+// File read by -follow
+type followReader struct {
+	io.ReadCloser
+	ctx  context.Context
+	idle time.Duration
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	idleSince := time.Now()
+	for {
+		n, err := r.ReadCloser.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if time.Since(idleSince) >= r.idle {
+			return 0, io.EOF
+		}
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(followPollInterval):
+		}
+	}
+}
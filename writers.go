@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/crashbrz/trufflehog-searcher/pkg/searcher"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Structured output formats supported by newFindingWriter
+var outputFormats = []string{"csv", "sarif", "parquet"}
+
+// Writer for findings in a structured output format
+type findingWriter interface {
+	WriteFinding(data JSONData, sourceFile string, sourceLine int) error
+	Close() error
+}
+
+// Create a writer for the given output format
+func newFindingWriter(format string, w io.Writer) (findingWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w), nil
+	case "sarif":
+		return &sarifWriter{out: w}, nil
+	case "parquet":
+		return &parquetWriter{writer: parquet.NewGenericWriter[flatFinding](w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q", format)
+}
+
+// Common trufflehog fields flattened into a single row
+type flatFinding struct {
+	SourceFile       string `parquet:"source_file" bigquery:"source_file"`
+	SourceLine       int64  `parquet:"source_line" bigquery:"source_line"`
+	DetectorName     string `parquet:"detector_name" bigquery:"detector_name"`
+	DetectorType     int64  `parquet:"detector_type" bigquery:"detector_type"`
+	DetectorTypeName string `parquet:"detector_type_name" bigquery:"detector_type_name"`
+	DecoderName      string `parquet:"decoder_name" bigquery:"decoder_name"`
+	Verified         bool   `parquet:"verified" bigquery:"verified"`
+	Raw              string `parquet:"raw" bigquery:"raw"`
+	RawV2            string `parquet:"raw_v2" bigquery:"raw_v2"`
+	Redacted         string `parquet:"redacted" bigquery:"redacted"`
+	SourceName       string `parquet:"source_name" bigquery:"source_name"`
+	SourceType       int64  `parquet:"source_type" bigquery:"source_type"`
+	Repository       string `parquet:"repository" bigquery:"repository"`
+	Commit           string `parquet:"commit" bigquery:"commit"`
+	File             string `parquet:"file" bigquery:"file"`
+	Line             int64  `parquet:"line" bigquery:"line"`
+	Email            string `parquet:"email" bigquery:"email"`
+	Timestamp        string `parquet:"timestamp" bigquery:"timestamp"`
+	Link             string `parquet:"link" bigquery:"link"`
+}
+
+// Column names of the flattened row, in csv order
+var flatColumns = []string{
+	"source_file", "source_line", "DetectorName", "DetectorType", "DetectorTypeName", "DecoderName", "Verified", "Raw", "RawV2",
+	"Redacted", "SourceName", "SourceType", "repository", "commit", "file", "line", "email", "timestamp", "link", "compliance",
+}
+
+// Flatten a finding into the common columns
+func flattenFinding(data JSONData, sourceFile string, sourceLine int) flatFinding {
+	str := func(field string) string {
+		value, _ := searcher.Lookup(data, field)
+		return searcher.String(value)
+	}
+	num := func(field string) int64 {
+		value, _ := searcher.Lookup(data, field)
+		if f, ok := value.(float64); ok {
+			return int64(f)
+		}
+		return 0
+	}
+	verified, _ := data["Verified"].(bool)
+	_, typeName, _ := detectorType(data)
+
+	flat := flatFinding{
+		SourceFile:       sourceFile,
+		SourceLine:       int64(sourceLine),
+		DetectorName:     str("DetectorName"),
+		DetectorType:     num("DetectorType"),
+		DetectorTypeName: typeName,
+		DecoderName:      str("DecoderName"),
+		Verified:         verified,
+		Raw:              str("Raw"),
+		RawV2:            str("RawV2"),
+		Redacted:         str("Redacted"),
+		SourceName:       str("SourceName"),
+		SourceType:       num("SourceType"),
+		Repository:       str("repository"),
+		Commit:           str("commit"),
+		File:             str("file"),
+		Line:             num("line"),
+		Email:            str("email"),
+		Timestamp:        str("timestamp"),
+		Link:             str("link"),
+	}
+	// Findings without a link get the permalink of their line
+	if flat.Link == "" {
+		flat.Link = permalink(data)
+	}
+	return flat
+}
+
+// CSV writer using the flattened columns
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteFinding(data JSONData, sourceFile string, sourceLine int) error {
+	if !c.header {
+		if err := c.writer.Write(flatColumns); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	f := flattenFinding(data, sourceFile, sourceLine)
+	return c.writer.Write([]string{
+		f.SourceFile, strconv.FormatInt(f.SourceLine, 10), f.DetectorName, strconv.FormatInt(f.DetectorType, 10),
+		f.DetectorTypeName, f.DecoderName, strconv.FormatBool(f.Verified), f.Raw, f.RawV2, f.Redacted, f.SourceName,
+		strconv.FormatInt(f.SourceType, 10), f.Repository, f.Commit, f.File, strconv.FormatInt(f.Line, 10),
+		f.Email, f.Timestamp, f.Link, strings.Join(complianceTags(f.DetectorName), "; "),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// Parquet writer using the flattened columns
+type parquetWriter struct {
+	writer *parquet.GenericWriter[flatFinding]
+}
+
+func (p *parquetWriter) WriteFinding(data JSONData, sourceFile string, sourceLine int) error {
+	_, err := p.writer.Write([]flatFinding{flattenFinding(data, sourceFile, sourceLine)})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.writer.Close()
+}
+
+// SARIF 2.1.0 writer. Results are buffered because the log is a single JSON document.
+type sarifWriter struct {
+	out     io.Writer
+	rules   []string
+	results []map[string]interface{}
+}
+
+func (s *sarifWriter) WriteFinding(data JSONData, sourceFile string, sourceLine int) error {
+	f := flattenFinding(data, sourceFile, sourceLine)
+	ruleID := f.DetectorName
+	if ruleID == "" {
+		ruleID = "unknown"
+	}
+	known := false
+	for _, rule := range s.rules {
+		if rule == ruleID {
+			known = true
+			break
+		}
+	}
+	if !known {
+		s.rules = append(s.rules, ruleID)
+	}
+
+	level := "warning"
+	if f.Verified {
+		level = "error"
+	}
+	// Fall back to the trufflehog output file when the finding has no source file
+	uri, line := f.File, f.Line
+	if uri == "" {
+		uri, line = f.SourceFile, f.SourceLine
+	}
+	region := map[string]interface{}{}
+	if line > 0 {
+		region["startLine"] = line
+	}
+
+	s.results = append(s.results, map[string]interface{}{
+		"ruleId":  ruleID,
+		"level":   level,
+		"message": map[string]interface{}{"text": fmt.Sprintf("%s secret found (verified: %t): %s", ruleID, f.Verified, f.Redacted)},
+		"locations": []interface{}{map[string]interface{}{
+			"physicalLocation": map[string]interface{}{
+				"artifactLocation": map[string]interface{}{"uri": uri},
+				"region":           region,
+			},
+		}},
+		"properties": map[string]interface{}{"repository": f.Repository, "commit": f.Commit, "tags": complianceTags(f.DetectorName)},
+	})
+	return nil
+}
+
+func (s *sarifWriter) Close() error {
+	rules := make([]interface{}, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, map[string]interface{}{
+			"id":               rule,
+			"shortDescription": map[string]interface{}{"text": rule + " secret"},
+		})
+	}
+	results := s.results
+	if results == nil {
+		results = []map[string]interface{}{}
+	}
+
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{map[string]interface{}{
+			"tool":    map[string]interface{}{"driver": map[string]interface{}{"name": "trufflehog", "informationUri": "https://github.com/trufflesecurity/trufflehog", "rules": rules}},
+			"results": results,
+		}},
+	}
+	encoder := json.NewEncoder(s.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}